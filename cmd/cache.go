@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/config"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/idempotency"
+)
+
+// adminCachePath адрес cashstore.Handler кэша сервиса на листенере админки.
+const adminCachePath = "/admin/cache"
+
+// openAdminCache кэш, который сбрасывает CLI после изменений в базе. Общий кэш (redis://) открывается
+// напрямую, а кэш в памяти (memory://) есть только у запущенного сервиса, поэтому CLI ходит в него
+// через листенер админки с токеном server.admin_token.
+//
+//nolint:ireturn // intentional
+func openAdminCache() (cashstore.Cache, error) {
+	cfg, errLoadConfig := loadConfig()
+	if errLoadConfig != nil {
+		return nil, errLoadConfig
+	}
+
+	if cfg.Cache.URL == "" {
+		return nil, nil
+	}
+
+	parsed, errParse := url.Parse(cfg.Cache.URL)
+	if errParse != nil {
+		return nil, errParse //nolint:wrapcheck // intentional
+	}
+
+	if parsed.Scheme != "memory" {
+		cache, _, errNewCache := newCache(cfg.Cache)
+
+		return cache, errNewCache
+	}
+
+	host, port, errSplit := net.SplitHostPort(cfg.Server.AdminAddr)
+	if errSplit != nil {
+		return nil, errSplit //nolint:wrapcheck // intentional
+	}
+
+	if host == "" {
+		host = "localhost"
+	}
+
+	return cashstore.NewRemote(
+		"http://"+net.JoinHostPort(host, port)+adminCachePath,
+		&http.Client{Timeout: remoteCacheTimeout},
+		http.Header{admin.TokenHeader: []string{cfg.Server.AdminToken}},
+	), nil
+}
+
+const remoteCacheTimeout = 5 * time.Second
+
+// newCache cfg.URL адрес кэша (memory:// или redis://...), без него кэш выключен и возвращается nil.
+//
+//nolint:ireturn // intentional
+func newCache(cfg config.Cache) (cashstore.Cache, time.Duration, error) {
+	if cfg.URL == "" {
+		return nil, 0, nil
+	}
+
+	cache, errOpen := cashstore.Open(cfg.URL, cashstore.Options{MaxEntries: cfg.MaxEntries})
+	if errOpen != nil {
+		return nil, 0, errOpen //nolint:wrapcheck // intentional
+	}
+
+	return cache, time.Duration(cfg.TTL), nil
+}
+
+// idempotencyOptions без idempotency_wait дубль сразу получает ошибку.
+func idempotencyOptions(cfg config.Policies) idempotency.Options {
+	return idempotency.Options{TTL: time.Duration(cfg.IdempotencyTTL), Wait: time.Duration(cfg.IdempotencyWait)}
+}
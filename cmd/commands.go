@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/migrate"
+	"github.com/rinatusmanov/jsonrpc20/zeromigrations"
+)
+
+// commands подкоманды бинаря, без аргументов запускается сервер.
+var commands = map[string]func(args []string) error{
+	"bank-group":       runBankGroup,
+	"config":           runConfig,
+	"currency":         runCurrency,
+	"exchange-rates":   runExchangeRates,
+	"ledger-chain":     runLedgerChain,
+	"migrate":          runMigrate,
+	"reconcile":        runReconcile,
+	"revenue-export":   runRevenueExport,
+	"rpc-audit-verify": runRPCAuditVerify,
+}
+
+var errUsage = errors.New("usage")
+
+func runCommand(name string, args []string) {
+	command, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for commandName := range commands {
+			names = append(names, commandName)
+		}
+
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "unknown command %q, available: %v\n", name, names)
+		os.Exit(2) //nolint:gomnd // exit code for usage errors
+	}
+
+	if err := command(args); err != nil {
+		if errors.Is(err, errUsage) {
+			os.Exit(2) //nolint:gomnd // exit code for usage errors
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func openDB() (*sqlx.DB, error) {
+	cfg, errLoadConfig := loadConfig()
+	if errLoadConfig != nil {
+		return nil, errLoadConfig
+	}
+
+	db, errOpen := sqlx.Open("postgres", cfg.Database.URL)
+	if errOpen != nil {
+		return nil, fmt.Errorf("open database: %w", errOpen)
+	}
+
+	configurePool(db, cfg.Database)
+
+	return db, nil
+}
+
+func newMigrator(db *sqlx.DB, logger *zap.Logger) (*migrate.Migrator, error) {
+	migrations, errLoad := migrate.Load(zeromigrations.FS, zeromigrations.Dir)
+	if errLoad != nil {
+		return nil, errLoad //nolint:wrapcheck // intentional
+	}
+
+	return migrate.New(db, migrations, logger), nil
+}
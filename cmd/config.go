@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/uber/jaeger-client-go"
+	jaegerCfg "github.com/uber/jaeger-client-go/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/config"
+)
+
+var errNoClientCA = errors.New("no certificates found")
+
+// configPath файл конфигурации из флага -config или CONFIG_FILE, пустой - только окружение.
+var configPath string
+
+func loadConfig() (config.Config, error) {
+	return config.Load(configPath) //nolint:wrapcheck // intentional
+}
+
+// runConfig
+//
+//	config check [-config path]
+//
+// печатает итоговую конфигурацию (значения по умолчанию, файл, окружение) со скрытыми секретами
+// и проверяет её, включая сертификаты TLS.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, "usage: config check [-config path]")
+
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("config check", flag.ContinueOnError)
+	path := flags.String("config", configPath, "config file")
+
+	if errParse := flags.Parse(args[1:]); errParse != nil {
+		return errUsage
+	}
+
+	cfg, errRead := config.Read(*path)
+	if errRead != nil {
+		return errRead //nolint:wrapcheck // intentional
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	// & в строке подключения печатается как есть
+	encoder.SetEscapeHTML(false)
+
+	if errEncode := encoder.Encode(cfg.Masked()); errEncode != nil {
+		return fmt.Errorf("print config: %w", errEncode)
+	}
+
+	if errValidate := cfg.Validate(); errValidate != nil {
+		return errValidate //nolint:wrapcheck // intentional
+	}
+
+	if _, errTLSConfig := tlsConfig(cfg.TLS); errTLSConfig != nil {
+		return errTLSConfig
+	}
+
+	fmt.Fprintln(os.Stderr, "config is valid")
+
+	return nil
+}
+
+func newLogger(cfg config.Logging) (*zap.Logger, error) {
+	level, errParseLevel := zapcore.ParseLevel(cfg.Level)
+	if errParseLevel != nil {
+		return nil, errParseLevel //nolint:wrapcheck // intentional
+	}
+
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	if cfg.Format == "console" {
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	}
+
+	return zap.New(zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), level)), nil
+}
+
+// tracerConfig переменные JAEGER_*, которых нет в config.Tracing, клиент jaeger по-прежнему читает сам.
+func tracerConfig(cfg config.Tracing) (*jaegerCfg.Configuration, error) {
+	tracer, errFromEnv := jaegerCfg.FromEnv()
+	if errFromEnv != nil {
+		return nil, errFromEnv //nolint:wrapcheck // intentional
+	}
+
+	tracer.Disabled = cfg.Disabled
+	tracer.ServiceName = cfg.ServiceName
+	tracer.Sampler.Type = cfg.SamplerType
+	tracer.Sampler.Param = cfg.SamplerParam
+	tracer.Reporter.CollectorEndpoint = cfg.Endpoint
+	tracer.Reporter.User = cfg.User
+	tracer.Reporter.Password = cfg.Password
+
+	if cfg.AgentHost != "" || cfg.AgentPort != 0 {
+		host, port := cfg.AgentHost, cfg.AgentPort
+		if host == "" {
+			host = jaeger.DefaultUDPSpanServerHost
+		}
+
+		if port == 0 {
+			port = jaeger.DefaultUDPSpanServerPort
+		}
+
+		tracer.Reporter.LocalAgentHostPort = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+
+	return tracer, nil
+}
+
+// tlsConfig nil - TLS выключен.
+func tlsConfig(cfg config.TLS) (*tls.Config, error) {
+	if !cfg.Enabled() {
+		return nil, nil //nolint:nilnil // TLS выключен
+	}
+
+	certificate, errLoadX509KeyPair := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if errLoadX509KeyPair != nil {
+		return nil, fmt.Errorf("load tls certificate: %w", errLoadX509KeyPair)
+	}
+
+	result := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.MinVersion == "1.3" {
+		result.MinVersion = tls.VersionTLS13
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, errReadFile := os.ReadFile(cfg.ClientCAFile)
+		if errReadFile != nil {
+			return nil, fmt.Errorf("read tls client ca: %w", errReadFile)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls client ca %s: %w", cfg.ClientCAFile, errNoClientCA)
+		}
+
+		result.ClientCAs = pool
+		result.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return result, nil
+}
+
+func configurePool(db *sqlx.DB, cfg config.Database) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime))
+	db.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime))
+}
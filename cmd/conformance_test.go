@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab.com/pjrpc/pjrpc/v2"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/generated"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+)
+
+// conformanceCase документированный провайдером вызов: имя метода JSON-RPC, метод сервиса, который должен
+// его обработать, пример params и пример result. Схемы - имена компонентов в swagger.
+type conformanceCase struct {
+	Name           string
+	Method         string
+	Handler        string
+	RequestSchema  string
+	ResponseSchema string
+	Params         json.RawMessage
+	Result         json.RawMessage
+}
+
+// conformanceCases примеры из документации провайдера, по одному и больше на каждый метод SeamlessV2Service.
+//
+//nolint:gochecknoglobals,lll // таблица примеров
+var conformanceCases = []conformanceCase{
+	{
+		Name:           "getBalance",
+		Method:         "getBalance",
+		Handler:        "GetBalance",
+		RequestSchema:  "types.GetBalanceRequest",
+		ResponseSchema: "types.GetBalanceResponse",
+		Params:         json.RawMessage(`{"callerId":1,"playerName":"player1","currency":"EUR","gameId":"riot","sessionId":"sid-1","sessionAlternativeId":"alt-1","bonusId":"bonus-1"}`),
+		Result:         json.RawMessage(`{"balance":10000,"freeroundsLeft":0}`),
+	},
+	{
+		Name:           "withdrawAndDeposit bet",
+		Method:         "withdrawAndDeposit",
+		Handler:        "WithdrawAndDeposit",
+		RequestSchema:  "types.WithdrawAndDepositRequest",
+		ResponseSchema: "types.WithdrawAndDepositResponse",
+		Params:         json.RawMessage(`{"callerId":1,"playerName":"player1","withdraw":400,"deposit":0,"currency":"EUR","transactionRef":"1:UOwGgNHZ0JlC","gameRoundRef":"1wawxl:93","gameId":"riot","reason":"GAME_PLAY","sessionId":"sid-1","sessionAlternativeId":"alt-1","chargeFreerounds":0,"bonusId":"","spinDetails":{"betType":"spin","winType":""}}`),
+		Result:         json.RawMessage(`{"newBalance":9600,"transactionId":"1:UOwGgNHZ0JlC","freeroundsLeft":0}`),
+	},
+	{
+		Name:           "withdrawAndDeposit final win",
+		Method:         "withdrawAndDeposit",
+		Handler:        "WithdrawAndDeposit",
+		RequestSchema:  "types.WithdrawAndDepositRequest",
+		ResponseSchema: "types.WithdrawAndDepositResponse",
+		Params:         json.RawMessage(`{"callerId":1,"playerName":"player1","withdraw":0,"deposit":1200,"currency":"EUR","transactionRef":"1:XzGvT4hT2iQP","gameRoundRef":"1wawxl:93","gameId":"riot","reason":"GAME_PLAY_FINAL","sessionId":"sid-1","sessionAlternativeId":"alt-1","chargeFreerounds":0,"bonusId":"","spinDetails":{"betType":"spin","winType":"standart"}}`),
+		Result:         json.RawMessage(`{"newBalance":10800,"transactionId":"1:XzGvT4hT2iQP","freeroundsLeft":0}`),
+	},
+	{
+		Name:           "rollbackTransaction",
+		Method:         "rollbackTransaction",
+		Handler:        "RollbackTransaction",
+		RequestSchema:  "types.RollbackTransactionRequest",
+		ResponseSchema: "types.RollbackTransactionResponse",
+		Params:         json.RawMessage(`{"callerId":1,"playerName":"player1","transactionRef":"1:UOwGgNHZ0JlC","gameId":"riot","sessionId":"sid-1","sessionAlternativeId":"alt-1"}`),
+		Result:         json.RawMessage(`{}`),
+	},
+	{
+		Name:           "getTransactionHistory",
+		Method:         "getTransactionHistory",
+		Handler:        "GetTransactionHistory",
+		RequestSchema:  "types.GetTransactionHistoryRequest",
+		ResponseSchema: "types.GetTransactionHistoryResponse",
+		Params:         json.RawMessage(`{"callerId":1,"playerName":"player1","from":"2022-09-01T00:00:00Z","to":"2022-09-02T00:00:00Z","gameId":"riot","gameRoundRef":"1wawxl:93","reason":"GAME_PLAY","rolledBack":false,"cursor":0,"limit":1}`),
+		Result:         json.RawMessage(`{"transactions":[{"id":42,"createdAt":"2022-09-01T12:00:00Z","transactionRef":"1:UOwGgNHZ0JlC","gameId":"riot","gameRoundRef":"1wawxl:93","reason":"GAME_PLAY","withdraw":400,"deposit":0,"balance":9600}],"nextCursor":41,"totals":{"count":2,"withdraw":400,"deposit":1200}}`),
+	},
+}
+
+// requestTypes типы params методов сервиса для проверки схемы без неизвестных полей.
+//
+//nolint:gochecknoglobals // справочник
+var requestTypes = map[string]func() interface{}{
+	"GetBalance":            func() interface{} { return new(types.GetBalanceRequest) },
+	"RollbackTransaction":   func() interface{} { return new(types.RollbackTransactionRequest) },
+	"WithdrawAndDeposit":    func() interface{} { return new(types.WithdrawAndDepositRequest) },
+	"GetTransactionHistory": func() interface{} { return new(types.GetTransactionHistoryRequest) },
+}
+
+// TestConformance отправляет каждый пример в обработчик /rpc/ по HTTP и проверяет, что вызов попал в нужный
+// метод сервиса, что все документированные поля params дошли до него без потерь и что result совпадает с примером.
+// База не нужна: сервис подменяется записывающей заглушкой.
+func TestConformance(t *testing.T) {
+	for _, c := range conformanceCases {
+		c := c
+
+		t.Run(c.Name, func(t *testing.T) {
+			newRequest, ok := requestTypes[c.Handler]
+			if !ok {
+				t.Fatalf("unknown handler %q", c.Handler)
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(c.Params))
+			decoder.DisallowUnknownFields()
+
+			if errDecode := decoder.Decode(newRequest()); errDecode != nil {
+				t.Fatalf("documented params do not match %s: %v", c.RequestSchema, errDecode)
+			}
+
+			svc := &recordingService{result: c.Result}
+
+			server := httptest.NewServer(newRPCHandler(svc, nil, 0, zap.NewNop()))
+			defer server.Close()
+
+			response := callRPC(t, server.URL+"/rpc/", c.Method, c.Params)
+
+			if response.Error != nil {
+				t.Fatalf("error response: %v", response.Error)
+			}
+
+			if len(svc.calls) != 1 || svc.calls[0] != c.Handler {
+				t.Fatalf("method %q handled by %v, want %s", c.Method, svc.calls, c.Handler)
+			}
+
+			if response.GetID() != "conformance" {
+				t.Errorf("id %q, want %q", response.GetID(), "conformance")
+			}
+
+			received, _ := json.Marshal(svc.request) //nolint:errchkjson // тип запроса из types
+			if missing := missingFields(c.Params, received); len(missing) != 0 {
+				t.Errorf("fields lost or changed on the way to %s: %v", c.Handler, missing)
+			}
+
+			if !jsonEqual(c.Result, response.Result) {
+				t.Errorf("result %s, want %s", response.Result, c.Result)
+			}
+		})
+	}
+}
+
+func callRPC(t *testing.T, url, method string, params json.RawMessage) pjrpc.Response {
+	t.Helper()
+
+	body, _ := json.Marshal(pjrpc.Request{ //nolint:errchkjson // обычная структура
+		JSONRPC: pjrpc.JSONRPCVersion,
+		ID:      json.RawMessage(`"conformance"`),
+		Method:  method,
+		Params:  params,
+	})
+
+	request, errNewRequest := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if errNewRequest != nil {
+		t.Fatal(errNewRequest)
+	}
+
+	request.Header.Set(pjrpc.ContentTypeHeaderName, pjrpc.ContentTypeHeaderValue)
+
+	resp, errDo := http.DefaultClient.Do(request)
+	if errDo != nil {
+		t.Fatalf("transport: %v", errDo)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("transport: status %d", resp.StatusCode)
+	}
+
+	var response pjrpc.Response
+	if errDecode := json.NewDecoder(resp.Body).Decode(&response); errDecode != nil {
+		t.Fatalf("response is not a JSON-RPC response: %v", errDecode)
+	}
+
+	return response
+}
+
+// missingFields поля документированного примера, которые не дошли до обработчика или дошли с другим значением.
+func missingFields(documented, received json.RawMessage) []string {
+	var want, got map[string]interface{}
+
+	_ = json.Unmarshal(documented, &want)
+	_ = json.Unmarshal(received, &got)
+
+	var missing []string
+
+	for key, value := range want {
+		receivedValue, ok := got[key]
+
+		// omitempty поля с нулевым значением не сериализуются
+		if !ok && isZeroJSON(value) {
+			continue
+		}
+
+		if !reflect.DeepEqual(receivedValue, value) {
+			missing = append(missing, key)
+		}
+	}
+
+	return missing
+}
+
+func isZeroJSON(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case float64:
+		return typed == 0
+	case string:
+		return typed == ""
+	case bool:
+		return !typed
+	default:
+		return false
+	}
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var left, right interface{}
+
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(left, right)
+}
+
+var _ generated.SeamlessV2ServiceServer = (*recordingService)(nil)
+
+// recordingService запоминает, какой метод сервиса вызван и с какими params, и отвечает примером result.
+type recordingService struct {
+	result  json.RawMessage
+	calls   []string
+	request interface{}
+}
+
+func (s *recordingService) GetBalance(
+	_ context.Context,
+	in *types.GetBalanceRequest,
+) (*types.GetBalanceResponse, error) {
+	return record(s, "GetBalance", in, new(types.GetBalanceResponse))
+}
+
+func (s *recordingService) RollbackTransaction(
+	_ context.Context,
+	in *types.RollbackTransactionRequest,
+) (*types.RollbackTransactionResponse, error) {
+	return record(s, "RollbackTransaction", in, new(types.RollbackTransactionResponse))
+}
+
+func (s *recordingService) WithdrawAndDeposit(
+	_ context.Context,
+	in *types.WithdrawAndDepositRequest,
+) (*types.WithdrawAndDepositResponse, error) {
+	return record(s, "WithdrawAndDeposit", in, new(types.WithdrawAndDepositResponse))
+}
+
+func (s *recordingService) GetTransactionHistory(
+	_ context.Context,
+	in *types.GetTransactionHistoryRequest,
+) (*types.GetTransactionHistoryResponse, error) {
+	return record(s, "GetTransactionHistory", in, new(types.GetTransactionHistoryResponse))
+}
+
+func record[T any](s *recordingService, handler string, in interface{}, out *T) (*T, error) {
+	s.calls = append(s.calls, handler)
+	s.request = in
+
+	if errUnmarshal := json.Unmarshal(s.result, out); errUnmarshal != nil {
+		return nil, errUnmarshal //nolint:wrapcheck // intentional
+	}
+
+	return out, nil
+}
+
+type swaggerSpec struct {
+	Paths map[string]struct {
+		Post struct {
+			OperationID string `json:"operationId"`
+			RequestBody struct {
+				Content map[string]struct {
+					Schema swaggerEnvelope `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema swaggerEnvelope `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"post"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+type swaggerEnvelope struct {
+	Properties struct {
+		Method struct {
+			Enum []string `json:"enum"`
+		} `json:"method"`
+		Params swaggerRef `json:"params"`
+		Result swaggerRef `json:"result"`
+	} `json:"properties"`
+}
+
+type swaggerRef struct {
+	Ref string `json:"$ref"`
+}
+
+const (
+	jsonContentType  = "application/json"
+	schemaRefPrefix  = "#/components/schemas/"
+	swaggerPathStart = "/#"
+)
+
+// TestSwaggerConformance сверяет описание методов в swagger с примерами: путь и operationId метода,
+// схемы params и result и наличие в них всех документированных полей.
+func TestSwaggerConformance(t *testing.T) {
+	file, errOpen := os.Open("../swagger/generated.json")
+	if errOpen != nil {
+		t.Fatal(errOpen)
+	}
+
+	defer file.Close()
+
+	var spec swaggerSpec
+	if errDecode := json.NewDecoder(file).Decode(&spec); errDecode != nil {
+		t.Fatalf("decode swagger: %v", errDecode)
+	}
+
+	for _, c := range conformanceCases {
+		c := c
+
+		t.Run(c.Name, func(t *testing.T) {
+			path, ok := spec.Paths[swaggerPathStart+c.Method]
+			if !ok {
+				t.Fatalf("no path %q", swaggerPathStart+c.Method)
+			}
+
+			if path.Post.OperationID != c.Method {
+				t.Errorf("operationId %q, want %q", path.Post.OperationID, c.Method)
+			}
+
+			request := path.Post.RequestBody.Content[jsonContentType].Schema.Properties
+			if len(request.Method.Enum) != 1 || request.Method.Enum[0] != c.Method {
+				t.Errorf("method enum %v, want [%s]", request.Method.Enum, c.Method)
+			}
+
+			if ref := strings.TrimPrefix(request.Params.Ref, schemaRefPrefix); ref != c.RequestSchema {
+				t.Errorf("params schema %q, want %q", ref, c.RequestSchema)
+			}
+
+			result := path.Post.Responses["200"].Content[jsonContentType].Schema.Properties.Result
+			if ref := strings.TrimPrefix(result.Ref, schemaRefPrefix); ref != c.ResponseSchema {
+				t.Errorf("result schema %q, want %q", ref, c.ResponseSchema)
+			}
+
+			for schema, example := range map[string]json.RawMessage{c.RequestSchema: c.Params, c.ResponseSchema: c.Result} {
+				var fields map[string]json.RawMessage
+
+				_ = json.Unmarshal(example, &fields)
+
+				for field := range fields {
+					if _, ok := spec.Components.Schemas[schema].Properties[field]; !ok {
+						t.Errorf("schema %s has no field %q", schema, field)
+					}
+				}
+			}
+		})
+	}
+}
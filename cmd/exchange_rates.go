@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/exchangerate"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var errUnknownCurrency = errors.New("unknown currency")
+
+// runExchangeRates курсы валют для пересчёта ставок из валюты игры в валюту кошелька.
+//
+//	exchange-rates import -file rates.csv [-format csv|json] [-source ecb]
+//	exchange-rates list [-from EUR] [-to SLE]
+func runExchangeRates(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: exchange-rates import|list")
+
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("exchange-rates "+args[0], flag.ContinueOnError)
+	file := flags.String("file", "", "csv or json file with rates")
+	format := flags.String("format", "", "csv or json, by file extension by default")
+	source := flags.String("source", "", "where the rates come from, stored with every rate")
+	from := flags.String("from", "", "source currency code, all by default")
+	to := flags.String("to", "", "target currency code, all by default")
+
+	if errParse := flags.Parse(args[1:]); errParse != nil {
+		return errUsage
+	}
+
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "import":
+		if *file == "" {
+			flags.Usage()
+
+			return errUsage
+		}
+
+		if *format == "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+		}
+
+		input, errOpen := os.Open(*file)
+		if errOpen != nil {
+			return fmt.Errorf("open rates: %w", errOpen)
+		}
+
+		defer input.Close()
+
+		rows, errParseRates := exchangerate.Parse(*format, input)
+		if errParseRates != nil {
+			return fmt.Errorf("parse %s: %w", *file, errParseRates)
+		}
+
+		imported, errImport := exchangerate.Import(ctx, db, rows, *source)
+		if errImport != nil {
+			return fmt.Errorf("import: %w", errImport)
+		}
+
+		fmt.Fprintf(os.Stdout, "imported %d rates\n", imported)
+
+		return nil
+	case "list":
+		tx, errBeginTxx := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+		if errBeginTxx != nil {
+			return fmt.Errorf("begin: %w", errBeginTxx)
+		}
+
+		defer tx.Rollback() //nolint:errcheck // только чтение
+
+		currencies, errGetCurrencies := repo.GetCurrencies(ctx, tx)
+		if errGetCurrencies != nil {
+			return fmt.Errorf("load currencies: %w", errGetCurrencies)
+		}
+
+		codes := make(map[int]string, len(currencies))
+		ids := make(map[string]int, len(currencies))
+
+		for _, currency := range currencies {
+			codes[currency.ID] = currency.Code
+			ids[currency.Code] = currency.ID
+		}
+
+		for _, code := range []string{*from, *to} {
+			if _, ok := ids[strings.ToUpper(code)]; code != "" && !ok {
+				return fmt.Errorf("%w: %s", errUnknownCurrency, code)
+			}
+		}
+
+		rates, errGetExchangeRates := repo.GetExchangeRates(ctx, tx, ids[strings.ToUpper(*from)], ids[strings.ToUpper(*to)])
+		if errGetExchangeRates != nil {
+			return fmt.Errorf("load rates: %w", errGetExchangeRates)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd // отступ колонок
+		fmt.Fprintln(writer, "FROM\tTO\tRATE\tEFFECTIVE AT\tSOURCE")
+
+		for _, rate := range rates {
+			fmt.Fprintf(
+				writer,
+				"%s\t%s\t%s\t%s\t%s\n",
+				codes[rate.FromCurrencyID],
+				codes[rate.ToCurrencyID],
+				rate.Rate,
+				rate.EffectiveAt.Format("2006-01-02 15:04:05"),
+				rate.Source,
+			)
+		}
+
+		return writer.Flush() //nolint:wrapcheck // intentional
+	default:
+		fmt.Fprintf(os.Stderr, "unknown exchange-rates command %q\n", args[0])
+
+		return errUsage
+	}
+}
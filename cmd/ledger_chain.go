@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/ledgerchain"
+)
+
+var errLedgerChainBroken = errors.New("ledger chain is broken")
+
+// runLedgerChain обслуживание цепочки хэшей billing.payments.
+//
+//	ledger-chain verify [-public-key base64]   проверить цепочки и снимки, отчёт в json
+//	ledger-chain backfill                      сцепить платежи, созданные до появления цепочки
+//	ledger-chain checkpoint                    снять подписанный снимок ключом из LEDGER_CHECKPOINT_KEY
+//	ledger-chain keygen                        сгенерировать пару ключей для снимков
+func runLedgerChain(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: ledger-chain verify|backfill|checkpoint|keygen")
+
+		return errUsage
+	}
+
+	switch args[0] {
+	case "verify":
+		return runLedgerChainVerify(args[1:])
+	case "backfill":
+		return runLedgerChainBackfill()
+	case "checkpoint":
+		return runLedgerChainCheckpoint()
+	case "keygen":
+		return runLedgerChainKeygen()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown ledger-chain command %q\n", args[0])
+
+		return errUsage
+	}
+}
+
+func runLedgerChainVerify(args []string) error {
+	flags := flag.NewFlagSet("ledger-chain verify", flag.ContinueOnError)
+	publicKey := flags.String("public-key", "", "base64 ed25519 public key to check checkpoint signatures with")
+
+	if errParse := flags.Parse(args); errParse != nil {
+		return errUsage
+	}
+
+	var key ed25519.PublicKey
+
+	if *publicKey != "" {
+		parsed, errParsePublicKey := ledgerchain.ParsePublicKey(*publicKey)
+		if errParsePublicKey != nil {
+			return fmt.Errorf("parse -public-key: %w", errParsePublicKey)
+		}
+
+		key = parsed
+	}
+
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	report, errVerify := ledgerchain.Verify(context.Background(), db, key)
+	if errVerify != nil {
+		return fmt.Errorf("verify: %w", errVerify)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if errEncode := encoder.Encode(report); errEncode != nil {
+		return fmt.Errorf("write report: %w", errEncode)
+	}
+
+	if len(report.Problems) != 0 {
+		return fmt.Errorf("%w: %d problems", errLedgerChainBroken, len(report.Problems))
+	}
+
+	return nil
+}
+
+func runLedgerChainBackfill() error {
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	count, errBackfill := ledgerchain.Backfill(context.Background(), db)
+	if errBackfill != nil {
+		return fmt.Errorf("backfill after %d payments: %w", count, errBackfill)
+	}
+
+	fmt.Fprintf(os.Stdout, "%d payments chained\n", count)
+
+	return nil
+}
+
+func runLedgerChainCheckpoint() error {
+	cfg, errLoadConfig := loadConfig()
+	if errLoadConfig != nil {
+		return errLoadConfig
+	}
+
+	key, errParsePrivateKey := ledgerchain.ParsePrivateKey(cfg.Ledger.CheckpointKey)
+	if errParsePrivateKey != nil {
+		return fmt.Errorf("parse ledger.checkpoint_key: %w", errParsePrivateKey)
+	}
+
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	checkpoint, errCheckpoint := ledgerchain.Checkpoint(context.Background(), db, key)
+	if errCheckpoint != nil {
+		return fmt.Errorf("checkpoint: %w", errCheckpoint)
+	}
+
+	if checkpoint == nil {
+		fmt.Fprintln(os.Stdout, "nothing new to checkpoint")
+
+		return nil
+	}
+
+	fmt.Fprintf(
+		os.Stdout,
+		"checkpoint %d: last link %d, %d wallets, root %s\n",
+		checkpoint.ID,
+		checkpoint.LastLinkID,
+		checkpoint.Wallets,
+		checkpoint.RootHash,
+	)
+
+	return nil
+}
+
+func runLedgerChainKeygen() error {
+	publicKey, privateKey, errGenerateKey := ed25519.GenerateKey(rand.Reader)
+	if errGenerateKey != nil {
+		return fmt.Errorf("generate key: %w", errGenerateKey)
+	}
+
+	fmt.Fprintf(os.Stdout, "LEDGER_CHECKPOINT_KEY=%s\n", base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	fmt.Fprintf(os.Stdout, "public key: %s\n", base64.StdEncoding.EncodeToString(publicKey))
+
+	return nil
+}
@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	jaegerCfg "github.com/uber/jaeger-client-go/config"
 	jaegerZap "github.com/uber/jaeger-client-go/log/zap"
 	jProm "github.com/uber/jaeger-lib/metrics/prometheus"
@@ -18,81 +26,328 @@ import (
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zapio"
 
-	_ "github.com/lib/pq"
-
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin"
+	adminGenerated "github.com/rinatusmanov/jsonrpc20/internal/pkg/admin/generated"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/config"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/health"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/idempotency"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/ledgerchain"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/metrics"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/migrate"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/outbox"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/revenue"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcaudit"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcbatch"
 	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
 	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/generated"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/tracing"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/webhook"
+	"github.com/rinatusmanov/jsonrpc20/zeromigrations"
 )
 
 func main() {
+	migrateOnStart := flag.Bool("migrate", false, "apply pending migrations before serving")
+	flag.StringVar(&configPath, "config", os.Getenv("CONFIG_FILE"), "JSON config file, environment variables override it")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		runCommand(flag.Arg(0), flag.Args()[1:])
+
+		return
+	}
+
+	cfg, errLoadConfig := loadConfig()
+	if errLoadConfig != nil {
+		fmt.Fprintln(os.Stderr, errLoadConfig)
+		os.Exit(1)
+	}
+
 	// инициализация логгера
-	atom := zap.NewAtomicLevel()
-	logger := zap.New(
-		zapcore.NewCore(
-			zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
-			zapcore.Lock(os.Stdout),
-			atom,
-		),
-	).With(zap.String("service", "SeamlessV2ServiceServer"))
+	baseLogger, errNewLogger := newLogger(cfg.Logging)
+	if errNewLogger != nil {
+		fmt.Fprintln(os.Stderr, errNewLogger)
+		os.Exit(1)
+	}
 
-	atom.SetLevel(zap.InfoLevel)
+	logger := baseLogger.With(zap.String("service", "SeamlessV2ServiceServer"))
+
+	// код выхода выставляется последним, когда отработали остальные defer
+	exitCode := 0
+
+	defer func() {
+		_ = logger.Sync()
+
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// SIGTERM от оркестратора запускает остановку: фоновые задачи завершаются, http серверы дожидаются запросов
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// инициализация клиента jaeger
-	cfg, errFromEnv := jaegerCfg.FromEnv()
-	if errFromEnv != nil {
-		logger.Panic("Could not parse Jaeger env vars", zap.Error(errFromEnv))
+	tracerCfg, errTracerConfig := tracerConfig(cfg.Tracing)
+	if errTracerConfig != nil {
+		logger.Panic("Could not parse Jaeger env vars", zap.Error(errTracerConfig))
 	}
 
-	cfg.ServiceName = "SeamlessV2ServiceServer"
+	// registry отдаётся на /metrics порта админки вместе с метриками самого jaeger-клиента
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
 
-	factory := jProm.New(jProm.WithRegisterer(prometheus.NewPedanticRegistry()))
+	factory := jProm.New(jProm.WithRegisterer(registry))
 
-	tracer, closer, errNewTracer := cfg.NewTracer(jaegerCfg.Metrics(factory))
-	if errFromEnv != nil {
-		logger.Panic("Could not parse Jaeger env vars", zap.Error(errNewTracer))
+	tracer, closer, errNewTracer := tracerCfg.NewTracer(jaegerCfg.Metrics(factory))
+	if errNewTracer != nil {
+		logger.Panic("Could not create Jaeger tracer", zap.Error(errNewTracer))
 	}
 
+	// отправляет в jaeger накопленные span'ы, в том числе запросов, завершённых во время остановки
 	defer func() {
 		_ = closer.Close()
 	}()
 
 	opentracing.SetGlobalTracer(jaegerZap.NewLoggingTracer(logger, tracer))
 
-	srv := pjrpc.NewServerHTTP()
-	srv.SetLogger(&zapio.Writer{Log: logger, Level: zapcore.InfoLevel})
+	// каждый запрос репозитория внутри вызова получает дочерний span с текстом SQL
+	db := sqlx.NewDb(sql.OpenDB(tracing.NewConnector(&pq.Driver{}, cfg.Database.URL)), "postgres")
+	configurePool(db, cfg.Database)
+
+	defer db.Close()
+
+	registry.MustRegister(collectors.NewDBStatsCollector(db.DB, "billing"))
+
+	if *migrateOnStart {
+		migrator, errNewMigrator := newMigrator(db, logger)
+		if errNewMigrator != nil {
+			logger.Panic("Could not load migrations", zap.Error(errNewMigrator))
+		}
 
-	db, errOpen := sqlx.Open("postgres", os.Getenv("CONNECTION_STRING"))
-	if errOpen != nil {
-		logger.Panic("Could not open database", zap.Error(errOpen))
+		if _, errUp := migrator.Up(ctx, 0); errUp != nil {
+			logger.Panic("Could not apply migrations", zap.Error(errUp))
+		}
 	}
 
-	rpcService := seamlessv2.NewRPCService(db, logger)
+	// фоновые задачи останавливаются по ctx, база закрывается только после них
+	var jobs sync.WaitGroup
 
-	generated.RegisterSeamlessV2ServiceServer(srv, rpcService, TraceMiddleWare)
+	defer jobs.Wait()
 
-	http.Handle("/rpc/", srv)
+	runJob := func(job func(ctx context.Context)) {
+		jobs.Add(1)
 
-	if errListenAndServe := http.ListenAndServe(":8086", nil); errListenAndServe != nil {
-		panic(errListenAndServe)
+		go func() {
+			defer jobs.Done()
+
+			job(ctx)
+		}()
+	}
+
+	if sink := newOutboxSink(cfg.Outbox, logger); sink != nil {
+		runJob(outbox.NewRelay(db, sink, logger).Run)
+	}
+
+	runJob(webhook.NewDispatcher(db, nil, logger).Run)
+
+	runJob(func(ctx context.Context) { revenue.RunRefresher(ctx, db, logger) })
+
+	// без ключа подписи снимки цепочки платежей не снимаются
+	if cfg.Ledger.CheckpointKey != "" {
+		checkpointKey, errParsePrivateKey := ledgerchain.ParsePrivateKey(cfg.Ledger.CheckpointKey)
+		if errParsePrivateKey != nil {
+			logger.Panic("Could not parse ledger checkpoint key", zap.Error(errParsePrivateKey))
+		}
+
+		runJob(func(ctx context.Context) { ledgerchain.RunCheckpointer(ctx, db, checkpointKey, logger) })
+	}
+
+	cache, cacheTTL, errOpenCache := newCache(cfg.Cache)
+	if errOpenCache != nil {
+		logger.Panic("Could not open cache", zap.Error(errOpenCache))
 	}
+
+	cacheStats := walletcache.NewStats()
+	registry.MustRegister(metrics.NewCacheCollector(cacheStats))
+
+	rpcMetrics := metrics.New(registry)
+
+	var store seamlessv2.Store = seamlessv2.NewPostgresStore(db)
+	if cache != nil {
+		defer func() {
+			_ = cache.Close()
+		}()
+
+		store = walletcache.NewStore(store, cache, cacheTTL, cacheStats, logger)
+	}
+
+	// снаружи кэша: код валюты для меток метрик берётся из закэшированного справочника
+	store = metrics.NewStore(store, rpcMetrics)
+
+	// без cache.url захват выключен, дубли transactionRef ловит только проверка в базе
+	rpcService := seamlessv2.NewRPCService(
+		store,
+		idempotency.New(cache, idempotencyOptions(cfg.Policies), logger),
+		cfg.Policies.StartingBalance,
+		logger,
+	)
+
+	auditStore := rpcaudit.NewStore(db)
+	auditWriter := rpcaudit.NewWriter(auditStore, logger)
+
+	runJob(auditWriter.Run)
+
+	if retention := rpcAuditRetention(cfg.Audit); retention > 0 {
+		runJob(func(ctx context.Context) { rpcaudit.RunRetention(ctx, auditStore, retention, logger) })
+	}
+
+	healthChecker := newHealth(db, cache, time.Duration(cfg.Server.HealthTimeout), logger)
+
+	// /healthz и /readyz для оркестратора, наружу через nginx не проксируются
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", healthChecker.LiveHandler())
+	mux.Handle("/readyz", healthChecker.ReadyHandler())
+
+	mux.Handle("/rpc/", newRPCHandler(
+		rpcService,
+		db,
+		cfg.Server.MaxBatchSize,
+		logger,
+		rpcMetrics.Middleware("seamless"),
+		rpcaudit.Middleware(auditWriter, rpcaudit.NewRedactor(cfg.Audit.Redact...)),
+	))
+
+	// административное API слушает отдельный порт, чтобы его не было видно провайдерам
+	adminSrv := pjrpc.NewServerHTTP()
+	adminSrv.SetLogger(&zapio.Writer{Log: logger, Level: zapcore.InfoLevel})
+
+	adminGenerated.RegisterAdminServiceServer(
+		adminSrv,
+		admin.NewRPCService(db, cache, logger),
+		tracing.Middleware("admin"),
+		rpcMetrics.Middleware("admin"),
+		admin.AuditMiddleware(db, logger),
+	)
+
+	// всё под /admin/ меняет состояние сервиса и доступно только с токеном
+	adminAPI := http.NewServeMux()
+	adminAPI.Handle("/admin/rpc/", adminSrv)
+	// POST снимает готовность перед остановкой, DELETE возвращает
+	adminAPI.Handle("/admin/drain", healthChecker.DrainHandler())
+
+	// кэш в памяти процесса CLI сбрасывает через этот адрес, см. openAdminCache
+	if cache != nil {
+		adminAPI.Handle(adminCachePath, cashstore.Handler(cache))
+	}
+
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/admin/", admin.Authenticate(cfg.Server.AdminToken, adminAPI))
+	// попадания в кэш кошельков по видам данных
+	adminMux.Handle("/debug/walletcache", cacheStats)
+	adminMux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	// TLS только на публичном порту, порт админки наружу не публикуется
+	publicTLS, errTLSConfig := tlsConfig(cfg.TLS)
+	if errTLSConfig != nil {
+		logger.Panic("Could not load TLS config", zap.Error(errTLSConfig))
+	}
+
+	readHeaderTimeout := time.Duration(cfg.Server.ReadHeaderTimeout)
+	servers := []*http.Server{
+		{Addr: cfg.Server.Addr, Handler: mux, TLSConfig: publicTLS, ReadHeaderTimeout: readHeaderTimeout},
+		{Addr: cfg.Server.AdminAddr, Handler: adminMux, ReadHeaderTimeout: readHeaderTimeout},
+	}
+
+	listeners, errListen := listen(servers)
+	if errListen != nil {
+		logger.Panic("Could not listen", zap.Error(errListen))
+	}
+
+	if errServe := serve(ctx, servers, listeners, healthChecker, newShutdownOptions(cfg.Server), logger); errServe != nil {
+		logger.Error("Stopped after server error", zap.Error(errServe))
+
+		exitCode = 1
+	}
+
+	// после серверов: фоновые задачи ещё работали, пока дожидались запросов
+	stop()
+}
+
+// newRPCHandler обработчик /rpc/ вокруг сервиса. TestConformance прогоняет через него примеры провайдера,
+// поэтому всё, что влияет на маршрутизацию вызовов, собирается здесь.
+func newRPCHandler(
+	svc generated.SeamlessV2ServiceServer,
+	db *sqlx.DB,
+	maxBatchSize int,
+	logger *zap.Logger,
+	middlewares ...pjrpc.Middleware,
+) http.Handler {
+	srv := pjrpc.NewServerHTTP()
+	srv.SetLogger(&zapio.Writer{Log: logger, Level: zapcore.InfoLevel})
+
+	// maxBatchSize максимум вызовов в пакете, 0 - rpcbatch.DefaultMaxBatchSize
+	batchHandler := rpcbatch.NewHandler(srv, db, maxBatchSize, logger)
+
+	middlewares = append([]pjrpc.Middleware{tracing.Middleware("seamless")}, middlewares...)
+	generated.RegisterSeamlessV2ServiceServer(srv, svc, append(middlewares, batchHandler.Middleware)...)
+
+	return batchHandler
 }
 
-func TraceMiddleWare(next pjrpc.Handler) pjrpc.Handler {
-	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
-		tracer := opentracing.GlobalTracer()
+// newHealth готовность: база отвечает, миграции бинаря применены, кэш (если задан) доступен.
+// timeout ограничивает все проверки /readyz вместе.
+func newHealth(db *sqlx.DB, cache cashstore.Cache, timeout time.Duration, logger *zap.Logger) *health.Health {
+	migrations, errLoad := migrate.Load(zeromigrations.FS, zeromigrations.Dir)
+	if errLoad != nil {
+		logger.Panic("Could not load migrations", zap.Error(errLoad))
+	}
 
-		span := tracer.StartSpan("RPCService.MiddleWare")
+	var expected string
+	if len(migrations) > 0 {
+		expected = migrations[len(migrations)-1].Version
+	}
 
-		defer span.Finish()
+	checker := health.New(timeout)
+	checker.Add("database", health.Database(db))
+	checker.Add("migrations", health.Migrations(db, expected))
 
-		now := time.Now()
-		res, err := next(opentracing.ContextWithSpan(ctx, span), params)
-		dur := time.Since(now).Seconds()
+	if cache != nil {
+		checker.Add("cache", health.Cache(cache))
+	}
 
-		span.SetTag("duration", dur)
-		span.SetTag("error", err)
-		span.SetTag("result", res)
+	return checker
+}
 
-		return res, err
+// newOutboxSink webhook_url отправляет события вебхуком, file пишет их в файл
+// (stdout для вывода в консоль), без них relay не запускается.
+//
+//nolint:ireturn // intentional
+func newOutboxSink(cfg config.Outbox, logger *zap.Logger) outbox.Sink {
+	if cfg.WebhookURL != "" {
+		return outbox.NewWebhookSink(cfg.WebhookURL, nil)
 	}
+
+	switch cfg.File {
+	case "":
+		return nil
+	case "stdout":
+		return outbox.NewWriterSink(os.Stdout)
+	default:
+		file, errOpenFile := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if errOpenFile != nil {
+			logger.Panic("Could not open outbox file", zap.Error(errOpenFile))
+		}
+
+		return outbox.NewWriterSink(file)
+	}
+}
+
+// rpcAuditRetention сколько хранить журнал вызовов, 0 - записи не удаляются.
+func rpcAuditRetention(cfg config.Audit) time.Duration {
+	return time.Duration(cfg.RetentionDays) * 24 * time.Hour //nolint:gomnd // часов в сутках
 }
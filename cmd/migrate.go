@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/migrate"
+)
+
+// runMigrate миграции схемы, встроенные в бинарь.
+//
+//	migrate up [-steps N]         применить новые миграции, по умолчанию все
+//	migrate down [-steps N]       откатить последние миграции, по умолчанию одну
+//	migrate redo                  откатить и заново применить последнюю миграцию
+//	migrate status                применённые и ожидающие миграции
+//	migrate baseline -version V   отметить миграции до V применёнными, для баз после migrate.sh
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: migrate up|down|redo|status|baseline")
+
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := flags.Int("steps", 0, "number of migrations, 0 for the default")
+	version := flags.String("version", "", "baseline version, e.g. 2022_090_07_01")
+
+	if errParse := flags.Parse(args[1:]); errParse != nil {
+		return errUsage
+	}
+
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	migrator, errNewMigrator := newMigrator(db, zap.NewNop())
+	if errNewMigrator != nil {
+		return errNewMigrator
+	}
+
+	ctx := context.Background()
+
+	var (
+		done   []migrate.Migration
+		errRun error
+	)
+
+	switch args[0] {
+	case "up":
+		done, errRun = migrator.Up(ctx, *steps)
+	case "down":
+		done, errRun = migrator.Down(ctx, *steps)
+	case "redo":
+		var redone *migrate.Migration
+		if redone, errRun = migrator.Redo(ctx); redone != nil {
+			done = append(done, *redone)
+		}
+	case "baseline":
+		if *version == "" {
+			flags.Usage()
+
+			return errUsage
+		}
+
+		done, errRun = migrator.Baseline(ctx, *version)
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n", args[0])
+
+		return errUsage
+	}
+
+	for _, migration := range done {
+		fmt.Fprintf(os.Stdout, "%s %s_%s\n", args[0], migration.Version, migration.Name)
+	}
+
+	if errRun != nil {
+		return fmt.Errorf("%s: %w", args[0], errRun)
+	}
+
+	if len(done) == 0 {
+		fmt.Fprintln(os.Stdout, "nothing to do")
+	}
+
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, errStatus := migrator.Status(ctx)
+	if errStatus != nil {
+		return fmt.Errorf("status: %w", errStatus)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd // отступ колонок
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+
+		switch {
+		case status.AppliedAt != nil && status.Up == "":
+			state = "applied, missing in binary"
+		case status.ChecksumMismatch:
+			state = "applied, changed since"
+		case status.AppliedAt != nil:
+			state = "applied"
+		}
+
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+
+	return writer.Flush() //nolint:wrapcheck // intentional
+}
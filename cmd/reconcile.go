@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/reconcile"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// runReconcile сверяет выписку провайдера с billing.payments за период и печатает отчёт.
+//
+//	reconcile -statement statement.csv -from 2022-08-01 -to 2022-09-01 [-output csv] [-out report.csv]
+func runReconcile(args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	statementPath := flags.String("statement", "", "provider statement file (.csv or .json)")
+	format := flags.String("format", "", "statement format: csv or json, by default taken from the file extension")
+	fromFlag := flags.String("from", "", "period start, inclusive (YYYY-MM-DD)")
+	toFlag := flags.String("to", "", "period end, exclusive (YYYY-MM-DD)")
+	output := flags.String("output", "json", "report format: json (discrepancies and totals) or csv (discrepancies)")
+	outPath := flags.String("out", "", "report file, stdout by default")
+
+	if errParse := flags.Parse(args); errParse != nil {
+		return errUsage
+	}
+
+	if *statementPath == "" || *fromFlag == "" || *toFlag == "" {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	from, errFrom := time.Parse("2006-01-02", *fromFlag)
+	if errFrom != nil {
+		return fmt.Errorf("parse -from: %w", errFrom)
+	}
+
+	to, errTo := time.Parse("2006-01-02", *toFlag)
+	if errTo != nil {
+		return fmt.Errorf("parse -to: %w", errTo)
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*statementPath)), ".")
+	}
+
+	statementFile, errOpen := os.Open(*statementPath)
+	if errOpen != nil {
+		return fmt.Errorf("open statement: %w", errOpen)
+	}
+
+	defer statementFile.Close()
+
+	statement, errParseStatement := reconcile.ParseStatement(statementFile, *format)
+	if errParseStatement != nil {
+		return fmt.Errorf("parse statement: %w", errParseStatement)
+	}
+
+	ledger, errLoadLedger := loadLedger(context.Background(), statement, from, to)
+	if errLoadLedger != nil {
+		return errLoadLedger
+	}
+
+	report := reconcile.Reconcile(statement, ledger)
+
+	var out io.Writer = os.Stdout
+
+	if *outPath != "" {
+		file, errCreate := os.Create(*outPath)
+		if errCreate != nil {
+			return fmt.Errorf("create report: %w", errCreate)
+		}
+
+		defer file.Close()
+
+		out = file
+	}
+
+	switch *output {
+	case "csv":
+		return reconcile.WriteCSV(out, report) //nolint:wrapcheck // intentional
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(report) //nolint:wrapcheck // intentional
+	default:
+		return fmt.Errorf("%w: unknown output %q", errUsage, *output)
+	}
+}
+
+func loadLedger(ctx context.Context, statement []reconcile.Entry, from, to time.Time) ([]reconcile.Entry, error) {
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return nil, errOpenDB
+	}
+
+	defer db.Close()
+
+	tx, errBeginTxx := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if errBeginTxx != nil {
+		return nil, fmt.Errorf("begin: %w", errBeginTxx)
+	}
+
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	refs := make([]string, 0, len(statement))
+	for _, entry := range statement {
+		refs = append(refs, entry.TransactionRef)
+	}
+
+	rows, errGetLedgerEntries := repo.GetLedgerEntries(ctx, tx, from, to, refs)
+	if errGetLedgerEntries != nil {
+		return nil, fmt.Errorf("load ledger: %w", errGetLedgerEntries)
+	}
+
+	ledger := make([]reconcile.Entry, 0, len(rows))
+	for _, row := range rows {
+		ledger = append(ledger, reconcile.Entry{
+			TransactionRef: row.TransactionRef,
+			Currency:       row.Currency,
+			GameID:         row.GameID,
+			Withdraw:       row.Withdraw,
+			Deposit:        row.Deposit,
+			RolledBack:     row.RollBackAt != nil,
+		})
+	}
+
+	return ledger, nil
+}
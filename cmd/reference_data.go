@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// runCurrency справочник валют, те же операции, что и в admin API.
+//
+//	currency list
+//	currency enable CODE
+//	currency disable CODE
+//	currency add -id 925 -code SLE -digits 2 -name "Sierra Leonean leone" [-replaces SLL]
+func runCurrency(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: currency list|enable|disable|add")
+
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return runAdminCall("listCurrencies", &types.ListCurrenciesRequest{},
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				return svc.ListCurrencies(ctx, &types.ListCurrenciesRequest{})
+			})
+	case "enable", "disable":
+		if len(args) != 2 { //nolint:gomnd // subcommand and code
+			fmt.Fprintf(os.Stderr, "usage: currency %s CODE\n", args[0])
+
+			return errUsage
+		}
+
+		in := &types.SetCurrencyEnabledRequest{Code: args[1], Enabled: args[0] == "enable"}
+
+		return runAdminCall("setCurrencyEnabled", in,
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				return svc.SetCurrencyEnabled(ctx, in)
+			})
+	case "add":
+		flags := flag.NewFlagSet("currency add", flag.ContinueOnError)
+		id := flags.Int("id", 0, "ISO 4217 numeric code")
+		code := flags.String("code", "", "ISO 4217 alphabetic code")
+		digits := flags.Int("digits", 2, "number of digits after the decimal separator") //nolint:gomnd // most currencies
+		name := flags.String("name", "", "currency name")
+		replaces := flags.String("replaces", "", "code of the currency to disable, e.g. SLL for SLE")
+
+		if errParse := flags.Parse(args[1:]); errParse != nil {
+			return errUsage
+		}
+
+		in := &types.CreateCurrencyRequest{
+			ID:                                     *id,
+			Code:                                   *code,
+			NumberOfDigitsAfterTheDecimalSeparator: *digits,
+			Name:                                   *name,
+			Replaces:                               *replaces,
+		}
+
+		return runAdminCall("createCurrency", in,
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				return svc.CreateCurrency(ctx, in)
+			})
+	default:
+		fmt.Fprintf(os.Stderr, "unknown currency command %q\n", args[0])
+
+		return errUsage
+	}
+}
+
+// runBankGroup банковские группы и настройки кошельков операторов.
+//
+//	bank-group list [-currency EUR]
+//	bank-group add -id BANK_EUR_VIP -currency EUR
+//	bank-group update -id BANK_EUR_VIP [-currencies EUR,USD] [-starting-balance N] [-bet-min N] [-bet-max N]
+//	                  [-rounding-step N] [-rounding-mode none|down|half_up] [-free-rounds allow|deny] [-free-rounds-max N]
+//	                  [-conversion=true|false]
+//	bank-group attach -operator 42 -id BANK_EUR_VIP   пустой -id отвязывает оператора
+//	bank-group delete -id BANK_EUR_VIP
+func runBankGroup(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: bank-group list|add|update|attach|delete")
+
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("bank-group "+args[0], flag.ContinueOnError)
+	id := flags.String("id", "", "bank group id")
+	currency := flags.String("currency", "", "currency code")
+	operator := flags.Int("operator", 0, "operator id")
+	currencies := flags.String("currencies", "", "comma separated allowed currency codes")
+	startingBalance := flags.Int("starting-balance", 0, "balance of a new wallet")
+	betMin := flags.Int("bet-min", 0, "minimal bet")
+	betMax := flags.Int("bet-max", 0, "maximal bet, 0 for no limit")
+	roundingStep := flags.Int("rounding-step", 1, "wins are rounded and bets must be a multiple of the step")
+	roundingMode := flags.String("rounding-mode", "", "none, down or half_up")
+	freeRounds := flags.String("free-rounds", "", "allow or deny")
+	freeRoundsMax := flags.Int("free-rounds-max", 0, "maximal chargeFreerounds per call, 0 for no limit")
+	conversion := flags.Bool("conversion", false, "convert bets in the game currency to the wallet currency")
+
+	if errParse := flags.Parse(args[1:]); errParse != nil {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		in := &types.ListBankGroupsRequest{CurrencyCode: *currency}
+
+		return runAdminCall("listBankGroups", in,
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				return svc.ListBankGroups(ctx, in)
+			})
+	case "add":
+		in := &types.CreateBankGroupRequest{ID: *id, CurrencyCode: *currency}
+
+		return runAdminCall("createBankGroup", in,
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				return svc.CreateBankGroup(ctx, in)
+			})
+	case "update":
+		in := &types.UpdateBankGroupRequest{ID: *id}
+
+		return runAdminCall("updateBankGroup", in,
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				// updateBankGroup заменяет настройки целиком, не указанные флаги берутся из текущих настроек
+				if errCurrent := currentBankGroup(ctx, svc, in); errCurrent != nil {
+					return nil, errCurrent
+				}
+
+				flags.Visit(func(set *flag.Flag) {
+					switch set.Name {
+					case "currencies":
+						in.CurrencyCodes = strings.Split(*currencies, ",")
+					case "starting-balance":
+						in.StartingBalance = *startingBalance
+					case "bet-min":
+						in.BetMin = *betMin
+					case "bet-max":
+						in.BetMax = *betMax
+					case "rounding-step":
+						in.RoundingStep = *roundingStep
+					case "rounding-mode":
+						in.RoundingMode = *roundingMode
+					case "free-rounds":
+						in.FreeRounds = *freeRounds
+					case "free-rounds-max":
+						in.FreeRoundsMaxCharge = *freeRoundsMax
+					case "conversion":
+						in.CurrencyConversion = *conversion
+					}
+				})
+
+				return svc.UpdateBankGroup(ctx, in)
+			})
+	case "attach":
+		in := &types.SetOperatorBankGroupRequest{OperatorID: *operator, BankGroupID: *id}
+
+		return runAdminCall("setOperatorBankGroup", in,
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				return svc.SetOperatorBankGroup(ctx, in)
+			})
+	case "delete":
+		in := &types.DeleteBankGroupRequest{ID: *id}
+
+		return runAdminCall("deleteBankGroup", in,
+			func(ctx context.Context, svc *admin.RPCService) (interface{}, error) {
+				return svc.DeleteBankGroup(ctx, in)
+			})
+	default:
+		fmt.Fprintf(os.Stderr, "unknown bank-group command %q\n", args[0])
+
+		return errUsage
+	}
+}
+
+var errBankGroupNotFound = errors.New("bank group not found")
+
+func currentBankGroup(ctx context.Context, svc *admin.RPCService, in *types.UpdateBankGroupRequest) error {
+	list, errListBankGroups := svc.ListBankGroups(ctx, &types.ListBankGroupsRequest{})
+	if errListBankGroups != nil {
+		return errListBankGroups //nolint:wrapcheck // intentional
+	}
+
+	for _, bankGroup := range list.BankGroups {
+		if bankGroup.ID != in.ID {
+			continue
+		}
+
+		in.CurrencyCodes = bankGroup.CurrencyCodes
+		in.StartingBalance = bankGroup.StartingBalance
+		in.BetMin = bankGroup.BetMin
+		in.BetMax = bankGroup.BetMax
+		in.RoundingStep = bankGroup.RoundingStep
+		in.RoundingMode = bankGroup.RoundingMode
+		in.FreeRounds = bankGroup.FreeRounds
+		in.FreeRoundsMaxCharge = bankGroup.FreeRoundsMaxCharge
+		in.CurrencyConversion = bankGroup.CurrencyConversion
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errBankGroupNotFound, in.ID)
+}
+
+// runAdminCall выполняет метод admin API от имени cli:$USER и пишет вызов в billing.admin_audit,
+// как это делает AuditMiddleware для http.
+func runAdminCall(
+	method string,
+	params interface{},
+	call func(ctx context.Context, svc *admin.RPCService) (interface{}, error),
+) error {
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	cache, errOpenCache := openAdminCache()
+	if errOpenCache != nil {
+		return errOpenCache
+	}
+
+	if cache != nil {
+		defer cache.Close()
+	}
+
+	adminName := "cli:" + os.Getenv("USER")
+	ctx := admin.ContextWithAdmin(context.Background(), adminName)
+
+	// stdout занят результатом, предупреждения (например, несброшенный кэш) идут в stderr
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
+		zapcore.Lock(os.Stderr),
+		zapcore.WarnLevel,
+	))
+
+	result, errCall := call(ctx, admin.NewRPCService(db, cache, logger))
+
+	// params сериализуются после вызова: call может дополнить запрос текущими значениями.
+	rawParams, errMarshalParams := json.Marshal(params)
+	if errMarshalParams != nil {
+		return fmt.Errorf("marshal params: %w", errMarshalParams)
+	}
+
+	var errText string
+	if errCall != nil {
+		errText = errCall.Error()
+	}
+
+	if errNewAdminAudit := repo.NewAdminAudit(
+		ctx,
+		db,
+		adminName,
+		method,
+		admin.RedactParams(rawParams),
+		errText,
+	); errNewAdminAudit != nil {
+		return fmt.Errorf("write admin audit: %w", errNewAdminAudit)
+	}
+
+	if errCall != nil {
+		return errCall
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(result) //nolint:wrapcheck // intentional
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/revenue"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// runRevenueExport обновляет дневные агрегаты GGR/NGR и выгружает их в csv.
+//
+//	revenue-export -from 2022-08-01 -to 2022-09-01 [-operator 42] [-game slots] [-currency EUR] [-out report.csv]
+func runRevenueExport(args []string) error {
+	flags := flag.NewFlagSet("revenue-export", flag.ContinueOnError)
+	fromFlag := flags.String("from", "", "period start, inclusive (YYYY-MM-DD)")
+	toFlag := flags.String("to", "", "period end, exclusive (YYYY-MM-DD)")
+	operator := flags.Int("operator", -1, "operator id, all operators by default")
+	gameID := flags.String("game", "", "game id, all games by default")
+	currency := flags.String("currency", "", "currency code, all currencies by default")
+	outPath := flags.String("out", "", "csv file, stdout by default")
+	skipRefresh := flags.Bool("skip-refresh", false, "export the rollup as is without refreshing it first")
+
+	if errParse := flags.Parse(args); errParse != nil {
+		return errUsage
+	}
+
+	if *fromFlag == "" || *toFlag == "" {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	from, errFrom := time.Parse("2006-01-02", *fromFlag)
+	if errFrom != nil {
+		return fmt.Errorf("parse -from: %w", errFrom)
+	}
+
+	to, errTo := time.Parse("2006-01-02", *toFlag)
+	if errTo != nil {
+		return fmt.Errorf("parse -to: %w", errTo)
+	}
+
+	filter := repo.RevenueFilter{From: from, To: to, GameID: *gameID, Currency: *currency}
+	if *operator >= 0 {
+		filter.OperatorID = operator
+	}
+
+	ctx := context.Background()
+
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	if !*skipRefresh {
+		if _, errRefresh := revenue.Refresh(ctx, db); errRefresh != nil {
+			return fmt.Errorf("refresh rollup: %w", errRefresh)
+		}
+	}
+
+	tx, errBeginTxx := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if errBeginTxx != nil {
+		return fmt.Errorf("begin: %w", errBeginTxx)
+	}
+
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	rows, errGetRevenueDaily := repo.GetRevenueDaily(ctx, tx, filter)
+	if errGetRevenueDaily != nil {
+		return fmt.Errorf("load rollup: %w", errGetRevenueDaily)
+	}
+
+	var out io.Writer = os.Stdout
+
+	if *outPath != "" {
+		file, errCreate := os.Create(*outPath)
+		if errCreate != nil {
+			return fmt.Errorf("create report: %w", errCreate)
+		}
+
+		defer file.Close()
+
+		out = file
+	}
+
+	return revenue.WriteCSV(out, rows) //nolint:wrapcheck // intentional
+}
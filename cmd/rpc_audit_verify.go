@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcaudit"
+)
+
+var errRPCAuditBroken = errors.New("rpc audit chain is broken")
+
+// runRPCAuditVerify проверяет целостность цепочки хэшей журнала вызовов.
+//
+//	rpc-audit-verify
+func runRPCAuditVerify(_ []string) error {
+	db, errOpenDB := openDB()
+	if errOpenDB != nil {
+		return errOpenDB
+	}
+
+	defer db.Close()
+
+	brokenID, checked, errVerify := rpcaudit.NewStore(db).Verify(context.Background())
+	if errVerify != nil {
+		return fmt.Errorf("verify: %w", errVerify)
+	}
+
+	if brokenID != 0 {
+		return fmt.Errorf("%w at record %d, %d records before it are intact", errRPCAuditBroken, brokenID, checked)
+	}
+
+	fmt.Fprintf(os.Stdout, "rpc audit chain is intact, %d records checked\n", checked)
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/config"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/health"
+)
+
+// cancelGrace сколько ждать запросы после отмены их контекста: откат транзакции занимает миллисекунды.
+const cancelGrace = 2 * time.Second
+
+type shutdownOptions struct {
+	// DrainDelay сколько /readyz отдаёт draining до остановки приёма соединений, чтобы балансировщик
+	// успел убрать экземпляр.
+	DrainDelay time.Duration
+	// Timeout сколько ждать незавершённые запросы, дальше их контекст отменяется.
+	Timeout time.Duration
+}
+
+// newShutdownOptions в сумме drain_delay и shutdown_timeout должны укладываться в terminationGracePeriod оркестратора.
+func newShutdownOptions(cfg config.Server) shutdownOptions {
+	return shutdownOptions{DrainDelay: time.Duration(cfg.DrainDelay), Timeout: time.Duration(cfg.ShutdownTimeout)}
+}
+
+// listen открывает порты всех серверов заранее, чтобы занятый порт был ошибкой запуска, а не работы.
+func listen(servers []*http.Server) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(servers))
+
+	for _, srv := range servers {
+		listener, errListen := net.Listen("tcp", srv.Addr)
+		if errListen != nil {
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+
+			return nil, fmt.Errorf("listen %s: %w", srv.Addr, errListen)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// serve обслуживает запросы до отмены ctx или ошибки одного из серверов, затем останавливается:
+// снимает готовность, ждёт DrainDelay, перестаёт принимать соединения и ждёт незавершённые запросы до Timeout.
+// Запросы, не успевшие за Timeout, отменяются через контекст: их транзакции откатываются целиком,
+// а не обрываются вместе с процессом посреди withdrawAndDeposit.
+// listeners[i] порт servers[i].
+func serve(
+	ctx context.Context,
+	servers []*http.Server,
+	listeners []net.Listener,
+	checker *health.Health,
+	options shutdownOptions,
+	logger *zap.Logger,
+) error {
+	// контекст запросов не наследует ctx: сигнал останавливает приём, а не начатые запросы
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	errs := make(chan error, len(servers))
+
+	for i, srv := range servers {
+		srv.BaseContext = func(net.Listener) context.Context { return requestCtx }
+
+		go func(srv *http.Server, listener net.Listener) {
+			var errServeListener error
+			if srv.TLSConfig != nil {
+				// сертификат уже загружен в TLSConfig
+				errServeListener = srv.ServeTLS(listener, "", "")
+			} else {
+				errServeListener = srv.Serve(listener)
+			}
+
+			if !errors.Is(errServeListener, http.ErrServerClosed) {
+				errs <- fmt.Errorf("serve %s: %w", listener.Addr(), errServeListener)
+			}
+		}(srv, listeners[i])
+	}
+
+	var errServe error
+
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down", zap.Duration("drain_delay", options.DrainDelay), zap.Duration("timeout", options.Timeout))
+	case errServe = <-errs:
+		logger.Error("server failed, shutting down", zap.Error(errServe))
+	}
+
+	checker.SetDraining(true)
+
+	// при упавшем сервере ждать балансировщик незачем
+	if errServe == nil {
+		time.Sleep(options.DrainDelay)
+	}
+
+	if !shutdownAll(servers, options.Timeout) {
+		logger.Warn("in-flight requests did not finish in time, cancelling them")
+		cancelRequests()
+
+		if !shutdownAll(servers, cancelGrace) {
+			logger.Error("cancelled requests did not finish, closing connections")
+
+			for _, srv := range servers {
+				_ = srv.Close()
+			}
+		}
+	}
+
+	logger.Info("http servers stopped")
+
+	return errServe
+}
+
+// shutdownAll false - не все запросы завершились за timeout.
+func shutdownAll(servers []*http.Server, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		finished = true
+	)
+
+	for _, srv := range servers {
+		wg.Add(1)
+
+		go func(srv *http.Server) {
+			defer wg.Done()
+
+			if errShutdown := srv.Shutdown(ctx); errShutdown != nil {
+				mu.Lock()
+				finished = false
+				mu.Unlock()
+			}
+		}(srv)
+	}
+
+	wg.Wait()
+
+	return finished
+}
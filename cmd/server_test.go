@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/health"
+)
+
+// startServe запускает serve на свободном порту и возвращает адрес, отмену ctx и канал с результатом serve.
+func startServe(
+	t *testing.T,
+	handler http.Handler,
+	options shutdownOptions,
+) (addr string, cancel context.CancelFunc, done <-chan error) {
+	t.Helper()
+
+	listener, errListen := net.Listen("tcp", "127.0.0.1:0")
+	if errListen != nil {
+		t.Fatal(errListen)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+
+	go func() {
+		result <- serve(
+			ctx,
+			[]*http.Server{{Handler: handler, ReadHeaderTimeout: time.Second}},
+			[]net.Listener{listener},
+			health.New(time.Second),
+			options,
+			zap.NewNop(),
+		)
+	}()
+
+	return listener.Addr().String(), cancel, result
+}
+
+// get отдельное соединение на каждый запрос, чтобы не переиспользовать уже открытое.
+func get(addr string) (int, string, error) {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	resp, errGet := client.Get("http://" + addr + "/")
+	if errGet != nil {
+		return 0, "", errGet //nolint:wrapcheck // intentional
+	}
+
+	defer resp.Body.Close()
+
+	body, errReadAll := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, string(body), errReadAll //nolint:wrapcheck // intentional
+}
+
+type response struct {
+	status int
+	body   string
+	err    error
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+
+		_, _ = io.WriteString(w, "done")
+	})
+
+	addr, cancel, done := startServe(t, handler, shutdownOptions{DrainDelay: 50 * time.Millisecond, Timeout: 5 * time.Second})
+	defer cancel()
+
+	inFlight := make(chan response, 1)
+
+	go func() {
+		status, body, errGet := get(addr)
+		inFlight <- response{status: status, body: body, err: errGet}
+	}()
+
+	<-started
+	cancel()
+
+	// после DrainDelay порт закрывается, а начатый запрос ещё выполняется
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		conn, errDial := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if errDial != nil {
+			break
+		}
+
+		_ = conn.Close()
+
+		if time.Now().After(deadline) {
+			t.Fatal("new connections are still accepted after shutdown started")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+
+	got := <-inFlight
+	if got.err != nil || got.status != http.StatusOK || got.body != "done" {
+		t.Fatalf("in-flight response = %d %q %v, want 200 \"done\"", got.status, got.body, got.err)
+	}
+
+	select {
+	case errServe := <-done:
+		if errServe != nil {
+			t.Fatalf("serve: %v", errServe)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve did not return after the in-flight request finished")
+	}
+}
+
+func TestServeCancelsRequestsAfterTimeout(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		cancelled <- r.Context().Err()
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	addr, cancel, done := startServe(t, handler, shutdownOptions{Timeout: 100 * time.Millisecond})
+	defer cancel()
+
+	go func() { _, _, _ = get(addr) }()
+
+	<-started
+	cancel()
+
+	select {
+	case errCtx := <-cancelled:
+		if !errors.Is(errCtx, context.Canceled) {
+			t.Fatalf("request context error = %v, want %v", errCtx, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request context was not cancelled after the shutdown timeout")
+	}
+
+	select {
+	case errServe := <-done:
+		if errServe != nil {
+			t.Fatalf("serve: %v", errServe)
+		}
+	case <-time.After(cancelGrace + time.Second):
+		t.Fatal("serve did not return after cancelling requests")
+	}
+}
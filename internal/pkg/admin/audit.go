@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/pjrpc/pjrpc/v2"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcaudit"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// AdminHeader заголовок с именем сотрудника, его проставляет авторизующий прокси перед админкой.
+// Верить ему можно только за Authenticate.
+const AdminHeader = "X-Admin-User"
+
+var ErrAdminIdentityRequired = errors.New("admin identity is required")
+
+// auditRedactor поля запросов admin API с секретами, например HMAC-ключ вебхука оператора.
+// Журнал читают шире, чем админку, секрет в нём равен утечке.
+var auditRedactor = rpcaudit.NewRedactor("secret")
+
+// RedactParams параметры вызова в том виде, в котором они пишутся в billing.admin_audit.
+func RedactParams(params []byte) []byte {
+	return auditRedactor.Redact(params)
+}
+
+type ctxKey int
+
+const ctxKeyAdmin ctxKey = 1
+
+func ContextWithAdmin(ctx context.Context, admin string) context.Context {
+	return context.WithValue(ctx, ctxKeyAdmin, admin)
+}
+
+func AdminFromContext(ctx context.Context) string {
+	admin, _ := ctx.Value(ctxKeyAdmin).(string)
+
+	return admin
+}
+
+// AuditMiddleware не пускает запросы без имени сотрудника и пишет каждый вызов в billing.admin_audit.
+func AuditMiddleware(db *sqlx.DB, logger *zap.Logger) pjrpc.Middleware {
+	return func(next pjrpc.Handler) pjrpc.Handler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			data, ok := pjrpc.ContextGetData(ctx)
+			if !ok {
+				return nil, ErrAdminIdentityRequired
+			}
+
+			admin := data.HTTTRequest.Header.Get(AdminHeader)
+			if admin == "" {
+				return nil, ErrAdminIdentityRequired
+			}
+
+			res, err := next(ContextWithAdmin(ctx, admin), params)
+
+			var errText string
+			if err != nil {
+				errText = err.Error()
+			}
+
+			if errNewAdminAudit := repo.NewAdminAudit(
+				ctx,
+				db,
+				admin,
+				data.JRPCRequest.Method,
+				RedactParams(params),
+				errText,
+			); errNewAdminAudit != nil {
+				logger.Error(
+					"could not write admin audit",
+					zap.String("admin", admin),
+					zap.String("method", data.JRPCRequest.Method),
+					zap.Error(errNewAdminAudit),
+				)
+			}
+
+			return res, err
+		}
+	}
+}
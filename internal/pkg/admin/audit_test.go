@@ -0,0 +1,31 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+)
+
+func TestRedactParamsHidesWebhookSecret(t *testing.T) {
+	params, errMarshal := json.Marshal(types.RegisterOperatorWebhookRequest{
+		OperatorID: 7,
+		URL:        "https://operator.example/hook",
+		Secret:     "hmac-secret",
+	})
+	if errMarshal != nil {
+		t.Fatal(errMarshal)
+	}
+
+	redacted := string(admin.RedactParams(params))
+
+	if strings.Contains(redacted, "hmac-secret") {
+		t.Fatalf("secret is stored in the audit: %s", redacted)
+	}
+
+	if !strings.Contains(redacted, "https://operator.example/hook") || !strings.Contains(redacted, `"operatorId":7`) {
+		t.Fatalf("other params are lost: %s", redacted)
+	}
+}
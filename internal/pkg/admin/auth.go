@@ -0,0 +1,25 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// TokenHeader заголовок с общим секретом листенера админки. Его добавляет авторизующий прокси
+// вместе с AdminHeader, а CLI - к запросам в кэш сервиса.
+const TokenHeader = "X-Admin-Token"
+
+// Authenticate пускает к next только запросы с token в TokenHeader. Без него AdminHeader мог бы
+// подставить любой, кто дотянулся до порта админки.
+func Authenticate(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got := req.Header.Get(TokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,82 @@
+package admin_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+)
+
+func TestAuthenticate(t *testing.T) {
+	handler := admin.Authenticate("secret", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tc := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "wrong token", token: "guess", status: http.StatusUnauthorized},
+		{name: "token", token: "secret", status: http.StatusNoContent},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+			if tc.token != "" {
+				req.Header.Set(admin.TokenHeader, tc.token)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestAuthenticateEmptyToken(t *testing.T) {
+	handler := admin.Authenticate("", http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/rpc/", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestAuthenticateRemoteCache CLI сбрасывает кэш сервиса только со своим токеном.
+func TestAuthenticateRemoteCache(t *testing.T) {
+	cache := cashstore.NewLRU(0, 0)
+	server := httptest.NewServer(admin.Authenticate("secret", cashstore.Handler(cache)))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+
+	if errSet := cache.Set(ctx, "key", []byte("value"), 0); errSet != nil {
+		t.Fatal(errSet)
+	}
+
+	anonymous := cashstore.NewRemote(server.URL, server.Client(), nil)
+	if errDelete := anonymous.Delete(ctx, "key"); !errors.Is(errDelete, cashstore.ErrRemoteStatus) {
+		t.Fatalf("delete without token: %v", errDelete)
+	}
+
+	remote := cashstore.NewRemote(server.URL, server.Client(), http.Header{admin.TokenHeader: []string{"secret"}})
+	if errDelete := remote.Delete(ctx, "key"); errDelete != nil {
+		t.Fatalf("delete with token: %v", errDelete)
+	}
+
+	if _, ok, _ := cache.Get(ctx, "key"); ok {
+		t.Fatal("key is still cached")
+	}
+}
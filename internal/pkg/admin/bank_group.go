@@ -0,0 +1,331 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var (
+	ErrBankGroupIDRequired      = errors.New("bank group id is required")
+	ErrInvalidStartingBalance   = errors.New("starting balance must not be negative")
+	ErrInvalidBetLimits         = errors.New("bet limits must not be negative and betMin must not exceed betMax")
+	ErrInvalidRoundingStep      = errors.New("rounding step must be positive")
+	ErrUnknownRoundingMode      = errors.New("unknown rounding mode")
+	ErrUnknownFreeRoundsPolicy  = errors.New("unknown free rounds policy")
+	ErrInvalidFreeRoundsLimit   = errors.New("free rounds max charge must not be negative")
+	ErrBankGroupCurrencyMissing = errors.New("bank group must allow its default currency")
+	ErrOperatorIDRequired       = errors.New("operator id is required")
+)
+
+func validateBankGroupPolicy(in *types.UpdateBankGroupRequest) error {
+	switch {
+	case in.ID == "":
+		return ErrBankGroupIDRequired
+	case in.StartingBalance < 0:
+		return ErrInvalidStartingBalance
+	case in.BetMin < 0 || in.BetMax < 0 || (in.BetMax > 0 && in.BetMin > in.BetMax):
+		return ErrInvalidBetLimits
+	case in.RoundingStep < 1:
+		return ErrInvalidRoundingStep
+	case in.FreeRoundsMaxCharge < 0:
+		return ErrInvalidFreeRoundsLimit
+	}
+
+	switch repo.RoundingMode(in.RoundingMode) {
+	case repo.RoundingNone, repo.RoundingDown, repo.RoundingHalfUp:
+	default:
+		return ErrUnknownRoundingMode
+	}
+
+	switch repo.FreeRoundsPolicy(in.FreeRounds) {
+	case repo.FreeRoundsAllow, repo.FreeRoundsDeny:
+	default:
+		return ErrUnknownFreeRoundsPolicy
+	}
+
+	return nil
+}
+
+func (r *RPCService) CreateBankGroup(
+	ctx context.Context,
+	in *types.CreateBankGroupRequest,
+) (*types.BankGroup, error) {
+	if in.ID == "" {
+		return nil, ErrBankGroupIDRequired
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	currency, errGetCurrencyByCode := repo.GetCurrencyByCode(ctx, tx, in.CurrencyCode)
+	if errGetCurrencyByCode != nil {
+		return nil, errGetCurrencyByCode //nolint:wrapcheck // intentional
+	}
+
+	bankGroup, errNewBankGroup := repo.NewBankGroup(ctx, tx, in.ID, currency.ID)
+	if errNewBankGroup != nil {
+		return nil, errNewBankGroup //nolint:wrapcheck // intentional
+	}
+
+	result, errBankGroupToType := bankGroupToType(ctx, tx, *bankGroup)
+	if errBankGroupToType != nil {
+		return nil, errBankGroupToType
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.logger.Info(
+		"bank group created",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("bank_group", in.ID),
+		zap.String("currency", currency.Code),
+	)
+
+	return result, nil
+}
+
+// UpdateBankGroup меняет настройки кошельков группы, они применяются со следующего запроса операторов группы.
+func (r *RPCService) UpdateBankGroup(
+	ctx context.Context,
+	in *types.UpdateBankGroupRequest,
+) (*types.BankGroup, error) {
+	if errValidate := validateBankGroupPolicy(in); errValidate != nil {
+		return nil, errValidate
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	bankGroup, errUpdateBankGroupPolicy := repo.UpdateBankGroupPolicy(ctx, tx, repo.BankGroup{
+		ID:                  in.ID,
+		StartingBalance:     in.StartingBalance,
+		BetMin:              in.BetMin,
+		BetMax:              in.BetMax,
+		RoundingStep:        in.RoundingStep,
+		RoundingMode:        repo.RoundingMode(in.RoundingMode),
+		FreeRounds:          repo.FreeRoundsPolicy(in.FreeRounds),
+		FreeRoundsMaxCharge: in.FreeRoundsMaxCharge,
+		CurrencyConversion:  in.CurrencyConversion,
+	})
+	if errUpdateBankGroupPolicy != nil {
+		return nil, errUpdateBankGroupPolicy //nolint:wrapcheck // intentional
+	}
+
+	if in.CurrencyCodes != nil {
+		currencyIDs := make([]int, 0, len(in.CurrencyCodes))
+		hasDefault := false
+
+		for _, code := range in.CurrencyCodes {
+			currency, errGetCurrencyByCode := repo.GetCurrencyByCode(ctx, tx, code)
+			if errGetCurrencyByCode != nil {
+				return nil, errGetCurrencyByCode //nolint:wrapcheck // intentional
+			}
+
+			hasDefault = hasDefault || currency.ID == bankGroup.CurrencyID
+			currencyIDs = append(currencyIDs, currency.ID)
+		}
+
+		if !hasDefault {
+			return nil, ErrBankGroupCurrencyMissing
+		}
+
+		if errSetBankGroupCurrencies := repo.SetBankGroupCurrencies(
+			ctx,
+			tx,
+			bankGroup.ID,
+			currencyIDs,
+		); errSetBankGroupCurrencies != nil {
+			return nil, errSetBankGroupCurrencies //nolint:wrapcheck // intentional
+		}
+	}
+
+	result, errBankGroupToType := bankGroupToType(ctx, tx, *bankGroup)
+	if errBankGroupToType != nil {
+		return nil, errBankGroupToType
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.invalidated(walletcache.InvalidateBankGroups(ctx, r.cache))
+
+	r.logger.Info(
+		"bank group updated",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("bank_group", in.ID),
+		zap.Strings("currencies", result.CurrencyCodes),
+	)
+
+	return result, nil
+}
+
+func (r *RPCService) ListBankGroups(
+	ctx context.Context,
+	in *types.ListBankGroupsRequest,
+) (*types.ListBankGroupsResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	var (
+		bankGroups       []repo.BankGroup
+		errGetBankGroups error
+	)
+
+	if in.CurrencyCode == "" {
+		bankGroups, errGetBankGroups = repo.GetBankGroups(ctx, tx)
+	} else {
+		currency, errGetCurrencyByCode := repo.GetCurrencyByCode(ctx, tx, in.CurrencyCode)
+		if errGetCurrencyByCode != nil {
+			return nil, errGetCurrencyByCode //nolint:wrapcheck // intentional
+		}
+
+		bankGroups, errGetBankGroups = repo.GetBankGroupsByCurrencyID(ctx, tx, currency.ID)
+	}
+
+	if errGetBankGroups != nil {
+		return nil, errGetBankGroups //nolint:wrapcheck // intentional
+	}
+
+	result := make([]types.BankGroup, 0, len(bankGroups))
+
+	for _, bankGroup := range bankGroups {
+		item, errBankGroupToType := bankGroupToType(ctx, tx, bankGroup)
+		if errBankGroupToType != nil {
+			return nil, errBankGroupToType
+		}
+
+		result = append(result, *item)
+	}
+
+	return &types.ListBankGroupsResponse{BankGroups: result}, nil
+}
+
+func (r *RPCService) DeleteBankGroup(
+	ctx context.Context,
+	in *types.DeleteBankGroupRequest,
+) (*types.DeleteBankGroupResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if errDeleteBankGroup := repo.DeleteBankGroup(ctx, tx, in.ID); errDeleteBankGroup != nil {
+		return nil, errDeleteBankGroup //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.invalidated(walletcache.InvalidateBankGroups(ctx, r.cache))
+
+	r.logger.Info(
+		"bank group deleted",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("bank_group", in.ID),
+	)
+
+	return &types.DeleteBankGroupResponse{}, nil
+}
+
+// SetOperatorBankGroup привязывает оператора к банковской группе, оператор заводится, если его ещё нет.
+func (r *RPCService) SetOperatorBankGroup(
+	ctx context.Context,
+	in *types.SetOperatorBankGroupRequest,
+) (*types.SetOperatorBankGroupResponse, error) {
+	if in.OperatorID <= 0 {
+		return nil, ErrOperatorIDRequired
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if in.BankGroupID != "" {
+		if _, errGetBankGroupByID := repo.GetBankGroupByID(ctx, tx, in.BankGroupID); errGetBankGroupByID != nil {
+			return nil, errGetBankGroupByID //nolint:wrapcheck // intentional
+		}
+	}
+
+	if errSetOperatorBankGroup := repo.SetOperatorBankGroup(
+		ctx,
+		tx,
+		in.OperatorID,
+		in.BankGroupID,
+	); errSetOperatorBankGroup != nil {
+		return nil, errSetOperatorBankGroup //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.invalidated(walletcache.InvalidateBankGroups(ctx, r.cache))
+
+	r.logger.Info(
+		"operator bank group changed",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.Int("operator_id", in.OperatorID),
+		zap.String("bank_group", in.BankGroupID),
+	)
+
+	return &types.SetOperatorBankGroupResponse{}, nil
+}
+
+func bankGroupToType(ctx context.Context, tx *sqlx.Tx, bankGroup repo.BankGroup) (*types.BankGroup, error) {
+	currencies, errGetCurrencies := repo.GetCurrencies(ctx, tx)
+	if errGetCurrencies != nil {
+		return nil, errGetCurrencies //nolint:wrapcheck // intentional
+	}
+
+	codes := make(map[int]string, len(currencies))
+	for _, currency := range currencies {
+		codes[currency.ID] = currency.Code
+	}
+
+	currencyIDs, errGetBankGroupCurrencyIDs := repo.GetBankGroupCurrencyIDs(ctx, tx, bankGroup.ID)
+	if errGetBankGroupCurrencyIDs != nil {
+		return nil, errGetBankGroupCurrencyIDs //nolint:wrapcheck // intentional
+	}
+
+	currencyCodes := make([]string, 0, len(currencyIDs))
+	for _, currencyID := range currencyIDs {
+		currencyCodes = append(currencyCodes, codes[currencyID])
+	}
+
+	return &types.BankGroup{
+		ID:                  bankGroup.ID,
+		CurrencyCode:        codes[bankGroup.CurrencyID],
+		CurrencyCodes:       currencyCodes,
+		StartingBalance:     bankGroup.StartingBalance,
+		BetMin:              bankGroup.BetMin,
+		BetMax:              bankGroup.BetMax,
+		RoundingStep:        bankGroup.RoundingStep,
+		RoundingMode:        string(bankGroup.RoundingMode),
+		FreeRounds:          string(bankGroup.FreeRounds),
+		FreeRoundsMaxCharge: bankGroup.FreeRoundsMaxCharge,
+		CurrencyConversion:  bankGroup.CurrencyConversion,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const defaultBalanceHistoryLimit = 100
+
+func (r *RPCService) GetPlayer(
+	ctx context.Context,
+	in *types.GetPlayerRequest,
+) (*types.GetPlayerResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	user, errFindUserByName := repo.FindUserByName(ctx, tx, in.PlayerName)
+	if errFindUserByName != nil {
+		return nil, errFindUserByName //nolint:wrapcheck // intentional
+	}
+
+	currencyID, errGetCurrencyID := repo.GetCurrencyID(ctx, tx, user.ID)
+	if errGetCurrencyID != nil {
+		return nil, errGetCurrencyID //nolint:wrapcheck // intentional
+	}
+
+	response := &types.GetPlayerResponse{
+		ID:            user.ID,
+		PlayerName:    user.Name,
+		CreatedAt:     user.CreatedAt,
+		Status:        types.PlayerStatus(user.Status),
+		ExcludedUntil: user.ExcludedUntil,
+		StatusReason:  user.StatusReason,
+	}
+
+	// без платежей у игрока ещё нет кошелька: ни валюты, ни баланса
+	if currencyID == -1 {
+		return response, nil
+	}
+
+	currency, errGetCurrencyByID := repo.GetCurrencyByID(ctx, tx, currencyID)
+	if errGetCurrencyByID != nil {
+		return nil, errGetCurrencyByID //nolint:wrapcheck // intentional
+	}
+
+	balance, errGetDepositByUserID := repo.GetDepositByUserID(ctx, tx, user.ID)
+	if errGetDepositByUserID != nil {
+		return nil, errGetDepositByUserID //nolint:wrapcheck // intentional
+	}
+
+	response.Currency = currency.Code
+	response.Balance = balance
+
+	return response, nil
+}
+
+// GetBalanceHistory последние платежи игрока с балансом после каждого из них, новые сверху.
+func (r *RPCService) GetBalanceHistory(
+	ctx context.Context,
+	in *types.GetBalanceHistoryRequest,
+) (*types.GetBalanceHistoryResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	user, errFindUserByName := repo.FindUserByName(ctx, tx, in.PlayerName)
+	if errFindUserByName != nil {
+		return nil, errFindUserByName //nolint:wrapcheck // intentional
+	}
+
+	limit := in.Limit
+	if limit <= 0 || limit > defaultBalanceHistoryLimit {
+		limit = defaultBalanceHistoryLimit
+	}
+
+	// лимит и баланс после каждого платежа считает база, история игрока целиком не читается
+	rows, errGetPaymentHistory := repo.GetPaymentHistory(ctx, tx, repo.PaymentHistoryFilter{UserID: user.ID}, limit)
+	if errGetPaymentHistory != nil {
+		return nil, errGetPaymentHistory //nolint:wrapcheck // intentional
+	}
+
+	items := make([]types.BalanceHistoryItem, 0, len(rows))
+
+	for _, row := range rows {
+		item := types.BalanceHistoryItem{
+			ID:             row.ID,
+			RollbackAt:     row.RollBackAt,
+			Withdraw:       row.Withdraw,
+			Deposit:        row.Deposit,
+			Balance:        row.Balance,
+			TransactionRef: row.TransactionRef,
+		}
+
+		if row.CreatedAt != nil {
+			item.CreatedAt = *row.CreatedAt
+		}
+
+		items = append(items, item)
+	}
+
+	return &types.GetBalanceHistoryResponse{Items: items}, nil
+}
@@ -0,0 +1,73 @@
+package admin_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+)
+
+func TestGetPlayerWithoutWallet(t *testing.T) {
+	db, s := openScripted(t, playerRow, scriptedResult{match: "FROM billing.payments", columns: []string{"id"}})
+	svc := admin.NewRPCService(db, nil, zap.NewNop())
+
+	player, errGetPlayer := svc.GetPlayer(context.Background(), &types.GetPlayerRequest{PlayerName: "player"})
+	if errGetPlayer != nil {
+		t.Fatalf("get player: %v", errGetPlayer)
+	}
+
+	if player.PlayerName != "player" || player.Currency != "" || player.Balance != 0 {
+		t.Fatalf("player = %+v, want no currency and zero balance", player)
+	}
+
+	for _, query := range s.queries {
+		if strings.Contains(query, "ref_currency") {
+			t.Fatalf("currency looked up for a player without wallet: %s", query)
+		}
+	}
+}
+
+// TestGetBalanceHistoryFromSQL лимит и баланс после платежа приходят из запроса, а не из всей истории игрока.
+func TestGetBalanceHistoryFromSQL(t *testing.T) {
+	now := time.Now()
+
+	db, s := openScripted(t, playerRow, scriptedResult{
+		match: "FROM billing.payments",
+		columns: []string{
+			"id", "created_at", "rollback_at", "user_id", "currency_id", "withdraw", "deposit", "transaction_ref", "balance",
+		},
+		rows: [][]driver.Value{
+			{int64(9), now, nil, int64(1), int64(1), int64(0), int64(30), "tx-9", int64(130)},
+			{int64(8), now, now, int64(1), int64(1), int64(50), int64(0), "tx-8", int64(100)},
+		},
+	})
+	svc := admin.NewRPCService(db, nil, zap.NewNop())
+
+	history, errGetBalanceHistory := svc.GetBalanceHistory(
+		context.Background(),
+		&types.GetBalanceHistoryRequest{PlayerName: "player", Limit: 2},
+	)
+	if errGetBalanceHistory != nil {
+		t.Fatalf("get balance history: %v", errGetBalanceHistory)
+	}
+
+	if len(history.Items) != 2 || history.Items[0].ID != 9 || history.Items[0].Balance != 130 ||
+		history.Items[1].Balance != 100 || history.Items[1].RollbackAt == nil {
+		t.Fatalf("items = %+v", history.Items)
+	}
+
+	query, args := s.queries[len(s.queries)-1], s.args[len(s.args)-1]
+	if !strings.Contains(query, "LIMIT") || !strings.Contains(query, "OVER (ORDER BY p.id)") {
+		t.Fatalf("history query does not limit and window in sql:\n%s", query)
+	}
+
+	if limit := args[len(args)-1].Value; limit != int64(2) {
+		t.Fatalf("limit arg = %v, want 2", limit)
+	}
+}
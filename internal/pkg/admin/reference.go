@@ -0,0 +1,203 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+func (r *RPCService) GrantBonus(
+	ctx context.Context,
+	in *types.GrantBonusRequest,
+) (*types.GrantBonusResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if _, errFindUserByName := repo.FindUserByName(ctx, tx, in.PlayerName); errFindUserByName != nil {
+		return nil, errFindUserByName //nolint:wrapcheck // intentional
+	}
+
+	if errWriteBonus := repo.WriteBonus(ctx, tx, in.BonusID); errWriteBonus != nil {
+		return nil, errWriteBonus //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.logger.Info(
+		"bonus granted",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("player", in.PlayerName),
+		zap.String("bonus_id", in.BonusID),
+	)
+
+	return &types.GrantBonusResponse{}, nil
+}
+
+var (
+	ErrInvalidCurrencyCode   = errors.New("currency code must be three uppercase latin letters (ISO 4217)")
+	ErrInvalidCurrencyID     = errors.New("currency id must be an ISO 4217 numeric code in range 1..999")
+	ErrInvalidCurrencyDigits = errors.New("number of digits after the decimal separator must be in range 0..4")
+	ErrCurrencyReplacesSelf  = errors.New("currency can not replace itself")
+)
+
+var currencyCodeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+const (
+	maxCurrencyID     = 999
+	maxCurrencyDigits = 4
+)
+
+func validateCurrency(in *types.CreateCurrencyRequest) error {
+	switch {
+	case !currencyCodeRegexp.MatchString(in.Code):
+		return ErrInvalidCurrencyCode
+	case in.ID < 1 || in.ID > maxCurrencyID:
+		return ErrInvalidCurrencyID
+	case in.NumberOfDigitsAfterTheDecimalSeparator < 0 ||
+		in.NumberOfDigitsAfterTheDecimalSeparator > maxCurrencyDigits:
+		return ErrInvalidCurrencyDigits
+	case in.Replaces == in.Code:
+		return ErrCurrencyReplacesSelf
+	}
+
+	return nil
+}
+
+// CreateCurrency заводит валюту вместе с банковской группой BANK_<code>, как это сделано для справочника
+// из миграций. Если указан Replaces, старая валюта отключается в той же транзакции.
+func (r *RPCService) CreateCurrency(
+	ctx context.Context,
+	in *types.CreateCurrencyRequest,
+) (*types.Currency, error) {
+	if errValidate := validateCurrency(in); errValidate != nil {
+		return nil, errValidate
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	currency, errNewCurrency := repo.NewCurrency(
+		ctx,
+		tx,
+		in.ID,
+		in.Code,
+		in.NumberOfDigitsAfterTheDecimalSeparator,
+		in.Name,
+	)
+	if errNewCurrency != nil {
+		return nil, errNewCurrency //nolint:wrapcheck // intentional
+	}
+
+	if _, errNewBankGroup := repo.NewBankGroup(ctx, tx, "BANK_"+currency.Code, currency.ID); errNewBankGroup != nil {
+		return nil, errNewBankGroup //nolint:wrapcheck // intentional
+	}
+
+	var (
+		replaced               *repo.Currency
+		errSetCurrencyDisabled error
+	)
+
+	if in.Replaces != "" {
+		replaced, errSetCurrencyDisabled = repo.SetCurrencyDisabled(ctx, tx, in.Replaces, true)
+		if errSetCurrencyDisabled != nil {
+			return nil, errSetCurrencyDisabled //nolint:wrapcheck // intentional
+		}
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	if replaced != nil {
+		r.invalidated(walletcache.InvalidateCurrency(ctx, r.cache, replaced.ID, replaced.Code))
+	}
+
+	r.logger.Info(
+		"currency created",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("code", currency.Code),
+		zap.String("replaces", in.Replaces),
+	)
+
+	return currencyToType(currency), nil
+}
+
+// SetCurrencyEnabled включает или отключает валюту. Отключённая валюта остаётся в справочнике,
+// но getBalance по ней возвращает ошибку.
+func (r *RPCService) SetCurrencyEnabled(
+	ctx context.Context,
+	in *types.SetCurrencyEnabledRequest,
+) (*types.Currency, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	currency, errSetCurrencyDisabled := repo.SetCurrencyDisabled(ctx, tx, in.Code, !in.Enabled)
+	if errSetCurrencyDisabled != nil {
+		return nil, errSetCurrencyDisabled //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.invalidated(walletcache.InvalidateCurrency(ctx, r.cache, currency.ID, currency.Code))
+
+	r.logger.Info(
+		"currency enabled changed",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("code", in.Code),
+		zap.Bool("enabled", in.Enabled),
+	)
+
+	return currencyToType(*currency), nil
+}
+
+func (r *RPCService) ListCurrencies(
+	ctx context.Context,
+	_ *types.ListCurrenciesRequest,
+) (*types.ListCurrenciesResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	currencies, errGetCurrencies := repo.GetCurrencies(ctx, tx)
+	if errGetCurrencies != nil {
+		return nil, errGetCurrencies //nolint:wrapcheck // intentional
+	}
+
+	result := make([]types.Currency, 0, len(currencies))
+	for _, currency := range currencies {
+		result = append(result, *currencyToType(currency))
+	}
+
+	return &types.ListCurrenciesResponse{Currencies: result}, nil
+}
+
+func currencyToType(currency repo.Currency) *types.Currency {
+	return &types.Currency{
+		ID:                                     currency.ID,
+		Code:                                   currency.Code,
+		NumberOfDigitsAfterTheDecimalSeparator: currency.NumberOfDigitsAfterTheDecimalSeparator,
+		Name:                                   currency.Name,
+		Enabled:                                !currency.Disabled,
+	}
+}
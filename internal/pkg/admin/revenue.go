@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var ErrInvalidPeriod = errors.New("from must be before to")
+
+// GetRevenueReport GGR/NGR из дневных агрегатов; данные отстают от платежей не больше чем на интервал обновления.
+func (r *RPCService) GetRevenueReport(
+	ctx context.Context,
+	in *types.GetRevenueReportRequest,
+) (*types.GetRevenueReportResponse, error) {
+	if !in.From.Before(in.To) {
+		return nil, ErrInvalidPeriod
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	rows, errGetRevenueDaily := repo.GetRevenueDaily(ctx, tx, repo.RevenueFilter{
+		From:       in.From,
+		To:         in.To,
+		OperatorID: in.OperatorID,
+		GameID:     in.GameID,
+		Currency:   in.Currency,
+	})
+	if errGetRevenueDaily != nil {
+		return nil, errGetRevenueDaily //nolint:wrapcheck // intentional
+	}
+
+	result := &types.GetRevenueReportResponse{
+		Rows:   make([]types.RevenueRow, 0, len(rows)),
+		Totals: []types.RevenueTotal{},
+	}
+	totals := make(map[string]*types.RevenueTotal)
+
+	for _, row := range rows {
+		result.Rows = append(result.Rows, types.RevenueRow{
+			Day:         row.Day.Format("2006-01-02"),
+			OperatorID:  row.OperatorID,
+			GameID:      row.GameID,
+			Currency:    row.Currency,
+			Bets:        row.Bets,
+			Stake:       row.Stake,
+			Win:         row.Win,
+			GGR:         row.GGR,
+			Adjustments: row.Adjustments,
+			NGR:         row.NGR,
+		})
+
+		total, ok := totals[row.Currency]
+		if !ok {
+			total = &types.RevenueTotal{Currency: row.Currency}
+			totals[row.Currency] = total
+		}
+
+		total.Bets += row.Bets
+		total.Stake += row.Stake
+		total.Win += row.Win
+		total.GGR += row.GGR
+		total.Adjustments += row.Adjustments
+		total.NGR += row.NGR
+	}
+
+	for _, total := range totals {
+		result.Totals = append(result.Totals, *total)
+	}
+
+	sort.Slice(result.Totals, func(i, j int) bool {
+		return result.Totals[i].Currency < result.Totals[j].Currency
+	})
+
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+type RPCService struct {
+	db *sqlx.DB
+	// cache кэш кошельков seamless API, nil если выключен. Админка пишет в базу напрямую и после
+	// фиксации сбрасывает затронутые ключи.
+	cache  cashstore.Cache
+	logger *zap.Logger
+}
+
+var (
+	ErrUnknownStatus        = errors.New("unknown player status")
+	ErrReasonRequired       = errors.New("reason is required")
+	ErrExcludedUntilInvalid = errors.New("self exclusion requires excludedUntil in the future")
+)
+
+// SetPlayerStatus меняет статус игрока, причина обязательна и попадает в историю статусов.
+func (r *RPCService) SetPlayerStatus(
+	ctx context.Context,
+	in *types.SetPlayerStatusRequest,
+) (*types.SetPlayerStatusResponse, error) {
+	status := repo.UserStatus(in.Status)
+	if !status.Valid() {
+		return nil, ErrUnknownStatus
+	}
+
+	if strings.TrimSpace(in.Reason) == "" {
+		return nil, ErrReasonRequired
+	}
+
+	excludedUntil := in.ExcludedUntil
+	if status != repo.UserStatusSelfExcluded {
+		excludedUntil = nil
+	} else if excludedUntil == nil || !excludedUntil.After(time.Now()) {
+		return nil, ErrExcludedUntilInvalid
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	user, errFindUserByName := repo.FindUserByName(ctx, tx, in.PlayerName)
+	if errFindUserByName != nil {
+		return nil, errFindUserByName //nolint:wrapcheck // intentional
+	}
+
+	user, errSetUserStatus := repo.SetUserStatus(ctx, tx, user.ID, status, excludedUntil, in.Reason)
+	if errSetUserStatus != nil {
+		return nil, errSetUserStatus //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.invalidated(walletcache.InvalidateUser(ctx, r.cache, user.Name))
+
+	r.logger.Info(
+		"player status changed",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("player", user.Name),
+		zap.String("status", string(user.Status)),
+		zap.String("reason", in.Reason),
+	)
+
+	return &types.SetPlayerStatusResponse{
+		PlayerName:    user.Name,
+		Status:        types.PlayerStatus(user.Status),
+		ExcludedUntil: user.ExcludedUntil,
+	}, nil
+}
+
+func NewRPCService(db *sqlx.DB, cache cashstore.Cache, logger *zap.Logger) *RPCService {
+	return &RPCService{
+		db:     db,
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// invalidated ошибка сброса кэша только логируется: изменения уже в базе, а старое значение доживёт до ttl.
+func (r *RPCService) invalidated(errInvalidate error) {
+	if errInvalidate != nil {
+		r.logger.Warn("wallet cache invalidation failed", zap.Error(errInvalidate))
+	}
+}
@@ -0,0 +1,207 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var (
+	ErrAmountNotPositive = errors.New("amount must be positive")
+	ErrUnknownReasonCode = errors.New("unknown reason code")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrAlreadyRolledBack = errors.New("payment already rolled back")
+	ErrNoWallet          = errors.New("player has no wallet currency yet")
+)
+
+const (
+	// manualTransactionRefPrefix отличает ручные корректировки от транзакций провайдеров.
+	manualTransactionRefPrefix = "admin-"
+	// forcedRollbackReason причина в вебхуке оператору о принудительном откате.
+	forcedRollbackReason = "FORCED_ROLLBACK"
+)
+
+func (r *RPCService) CreditPlayer(
+	ctx context.Context,
+	in *types.AdjustBalanceRequest,
+) (*types.AdjustBalanceResponse, error) {
+	return r.adjustBalance(ctx, in, in.Amount)
+}
+
+func (r *RPCService) DebitPlayer(
+	ctx context.Context,
+	in *types.AdjustBalanceRequest,
+) (*types.AdjustBalanceResponse, error) {
+	return r.adjustBalance(ctx, in, -in.Amount)
+}
+
+// adjustBalance ручная корректировка баланса: delta > 0 зачисление, delta < 0 списание.
+func (r *RPCService) adjustBalance(
+	ctx context.Context,
+	in *types.AdjustBalanceRequest,
+	delta int,
+) (*types.AdjustBalanceResponse, error) {
+	if in.Amount <= 0 {
+		return nil, ErrAmountNotPositive
+	}
+
+	switch in.ReasonCode {
+	case types.AdjustmentGoodwill, types.AdjustmentCorrection, types.AdjustmentCompensation, types.AdjustmentChargeback:
+	default:
+		return nil, ErrUnknownReasonCode
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	// блокировка до чтения баланса: две корректировки одного игрока не спишут больше, чем на нём есть
+	user, errLockUserByName := repo.LockUserByName(ctx, tx, in.PlayerName)
+	if errLockUserByName != nil {
+		return nil, errLockUserByName //nolint:wrapcheck // intentional
+	}
+
+	currencyID, errGetCurrencyID := repo.GetCurrencyID(ctx, tx, user.ID)
+	if errGetCurrencyID != nil {
+		return nil, errGetCurrencyID //nolint:wrapcheck // intentional
+	}
+
+	// валюта кошелька берётся из первого платежа, без платежей её не из чего взять
+	if currencyID == -1 {
+		return nil, ErrNoWallet
+	}
+
+	balance, errGetDepositByUserID := repo.GetDepositByUserID(ctx, tx, user.ID)
+	if errGetDepositByUserID != nil {
+		return nil, errGetDepositByUserID //nolint:wrapcheck // intentional
+	}
+
+	newBalance := balance + delta
+	if newBalance < 0 {
+		return nil, ErrInsufficientFunds
+	}
+
+	var withdraw, deposit int
+	if delta > 0 {
+		deposit = delta
+	} else {
+		withdraw = -delta
+	}
+
+	transactionRef := manualTransactionRefPrefix + uuid.NewString()
+
+	extras, errMarshal := json.Marshal(map[string]string{
+		"admin":   AdminFromContext(ctx),
+		"comment": in.Comment,
+	})
+	if errMarshal != nil {
+		return nil, errMarshal //nolint:wrapcheck // intentional
+	}
+
+	// вебхук оператору ставит сам NewPayment
+	_, errNewPayment := repo.NewPayment(
+		ctx,
+		tx,
+		user.ID,
+		currencyID,
+		withdraw,
+		deposit,
+		transactionRef,
+		repo.PaymentContext{Reason: string(in.ReasonCode), Extras: extras},
+	)
+	if errNewPayment != nil {
+		return nil, errNewPayment //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.invalidated(walletcache.InvalidateBalance(ctx, r.cache, user.ID))
+
+	r.logger.Info(
+		"manual balance adjustment",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("player", user.Name),
+		zap.Int("delta", delta),
+		zap.String("reason_code", string(in.ReasonCode)),
+		zap.String("transaction_ref", transactionRef),
+	)
+
+	return &types.AdjustBalanceResponse{
+		NewBalance:     newBalance,
+		TransactionRef: transactionRef,
+	}, nil
+}
+
+// ForceRollback откатывает платёж в обход провайдера, например когда он так и не прислал rollbackTransaction.
+func (r *RPCService) ForceRollback(
+	ctx context.Context,
+	in *types.ForceRollbackRequest,
+) (*types.ForceRollbackResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	payment, errFindPayment := repo.FindPaymentByTransactionRef(ctx, tx, in.TransactionRef)
+	if errFindPayment != nil {
+		return nil, errFindPayment //nolint:wrapcheck // intentional
+	}
+
+	if payment.RollBackAt != nil {
+		return nil, ErrAlreadyRolledBack
+	}
+
+	rolledBack, errRollbackPayment := repo.RollbackPayment(
+		repo.WithWebhookReason(ctx, forcedRollbackReason),
+		tx,
+		in.TransactionRef,
+	)
+	if errRollbackPayment != nil {
+		return nil, errRollbackPayment //nolint:wrapcheck // intentional
+	}
+
+	// провайдер успел откатить платёж между проверкой и откатом
+	if len(rolledBack) == 0 {
+		return nil, ErrAlreadyRolledBack
+	}
+
+	user, errFindUserByID := repo.FindUserByID(ctx, tx, payment.UserID)
+	if errFindUserByID != nil {
+		return nil, errFindUserByID //nolint:wrapcheck // intentional
+	}
+
+	balance, errGetDepositByUserID := repo.GetDepositByUserID(ctx, tx, user.ID)
+	if errGetDepositByUserID != nil {
+		return nil, errGetDepositByUserID //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.invalidated(walletcache.InvalidateBalance(ctx, r.cache, user.ID))
+
+	r.logger.Info(
+		"forced rollback",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.String("player", user.Name),
+		zap.String("transaction_ref", in.TransactionRef),
+	)
+
+	return &types.ForceRollbackResponse{
+		PlayerName: user.Name,
+		NewBalance: balance,
+	}, nil
+}
@@ -0,0 +1,166 @@
+package admin_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+)
+
+var errUnexpectedQuery = errors.New("unexpected query")
+
+// scriptedResult ответ на запрос, в текст которого входит match.
+type scriptedResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+}
+
+// scriptedDriver отвечает на запросы по сценарию и запоминает их. Сценарий выбирается по dsn.
+type scriptedDriver struct {
+	mu      sync.Mutex
+	scripts map[string]*script
+}
+
+type script struct {
+	results []scriptedResult
+	queries []string
+	args    [][]driver.NamedValue
+}
+
+func (d *scriptedDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return &scriptedConn{script: d.scripts[dsn]}, nil
+}
+
+type scriptedConn struct {
+	script *script
+}
+
+func (c *scriptedConn) Prepare(string) (driver.Stmt, error) { return nil, errUnexpectedQuery }
+func (c *scriptedConn) Close() error                        { return nil }
+func (c *scriptedConn) Begin() (driver.Tx, error)           { return scriptedTx{}, nil }
+
+func (c *scriptedConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.script.queries = append(c.script.queries, query)
+	c.script.args = append(c.script.args, args)
+
+	for _, result := range c.script.results {
+		if strings.Contains(query, result.match) {
+			return &scriptedRows{columns: result.columns, rows: result.rows}, nil
+		}
+	}
+
+	return nil, errUnexpectedQuery
+}
+
+func (c *scriptedConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.script.queries = append(c.script.queries, query)
+
+	return nil, errUnexpectedQuery
+}
+
+type scriptedTx struct{}
+
+func (scriptedTx) Commit() error   { return nil }
+func (scriptedTx) Rollback() error { return nil }
+
+type scriptedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *scriptedRows) Columns() []string { return r.columns }
+func (r *scriptedRows) Close() error      { return nil }
+
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+var fakeDriver = &scriptedDriver{scripts: map[string]*script{}}
+
+func init() {
+	sql.Register("admin-scripted", fakeDriver)
+}
+
+func openScripted(t *testing.T, results ...scriptedResult) (*sqlx.DB, *script) {
+	t.Helper()
+
+	s := &script{results: results}
+
+	fakeDriver.mu.Lock()
+	fakeDriver.scripts[t.Name()] = s
+	fakeDriver.mu.Unlock()
+
+	db, errOpen := sqlx.Open("admin-scripted", t.Name())
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db, s
+}
+
+var playerRow = scriptedResult{
+	match: "FROM public.users",
+	columns: []string{
+		"id", "created_at", "name", "status", "excluded_until", "status_reason", "updated_at", "operator_id",
+	},
+	rows: [][]driver.Value{{int64(1), time.Now(), "player", "active", nil, "", time.Now(), nil}},
+}
+
+func TestAdjustBalanceWithoutWallet(t *testing.T) {
+	db, s := openScripted(t, playerRow, scriptedResult{match: "FROM billing.payments", columns: []string{"id"}})
+	svc := admin.NewRPCService(db, nil, zap.NewNop())
+
+	_, errCredit := svc.CreditPlayer(context.Background(), &types.AdjustBalanceRequest{
+		PlayerName: "player",
+		Amount:     10,
+		ReasonCode: types.AdjustmentGoodwill,
+	})
+	if !errors.Is(errCredit, admin.ErrNoWallet) {
+		t.Fatalf("got %v, want %v", errCredit, admin.ErrNoWallet)
+	}
+
+	for _, query := range s.queries {
+		if strings.Contains(query, "INSERT") {
+			t.Fatalf("payment written for a player without wallet: %s", query)
+		}
+	}
+}
+
+func TestAdjustBalanceLocksPlayer(t *testing.T) {
+	db, s := openScripted(t, playerRow, scriptedResult{match: "FROM billing.payments", columns: []string{"id"}})
+	svc := admin.NewRPCService(db, nil, zap.NewNop())
+
+	_, _ = svc.DebitPlayer(context.Background(), &types.AdjustBalanceRequest{
+		PlayerName: "player",
+		Amount:     10,
+		ReasonCode: types.AdjustmentCorrection,
+	})
+
+	if len(s.queries) == 0 || !strings.Contains(s.queries[0], "FOR UPDATE") {
+		t.Fatalf("player is not locked before the balance is read: %v", s.queries)
+	}
+}
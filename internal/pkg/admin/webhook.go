@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const minWebhookSecretLength = 16
+
+var (
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute http(s) url")
+	ErrWebhookSecretLength = errors.New("webhook secret must be at least 16 characters")
+)
+
+func (r *RPCService) RegisterOperatorWebhook(
+	ctx context.Context,
+	in *types.RegisterOperatorWebhookRequest,
+) (*types.RegisterOperatorWebhookResponse, error) {
+	parsed, errParse := url.Parse(in.URL)
+	if errParse != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	if len(in.Secret) < minWebhookSecretLength {
+		return nil, ErrWebhookSecretLength
+	}
+
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	webhook, errNewOperatorWebhook := repo.NewOperatorWebhook(ctx, tx, in.OperatorID, in.URL, in.Secret)
+	if errNewOperatorWebhook != nil {
+		return nil, errNewOperatorWebhook //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	r.logger.Info(
+		"operator webhook registered",
+		zap.String("admin", AdminFromContext(ctx)),
+		zap.Int("operator_id", webhook.OperatorID),
+		zap.Int("webhook_id", webhook.ID),
+	)
+
+	return &types.RegisterOperatorWebhookResponse{ID: webhook.ID}, nil
+}
+
+func (r *RPCService) DeactivateOperatorWebhook(
+	ctx context.Context,
+	in *types.DeactivateOperatorWebhookRequest,
+) (*types.DeactivateOperatorWebhookResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if errDeactivate := repo.DeactivateOperatorWebhook(ctx, tx, in.ID); errDeactivate != nil {
+		return nil, errDeactivate //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	return &types.DeactivateOperatorWebhookResponse{}, nil
+}
+
+// ReplayWebhookDeadLetter повторная отправка доставки из dead letter, попытки начинаются заново.
+func (r *RPCService) ReplayWebhookDeadLetter(
+	ctx context.Context,
+	in *types.ReplayWebhookDeadLetterRequest,
+) (*types.ReplayWebhookDeadLetterResponse, error) {
+	tx, errBeginTxx := r.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	deliveryID, errReplay := repo.ReplayWebhookDeadLetter(ctx, tx, in.ID)
+	if errReplay != nil {
+		return nil, errReplay //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	return &types.ReplayWebhookDeadLetterResponse{DeliveryID: deliveryID}, nil
+}
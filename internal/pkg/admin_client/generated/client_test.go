@@ -0,0 +1,213 @@
+package generated_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"gitlab.com/pjrpc/pjrpc/v2"
+	"gitlab.com/pjrpc/pjrpc/v2/client"
+
+	adminGenerated "github.com/rinatusmanov/jsonrpc20/internal/pkg/admin/generated"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/admin_client/generated"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+)
+
+// fakeAdmin отвечает на вызовы клиента. Методы, которые тест не переопределил, паникуют
+// на nil интерфейсе, и pjrpc вернёт клиенту ошибку.
+type fakeAdmin struct {
+	adminGenerated.AdminServiceServer
+}
+
+func (fakeAdmin) SetPlayerStatus(
+	_ context.Context,
+	in *types.SetPlayerStatusRequest,
+) (*types.SetPlayerStatusResponse, error) {
+	return &types.SetPlayerStatusResponse{PlayerName: in.PlayerName, Status: in.Status}, nil
+}
+
+func (fakeAdmin) CreditPlayer(context.Context, *types.AdjustBalanceRequest) (*types.AdjustBalanceResponse, error) {
+	return &types.AdjustBalanceResponse{}, nil
+}
+
+func (fakeAdmin) DebitPlayer(context.Context, *types.AdjustBalanceRequest) (*types.AdjustBalanceResponse, error) {
+	return &types.AdjustBalanceResponse{}, nil
+}
+
+func (fakeAdmin) ForceRollback(context.Context, *types.ForceRollbackRequest) (*types.ForceRollbackResponse, error) {
+	return &types.ForceRollbackResponse{}, nil
+}
+
+func (fakeAdmin) GetPlayer(context.Context, *types.GetPlayerRequest) (*types.GetPlayerResponse, error) {
+	return &types.GetPlayerResponse{}, nil
+}
+
+func (fakeAdmin) GetBalanceHistory(context.Context, *types.GetBalanceHistoryRequest) (*types.GetBalanceHistoryResponse, error) {
+	return &types.GetBalanceHistoryResponse{}, nil
+}
+
+func (fakeAdmin) GrantBonus(context.Context, *types.GrantBonusRequest) (*types.GrantBonusResponse, error) {
+	return &types.GrantBonusResponse{}, nil
+}
+
+func (fakeAdmin) CreateCurrency(context.Context, *types.CreateCurrencyRequest) (*types.Currency, error) {
+	return &types.Currency{}, nil
+}
+
+func (fakeAdmin) ListCurrencies(context.Context, *types.ListCurrenciesRequest) (*types.ListCurrenciesResponse, error) {
+	return &types.ListCurrenciesResponse{}, nil
+}
+
+func (fakeAdmin) SetCurrencyEnabled(context.Context, *types.SetCurrencyEnabledRequest) (*types.Currency, error) {
+	return &types.Currency{}, nil
+}
+
+func (fakeAdmin) CreateBankGroup(context.Context, *types.CreateBankGroupRequest) (*types.BankGroup, error) {
+	return &types.BankGroup{}, nil
+}
+
+func (fakeAdmin) UpdateBankGroup(context.Context, *types.UpdateBankGroupRequest) (*types.BankGroup, error) {
+	return &types.BankGroup{}, nil
+}
+
+func (fakeAdmin) ListBankGroups(context.Context, *types.ListBankGroupsRequest) (*types.ListBankGroupsResponse, error) {
+	return &types.ListBankGroupsResponse{}, nil
+}
+
+func (fakeAdmin) DeleteBankGroup(context.Context, *types.DeleteBankGroupRequest) (*types.DeleteBankGroupResponse, error) {
+	return &types.DeleteBankGroupResponse{}, nil
+}
+
+func (fakeAdmin) SetOperatorBankGroup(context.Context, *types.SetOperatorBankGroupRequest) (*types.SetOperatorBankGroupResponse, error) {
+	return &types.SetOperatorBankGroupResponse{}, nil
+}
+
+func (fakeAdmin) RegisterOperatorWebhook(context.Context, *types.RegisterOperatorWebhookRequest) (*types.RegisterOperatorWebhookResponse, error) {
+	return &types.RegisterOperatorWebhookResponse{}, nil
+}
+
+func (fakeAdmin) DeactivateOperatorWebhook(context.Context, *types.DeactivateOperatorWebhookRequest) (*types.DeactivateOperatorWebhookResponse, error) {
+	return &types.DeactivateOperatorWebhookResponse{}, nil
+}
+
+func (fakeAdmin) ReplayWebhookDeadLetter(context.Context, *types.ReplayWebhookDeadLetterRequest) (*types.ReplayWebhookDeadLetterResponse, error) {
+	return &types.ReplayWebhookDeadLetterResponse{}, nil
+}
+
+func (fakeAdmin) GetRevenueReport(context.Context, *types.GetRevenueReportRequest) (*types.GetRevenueReportResponse, error) {
+	return &types.GetRevenueReportResponse{}, nil
+}
+
+// newClient клиент к настоящему серверу админки поверх httptest: ответ проходит весь путь
+// от генерированного сервера до разбора в генерированном клиенте.
+func newClient(t *testing.T) generated.AdminServiceClient {
+	t.Helper()
+
+	srv := pjrpc.NewServerHTTP()
+	adminGenerated.RegisterAdminServiceServer(srv, fakeAdmin{})
+
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+
+	cl, errNew := client.New(server.URL + "/admin/rpc/")
+	if errNew != nil {
+		t.Fatalf("new client: %v", errNew)
+	}
+
+	return generated.NewAdminServiceClient(cl)
+}
+
+func TestClientSetPlayerStatus(t *testing.T) {
+	response, errSetPlayerStatus := newClient(t).SetPlayerStatus(context.Background(), &types.SetPlayerStatusRequest{
+		PlayerName: "player",
+		Status:     types.PlayerSuspended,
+		Reason:     "support ticket",
+	})
+	if errSetPlayerStatus != nil {
+		t.Fatalf("set player status: %v", errSetPlayerStatus)
+	}
+
+	if response.PlayerName != "player" || response.Status != types.PlayerSuspended {
+		t.Fatalf("response = %+v", response)
+	}
+}
+
+// TestClientDecodesEveryMethod каждый метод клиента разбирает ответ сервера в непустой результат.
+func TestClientDecodesEveryMethod(t *testing.T) {
+	ctx := context.Background()
+	cl := newClient(t)
+
+	for _, tc := range []struct {
+		method string
+		call   func(cl generated.AdminServiceClient) (interface{}, error)
+	}{
+		{"CreditPlayer", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.CreditPlayer(ctx, &types.AdjustBalanceRequest{})
+		}},
+		{"DebitPlayer", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.DebitPlayer(ctx, &types.AdjustBalanceRequest{})
+		}},
+		{"ForceRollback", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.ForceRollback(ctx, &types.ForceRollbackRequest{})
+		}},
+		{"GetPlayer", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.GetPlayer(ctx, &types.GetPlayerRequest{})
+		}},
+		{"GetBalanceHistory", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.GetBalanceHistory(ctx, &types.GetBalanceHistoryRequest{})
+		}},
+		{"GrantBonus", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.GrantBonus(ctx, &types.GrantBonusRequest{})
+		}},
+		{"CreateCurrency", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.CreateCurrency(ctx, &types.CreateCurrencyRequest{})
+		}},
+		{"ListCurrencies", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.ListCurrencies(ctx, &types.ListCurrenciesRequest{})
+		}},
+		{"SetCurrencyEnabled", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.SetCurrencyEnabled(ctx, &types.SetCurrencyEnabledRequest{})
+		}},
+		{"CreateBankGroup", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.CreateBankGroup(ctx, &types.CreateBankGroupRequest{})
+		}},
+		{"UpdateBankGroup", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.UpdateBankGroup(ctx, &types.UpdateBankGroupRequest{})
+		}},
+		{"ListBankGroups", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.ListBankGroups(ctx, &types.ListBankGroupsRequest{})
+		}},
+		{"DeleteBankGroup", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.DeleteBankGroup(ctx, &types.DeleteBankGroupRequest{})
+		}},
+		{"SetOperatorBankGroup", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.SetOperatorBankGroup(ctx, &types.SetOperatorBankGroupRequest{})
+		}},
+		{"RegisterOperatorWebhook", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.RegisterOperatorWebhook(ctx, &types.RegisterOperatorWebhookRequest{})
+		}},
+		{"DeactivateOperatorWebhook", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.DeactivateOperatorWebhook(ctx, &types.DeactivateOperatorWebhookRequest{})
+		}},
+		{"ReplayWebhookDeadLetter", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.ReplayWebhookDeadLetter(ctx, &types.ReplayWebhookDeadLetterRequest{})
+		}},
+		{"GetRevenueReport", func(cl generated.AdminServiceClient) (interface{}, error) {
+			return cl.GetRevenueReport(ctx, &types.GetRevenueReportRequest{})
+		}},
+	} {
+		tc := tc
+
+		t.Run(tc.method, func(t *testing.T) {
+			response, errCall := tc.call(cl)
+			if errCall != nil {
+				t.Fatalf("%s: %v", tc.method, errCall)
+			}
+
+			if reflect.ValueOf(response).IsNil() {
+				t.Fatalf("%s: nil result", tc.method)
+			}
+		})
+	}
+}
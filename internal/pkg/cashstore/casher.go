@@ -1,30 +1,102 @@
 package cashstore
 
-// Cache интерфейс для хранения в кэше данных
-// здесь и сейчас данные хранятся в собственной памяти
-// однако в дальнейшем можно перевести хранение например в редиску.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrValueTooLarge = errors.New("cache value is too large")
+	ErrClosed        = errors.New("cache is closed")
+	ErrUnknownScheme = errors.New("unknown cache scheme, expected memory or redis")
+)
+
+// Cache интерфейс для хранения в кэше данных. В памяти процесса это LRU, общий для нескольких
+// экземпляров сервиса кэш - Redis. Кэш не источник истины: ошибка бэкенда означает промах, а не отказ.
 type Cache interface {
-	Cache(string)
-	Check(string) bool
+	// Cache запоминает ключ без значения и срока жизни.
+	Cache(key string)
+	// Check есть ли ключ, при ошибке бэкенда false.
+	Check(key string) bool
+	// Get второй результат false, если ключа нет или срок его жизни истёк.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set ttl 0 - без срока жизни.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX записывает значение, только если ключа ещё нет, true если записал.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+	// CompareAndDelete атомарно удаляет ключ, только если его значение равно value, true если удалил.
+	CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error)
+	Close() error
 }
 
-type cache struct {
-	data map[string]struct{}
+//nolint:nolintlint //nolint:ireturn
+func NewCache() Cache {
+	return NewLRU(0, 0)
 }
 
-func (c *cache) Cache(s string) {
-	c.data[s] = struct{}{}
+// Options ограничения кэша. 0 - без ограничения.
+type Options struct {
+	// MaxEntries и MaxBytes ограничивают LRU в памяти, Redis ограничивается своим maxmemory.
+	MaxEntries int
+	MaxBytes   int
+	// MaxValueSize значения больше не кэшируются, Set возвращает ErrValueTooLarge.
+	MaxValueSize int
 }
 
-func (c *cache) Check(s string) bool {
-	_, ok := c.data[s]
+// Open кэш по адресу:
+//
+//	memory://                                   LRU в памяти процесса
+//	redis://[:password@]host:port[/db][?prefix=jsonrpc20:&pool=8]
+//
+//nolint:ireturn // intentional
+func Open(rawURL string, options Options) (Cache, error) {
+	parsed, errParse := url.Parse(rawURL)
+	if errParse != nil {
+		return nil, fmt.Errorf("parse cache url: %w", errParse)
+	}
 
-	return ok
-}
+	switch parsed.Scheme {
+	case "memory":
+		lru := NewLRU(options.MaxEntries, options.MaxBytes)
+		lru.maxValueSize = options.MaxValueSize
 
-//nolint:nolintlint //nolint:ireturn
-func NewCache() Cache {
-	return &cache{
-		data: make(map[string]struct{}),
+		return lru, nil
+	case "redis":
+		redisOptions := RedisOptions{
+			Addr:         parsed.Host,
+			Prefix:       parsed.Query().Get("prefix"),
+			MaxValueSize: options.MaxValueSize,
+		}
+
+		if password, ok := parsed.User.Password(); ok {
+			redisOptions.Password = password
+		}
+
+		if db := parsed.Path; len(db) > 1 {
+			number, errAtoi := strconv.Atoi(db[1:])
+			if errAtoi != nil {
+				return nil, fmt.Errorf("parse redis db: %w", errAtoi)
+			}
+
+			redisOptions.DB = number
+		}
+
+		if pool := parsed.Query().Get("pool"); pool != "" {
+			size, errAtoi := strconv.Atoi(pool)
+			if errAtoi != nil {
+				return nil, fmt.Errorf("parse redis pool: %w", errAtoi)
+			}
+
+			redisOptions.PoolSize = size
+		}
+
+		return NewRedis(redisOptions), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, parsed.Scheme)
 	}
 }
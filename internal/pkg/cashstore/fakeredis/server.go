@@ -0,0 +1,380 @@
+// Package fakeredis минимальный сервер с протоколом Redis в памяти процесса, чтобы проверять
+// cashstore.Redis и запускать сервис с общим кэшем без настоящего Redis.
+// Поддерживаются PING, AUTH, SELECT, GET, SET (EX, PX, NX, XX), DEL, EXISTS, DBSIZE, FLUSHALL
+// и EVAL скриптов, которые использует cashstore. Lua здесь нет: скрипт узнаётся по тексту.
+package fakeredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+)
+
+type Server struct {
+	listener net.Listener
+	password string
+
+	mu  sync.Mutex
+	dbs map[int]map[string]entry
+	now func() time.Time
+
+	connsMu sync.Mutex
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+}
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Start слушает addr, "127.0.0.1:0" - свободный порт. Пустой password - без AUTH.
+func Start(addr, password string) (*Server, error) {
+	listener, errListen := net.Listen("tcp", addr)
+	if errListen != nil {
+		return nil, fmt.Errorf("listen: %w", errListen)
+	}
+
+	server := &Server{
+		listener: listener,
+		password: password,
+		dbs:      make(map[int]map[string]entry),
+		now:      time.Now,
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	server.wg.Add(1)
+
+	go server.accept()
+
+	return server, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close закрывает listener и все клиентские соединения.
+func (s *Server) Close() error {
+	errClose := s.listener.Close()
+
+	s.connsMu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connsMu.Unlock()
+
+	s.wg.Wait()
+
+	return errClose //nolint:wrapcheck // intentional
+}
+
+// SetNow подменяет часы, чтобы проверять истечение TTL без ожидания.
+func (s *Server) SetNow(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	var conns sync.WaitGroup
+	defer conns.Wait()
+
+	for {
+		conn, errAccept := s.listener.Accept()
+		if errAccept != nil {
+			return
+		}
+
+		s.connsMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.connsMu.Unlock()
+
+		conns.Add(1)
+
+		go func() {
+			defer conns.Done()
+			s.serve(conn)
+		}()
+	}
+}
+
+type session struct {
+	db     int
+	authed bool
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.connsMu.Lock()
+		delete(s.conns, conn)
+		s.connsMu.Unlock()
+
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	state := &session{authed: s.password == ""}
+
+	for {
+		request, errReadReply := cashstore.ReadReply(reader)
+		if errReadReply != nil {
+			return
+		}
+
+		args, ok := commandArgs(request)
+		if !ok {
+			writeError(writer, "ERR Protocol error: expected array of bulk strings")
+
+			return
+		}
+
+		s.execute(writer, state, args)
+
+		if errFlush := writer.Flush(); errFlush != nil {
+			return
+		}
+	}
+}
+
+func commandArgs(request interface{}) ([]string, bool) {
+	items, ok := request.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+
+	args := make([]string, 0, len(items))
+
+	for _, item := range items {
+		arg, isBytes := item.([]byte)
+		if !isBytes {
+			return nil, false
+		}
+
+		args = append(args, string(arg))
+	}
+
+	return args, true
+}
+
+//nolint:cyclop // один switch по командам
+func (s *Server) execute(w *bufio.Writer, state *session, args []string) {
+	command := strings.ToUpper(args[0])
+
+	if !state.authed && command != "AUTH" {
+		writeError(w, "NOAUTH Authentication required.")
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db := s.db(state.db)
+
+	switch command {
+	case "PING":
+		fmt.Fprint(w, "+PONG\r\n")
+	case "AUTH":
+		if len(args) != 2 || args[1] != s.password { //nolint:gomnd // AUTH password
+			writeError(w, "WRONGPASS invalid password")
+
+			return
+		}
+
+		state.authed = true
+		fmt.Fprint(w, "+OK\r\n")
+	case "SELECT":
+		number, errAtoi := strconv.Atoi(argAt(args, 1))
+		if errAtoi != nil {
+			writeError(w, "ERR invalid DB index")
+
+			return
+		}
+
+		state.db = number
+		fmt.Fprint(w, "+OK\r\n")
+	case "GET":
+		value, ok := s.get(db, argAt(args, 1))
+		if !ok {
+			fmt.Fprint(w, "$-1\r\n")
+
+			return
+		}
+
+		writeBulk(w, value)
+	case "SET":
+		s.set(w, db, args)
+	case "DEL", "EXISTS":
+		count := 0
+
+		for _, key := range args[1:] {
+			if _, ok := s.get(db, key); ok {
+				count++
+
+				if command == "DEL" {
+					delete(db, key)
+				}
+			}
+		}
+
+		fmt.Fprintf(w, ":%d\r\n", count)
+	case "DBSIZE":
+		count := 0
+
+		for key := range db {
+			if _, ok := s.get(db, key); ok {
+				count++
+			}
+		}
+
+		fmt.Fprintf(w, ":%d\r\n", count)
+	case "EVAL":
+		s.eval(w, db, args)
+	case "FLUSHALL":
+		s.dbs = make(map[int]map[string]entry)
+		fmt.Fprint(w, "+OK\r\n")
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+var errSyntax = errors.New("ERR syntax error")
+
+func (s *Server) set(w *bufio.Writer, db map[string]entry, args []string) {
+	if len(args) < 3 { //nolint:gomnd // SET key value
+		writeError(w, "ERR wrong number of arguments for 'set' command")
+
+		return
+	}
+
+	key, item := args[1], entry{value: []byte(args[2])}
+
+	var onlyNew, onlyExisting bool
+
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			onlyNew = true
+		case "XX":
+			onlyExisting = true
+		case "PX", "EX":
+			amount, errParseInt := strconv.ParseInt(argAt(args, i+1), 10, 64)
+			if errParseInt != nil || amount <= 0 {
+				writeError(w, errSyntax.Error())
+
+				return
+			}
+
+			unit := time.Millisecond
+			if strings.ToUpper(args[i]) == "EX" {
+				unit = time.Second
+			}
+
+			item.expiresAt = s.now().Add(time.Duration(amount) * unit)
+			i++
+		default:
+			writeError(w, errSyntax.Error())
+
+			return
+		}
+	}
+
+	_, exists := s.get(db, key)
+	if (onlyNew && exists) || (onlyExisting && !exists) {
+		fmt.Fprint(w, "$-1\r\n")
+
+		return
+	}
+
+	db[key] = item
+	fmt.Fprint(w, "+OK\r\n")
+}
+
+// eval EVAL script numkeys key [key ...] arg [arg ...] для известных скриптов.
+func (s *Server) eval(w *bufio.Writer, db map[string]entry, args []string) {
+	numKeys, errAtoi := strconv.Atoi(argAt(args, 2)) //nolint:gomnd // EVAL script numkeys
+	if errAtoi != nil || numKeys < 0 || len(args) < 3+numKeys {
+		writeError(w, "ERR wrong number of arguments for 'eval' command")
+
+		return
+	}
+
+	keys, scriptArgs := args[3:3+numKeys], args[3+numKeys:]
+
+	switch args[1] {
+	case cashstore.CompareAndDeleteScript:
+		if len(keys) != 1 || len(scriptArgs) != 1 {
+			writeError(w, "ERR wrong number of arguments for compare and delete script")
+
+			return
+		}
+
+		value, ok := s.get(db, keys[0])
+		if !ok || string(value) != scriptArgs[0] {
+			fmt.Fprint(w, ":0\r\n")
+
+			return
+		}
+
+		delete(db, keys[0])
+		fmt.Fprint(w, ":1\r\n")
+	default:
+		writeError(w, "NOSCRIPT fakeredis runs only cashstore scripts")
+	}
+}
+
+func (s *Server) db(number int) map[string]entry {
+	db, ok := s.dbs[number]
+	if !ok {
+		db = make(map[string]entry)
+		s.dbs[number] = db
+	}
+
+	return db
+}
+
+// get удаляет просроченный ключ, как это делает Redis при обращении.
+func (s *Server) get(db map[string]entry, key string) ([]byte, bool) {
+	item, ok := db[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !item.expiresAt.IsZero() && !s.now().Before(item.expiresAt) {
+		delete(db, key)
+
+		return nil, false
+	}
+
+	return item.value, true
+}
+
+func argAt(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+
+	return ""
+}
+
+func writeBulk(w *bufio.Writer, value []byte) {
+	fmt.Fprintf(w, "$%d\r\n", len(value))
+	w.Write(value)        //nolint:errcheck // ошибка записи всплывёт на Flush
+	w.WriteString("\r\n") //nolint:errcheck // ошибка записи всплывёт на Flush
+}
+
+func writeError(w *bufio.Writer, message string) {
+	fmt.Fprintf(w, "-%s\r\n", message)
+}
@@ -0,0 +1,231 @@
+package cashstore
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Cache = (*LRU)(nil)
+
+// LRU кэш в памяти процесса. При превышении MaxEntries или MaxBytes вытесняются давно не читанные
+// ключи, просроченные ключи удаляются при обращении к ним или при вытеснении.
+type LRU struct {
+	mu           sync.Mutex
+	items        map[string]*list.Element
+	order        *list.List
+	bytes        int
+	maxEntries   int
+	maxBytes     int
+	maxValueSize int
+	closed       bool
+	now          func() time.Time
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e *lruEntry) size() int {
+	return len(e.key) + len(e.value)
+}
+
+func (e *lruEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// NewLRU 0 в maxEntries или maxBytes - без ограничения.
+func NewLRU(maxEntries, maxBytes int) *LRU {
+	return &LRU{
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		now:        time.Now,
+	}
+}
+
+func (c *LRU) Cache(key string) {
+	_ = c.Set(context.Background(), key, nil, 0)
+}
+
+func (c *LRU) Check(key string) bool {
+	_, ok, _ := c.Get(context.Background(), key)
+
+	return ok
+}
+
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, false, ErrClosed
+	}
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry) //nolint:forcetypeassert // в списке только *lruEntry
+	if entry.expired(c.now()) {
+		c.remove(element)
+
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.set(key, value, ttl)
+}
+
+func (c *LRU) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*lruEntry) //nolint:forcetypeassert // в списке только *lruEntry
+		if !entry.expired(c.now()) {
+			return false, nil
+		}
+	}
+
+	if errSet := c.set(key, value, ttl); errSet != nil {
+		return false, errSet
+	}
+
+	return true, nil
+}
+
+func (c *LRU) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.remove(element)
+		}
+	}
+
+	return nil
+}
+
+func (c *LRU) CompareAndDelete(_ context.Context, key string, value []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false, ErrClosed
+	}
+
+	element, ok := c.items[key]
+	if !ok {
+		return false, nil
+	}
+
+	entry := element.Value.(*lruEntry) //nolint:forcetypeassert // в списке только *lruEntry
+	if entry.expired(c.now()) || !bytes.Equal(entry.value, value) {
+		return false, nil
+	}
+
+	c.remove(element)
+
+	return true, nil
+}
+
+func (c *LRU) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+
+	return nil
+}
+
+// Len число ключей вместе с ещё не удалёнными просроченными.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU) set(key string, value []byte, ttl time.Duration) error {
+	if c.closed {
+		return ErrClosed
+	}
+
+	if c.maxValueSize > 0 && len(value) > c.maxValueSize {
+		return ErrValueTooLarge
+	}
+
+	entry := &lruEntry{key: key, value: append([]byte(nil), value...)}
+
+	// значение, которое не влезает в кэш целиком, вытеснило бы всё остальное
+	if c.maxBytes > 0 && entry.size() > c.maxBytes {
+		return ErrValueTooLarge
+	}
+
+	if ttl > 0 {
+		entry.expiresAt = c.now().Add(ttl)
+	}
+
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+
+	c.items[key] = c.order.PushFront(entry)
+	c.bytes += entry.size()
+
+	c.evict()
+
+	return nil
+}
+
+func (c *LRU) evict() {
+	now := c.now()
+
+	// сначала просроченные, потом самые давние
+	for element := c.order.Back(); element != nil && c.overLimit(); {
+		prev := element.Prev()
+
+		if element.Value.(*lruEntry).expired(now) { //nolint:forcetypeassert // в списке только *lruEntry
+			c.remove(element)
+		}
+
+		element = prev
+	}
+
+	for c.overLimit() {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) overLimit() bool {
+	return (c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+func (c *LRU) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*lruEntry) //nolint:forcetypeassert // в списке только *lruEntry
+	delete(c.items, entry.key)
+	c.bytes -= entry.size()
+}
@@ -0,0 +1,219 @@
+package cashstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLRU LRU с часами, которые двигает тест.
+func newTestLRU(maxEntries, maxBytes int) (*LRU, *time.Time) {
+	now := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	lru := NewLRU(maxEntries, maxBytes)
+	lru.now = func() time.Time { return now }
+
+	return lru, &now
+}
+
+func mustSet(t *testing.T, cache Cache, key, value string, ttl time.Duration) {
+	t.Helper()
+
+	if errSet := cache.Set(context.Background(), key, []byte(value), ttl); errSet != nil {
+		t.Fatalf("set %s: %v", key, errSet)
+	}
+}
+
+func has(cache Cache, key string) bool {
+	_, ok, _ := cache.Get(context.Background(), key)
+
+	return ok
+}
+
+func TestLRUEvictsByEntries(t *testing.T) {
+	lru, _ := newTestLRU(2, 0)
+
+	mustSet(t, lru, "a", "1", 0)
+	mustSet(t, lru, "b", "2", 0)
+
+	// чтение a делает давним b
+	if !has(lru, "a") {
+		t.Fatal("a missing")
+	}
+
+	mustSet(t, lru, "c", "3", 0)
+
+	if has(lru, "b") || !has(lru, "a") || !has(lru, "c") {
+		t.Fatalf("want b evicted, got a=%v b=%v c=%v", has(lru, "a"), has(lru, "b"), has(lru, "c"))
+	}
+
+	if lru.Len() != 2 {
+		t.Fatalf("len = %d, want 2", lru.Len())
+	}
+}
+
+func TestLRUEvictsByBytes(t *testing.T) {
+	// ключ и значение по 1 + 4 байта
+	lru, _ := newTestLRU(0, 10)
+
+	mustSet(t, lru, "a", "1111", 0)
+	mustSet(t, lru, "b", "2222", 0)
+	mustSet(t, lru, "c", "3333", 0)
+
+	if has(lru, "a") || !has(lru, "b") || !has(lru, "c") {
+		t.Fatalf("want a evicted, got a=%v b=%v c=%v", has(lru, "a"), has(lru, "b"), has(lru, "c"))
+	}
+
+	// перезапись не должна считать старое значение дважды
+	mustSet(t, lru, "c", "33", 0)
+
+	if lru.bytes != 8 {
+		t.Fatalf("bytes = %d, want 8", lru.bytes)
+	}
+
+	if errSet := lru.Set(context.Background(), "big", make([]byte, 10), 0); !errors.Is(errSet, ErrValueTooLarge) {
+		t.Fatalf("set big = %v, want ErrValueTooLarge", errSet)
+	}
+
+	if !has(lru, "b") || !has(lru, "c") {
+		t.Fatal("too large value evicted other keys")
+	}
+}
+
+func TestLRUEvictsExpiredFirst(t *testing.T) {
+	lru, now := newTestLRU(2, 0)
+
+	mustSet(t, lru, "a", "1", 0)
+	mustSet(t, lru, "b", "2", time.Second)
+	*now = now.Add(time.Second)
+
+	// a давнее, но просрочено b
+	mustSet(t, lru, "c", "3", 0)
+
+	if !has(lru, "a") || !has(lru, "c") {
+		t.Fatalf("want b evicted, got a=%v c=%v", has(lru, "a"), has(lru, "c"))
+	}
+}
+
+func TestLRUTTL(t *testing.T) {
+	lru, now := newTestLRU(0, 0)
+
+	mustSet(t, lru, "short", "1", time.Second)
+	mustSet(t, lru, "forever", "2", 0)
+
+	*now = now.Add(time.Second - time.Nanosecond)
+
+	if !has(lru, "short") {
+		t.Fatal("short expired before ttl")
+	}
+
+	*now = now.Add(time.Nanosecond)
+
+	if has(lru, "short") {
+		t.Fatal("short not expired after ttl")
+	}
+
+	*now = now.Add(time.Hour)
+
+	if !has(lru, "forever") {
+		t.Fatal("key without ttl expired")
+	}
+
+	if lru.Len() != 1 {
+		t.Fatalf("len = %d, expired key not removed on read", lru.Len())
+	}
+}
+
+func TestLRUSetNX(t *testing.T) {
+	ctx := context.Background()
+	lru, now := newTestLRU(0, 0)
+
+	if written, _ := lru.SetNX(ctx, "a", []byte("1"), time.Second); !written {
+		t.Fatal("setnx on missing key not written")
+	}
+
+	if written, _ := lru.SetNX(ctx, "a", []byte("2"), time.Second); written {
+		t.Fatal("setnx on existing key written")
+	}
+
+	if value, _, _ := lru.Get(ctx, "a"); string(value) != "1" {
+		t.Fatalf("a = %q, want 1", value)
+	}
+
+	*now = now.Add(time.Second)
+
+	if written, _ := lru.SetNX(ctx, "a", []byte("3"), 0); !written {
+		t.Fatal("setnx on expired key not written")
+	}
+
+	if value, _, _ := lru.Get(ctx, "a"); string(value) != "3" {
+		t.Fatalf("a = %q, want 3", value)
+	}
+}
+
+func TestLRUGetCopiesValue(t *testing.T) {
+	lru, _ := newTestLRU(0, 0)
+	mustSet(t, lru, "a", "1", 0)
+
+	value, _, _ := lru.Get(context.Background(), "a")
+	value[0] = '2'
+
+	if value, _, _ := lru.Get(context.Background(), "a"); string(value) != "1" {
+		t.Fatalf("a = %q after caller changed the returned slice", value)
+	}
+}
+
+func TestLRUClose(t *testing.T) {
+	ctx := context.Background()
+	lru, _ := newTestLRU(0, 0)
+	mustSet(t, lru, "a", "1", 0)
+
+	if errClose := lru.Close(); errClose != nil {
+		t.Fatalf("close: %v", errClose)
+	}
+
+	if lru.Len() != 0 {
+		t.Fatalf("len = %d after close", lru.Len())
+	}
+
+	if _, _, errGet := lru.Get(ctx, "a"); !errors.Is(errGet, ErrClosed) {
+		t.Fatalf("get = %v, want ErrClosed", errGet)
+	}
+
+	if errSet := lru.Set(ctx, "a", nil, 0); !errors.Is(errSet, ErrClosed) {
+		t.Fatalf("set = %v, want ErrClosed", errSet)
+	}
+
+	if _, errSetNX := lru.SetNX(ctx, "a", nil, 0); !errors.Is(errSetNX, ErrClosed) {
+		t.Fatalf("setnx = %v, want ErrClosed", errSetNX)
+	}
+
+	if errDelete := lru.Delete(ctx, "a"); !errors.Is(errDelete, ErrClosed) {
+		t.Fatalf("delete = %v, want ErrClosed", errDelete)
+	}
+}
+
+func TestLRUCompareAndDelete(t *testing.T) {
+	ctx := context.Background()
+	lru, now := newTestLRU(0, 0)
+	mustSet(t, lru, "a", "owner-1", time.Second)
+
+	if deleted, _ := lru.CompareAndDelete(ctx, "a", []byte("owner-2")); deleted || !has(lru, "a") {
+		t.Fatal("deleted a key with another value")
+	}
+
+	if deleted, _ := lru.CompareAndDelete(ctx, "a", []byte("owner-1")); !deleted || has(lru, "a") {
+		t.Fatal("key with the same value not deleted")
+	}
+
+	if deleted, _ := lru.CompareAndDelete(ctx, "a", []byte("owner-1")); deleted {
+		t.Fatal("deleted a missing key")
+	}
+
+	mustSet(t, lru, "b", "owner-1", time.Second)
+	*now = now.Add(time.Second)
+
+	if deleted, _ := lru.CompareAndDelete(ctx, "b", []byte("owner-1")); deleted {
+		t.Fatal("deleted an expired key")
+	}
+}
@@ -0,0 +1,313 @@
+package cashstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ Cache = (*Redis)(nil)
+
+var errUnexpectedReply = errors.New("unexpected redis reply")
+
+const (
+	defaultRedisPoolSize    = 8
+	defaultRedisDialTimeout = time.Second
+	defaultRedisIOTimeout   = time.Second
+)
+
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix добавляется ко всем ключам, чтобы несколько сервисов могли жить в одной базе Redis.
+	Prefix string
+	// PoolSize сколько простаивающих соединений держать открытыми.
+	PoolSize     int
+	DialTimeout  time.Duration
+	IOTimeout    time.Duration
+	MaxValueSize int
+}
+
+// Redis кэш, общий для всех экземпляров сервиса. Протокол RESP2 реализован здесь же, без внешних
+// зависимостей: нужны только GET, SET с PX/NX, DEL, EVAL, AUTH, SELECT и PING.
+type Redis struct {
+	options RedisOptions
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func NewRedis(options RedisOptions) *Redis {
+	if options.PoolSize <= 0 {
+		options.PoolSize = defaultRedisPoolSize
+	}
+
+	if options.DialTimeout <= 0 {
+		options.DialTimeout = defaultRedisDialTimeout
+	}
+
+	if options.IOTimeout <= 0 {
+		options.IOTimeout = defaultRedisIOTimeout
+	}
+
+	return &Redis{options: options}
+}
+
+func (r *Redis) Cache(key string) {
+	_ = r.Set(context.Background(), key, nil, 0)
+}
+
+func (r *Redis) Check(key string) bool {
+	_, ok, _ := r.Get(context.Background(), key)
+
+	return ok
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, errDo := r.Do(ctx, []byte("GET"), r.key(key))
+	if errDo != nil {
+		return nil, false, errDo
+	}
+
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: GET %T", errUnexpectedReply, reply)
+	}
+
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, errSet := r.set(ctx, key, value, ttl, false)
+
+	return errSet
+}
+
+func (r *Redis) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return r.set(ctx, key, value, ttl, true)
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	args := make([][]byte, 0, len(keys)+1)
+	args = append(args, []byte("DEL"))
+
+	for _, key := range keys {
+		args = append(args, r.key(key))
+	}
+
+	_, errDo := r.Do(ctx, args...)
+
+	return errDo
+}
+
+// CompareAndDeleteScript Lua-скрипт CompareAndDelete: проверка и удаление выполняются в Redis
+// одной командой, между ними никто не успеет перезаписать ключ.
+const CompareAndDeleteScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+func (r *Redis) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	reply, errDo := r.Do(ctx, []byte("EVAL"), []byte(CompareAndDeleteScript), []byte("1"), r.key(key), value)
+	if errDo != nil {
+		return false, errDo
+	}
+
+	deleted, ok := reply.(int64)
+	if !ok {
+		return false, fmt.Errorf("%w: EVAL %T", errUnexpectedReply, reply)
+	}
+
+	return deleted == 1, nil
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	_, errDo := r.Do(ctx, []byte("PING"))
+
+	return errDo
+}
+
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	for _, conn := range r.idle {
+		conn.conn.Close()
+	}
+
+	r.idle = nil
+
+	return nil
+}
+
+func (r *Redis) set(ctx context.Context, key string, value []byte, ttl time.Duration, onlyNew bool) (bool, error) {
+	if r.options.MaxValueSize > 0 && len(value) > r.options.MaxValueSize {
+		return false, ErrValueTooLarge
+	}
+
+	args := [][]byte{[]byte("SET"), r.key(key), value}
+
+	if ttl > 0 {
+		millis := ttl.Milliseconds()
+		if millis == 0 {
+			millis = 1
+		}
+
+		args = append(args, []byte("PX"), []byte(strconv.FormatInt(millis, 10)))
+	}
+
+	if onlyNew {
+		args = append(args, []byte("NX"))
+	}
+
+	reply, errDo := r.Do(ctx, args...)
+	if errDo != nil {
+		return false, errDo
+	}
+
+	// SET NX отвечает nil, если ключ уже есть
+	return reply != nil, nil
+}
+
+func (r *Redis) key(key string) []byte {
+	return []byte(r.options.Prefix + key)
+}
+
+// Do отправляет команду и читает ответ. Ответ-ошибка сервера возвращается как RedisError,
+// соединение при этом остаётся в пуле: протокол не нарушен.
+func (r *Redis) Do(ctx context.Context, args ...[]byte) (interface{}, error) {
+	conn, errGetConn := r.getConn(ctx)
+	if errGetConn != nil {
+		return nil, errGetConn
+	}
+
+	reply, errRoundTrip := r.roundTrip(ctx, conn, args...)
+	if errRoundTrip != nil {
+		conn.conn.Close()
+
+		return nil, errRoundTrip
+	}
+
+	r.putConn(conn)
+
+	if redisError, ok := reply.(RedisError); ok {
+		return nil, redisError
+	}
+
+	return reply, nil
+}
+
+func (r *Redis) roundTrip(ctx context.Context, conn *redisConn, args ...[]byte) (interface{}, error) {
+	deadline := time.Now().Add(r.options.IOTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if errSetDeadline := conn.conn.SetDeadline(deadline); errSetDeadline != nil {
+		return nil, errSetDeadline //nolint:wrapcheck // intentional
+	}
+
+	if errWriteCommand := WriteCommand(conn.writer, args...); errWriteCommand != nil {
+		return nil, fmt.Errorf("redis write: %w", errWriteCommand)
+	}
+
+	reply, errReadReply := ReadReply(conn.reader)
+	if errReadReply != nil {
+		return nil, fmt.Errorf("redis read: %w", errReadReply)
+	}
+
+	return reply, nil
+}
+
+func (r *Redis) getConn(ctx context.Context) (*redisConn, error) {
+	r.mu.Lock()
+
+	if r.closed {
+		r.mu.Unlock()
+
+		return nil, ErrClosed
+	}
+
+	if n := len(r.idle); n > 0 {
+		conn := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+
+		return conn, nil
+	}
+
+	r.mu.Unlock()
+
+	return r.dial(ctx)
+}
+
+func (r *Redis) putConn(conn *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || len(r.idle) >= r.options.PoolSize {
+		conn.conn.Close()
+
+		return
+	}
+
+	r.idle = append(r.idle, conn)
+}
+
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: r.options.DialTimeout}
+
+	netConn, errDial := dialer.DialContext(ctx, "tcp", r.options.Addr)
+	if errDial != nil {
+		return nil, fmt.Errorf("redis dial: %w", errDial)
+	}
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}
+
+	var setup [][][]byte
+
+	if r.options.Password != "" {
+		setup = append(setup, [][]byte{[]byte("AUTH"), []byte(r.options.Password)})
+	}
+
+	if r.options.DB != 0 {
+		setup = append(setup, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(r.options.DB))})
+	}
+
+	for _, command := range setup {
+		reply, errRoundTrip := r.roundTrip(ctx, conn, command...)
+		if errRoundTrip == nil {
+			if redisError, ok := reply.(RedisError); ok {
+				errRoundTrip = redisError
+			}
+		}
+
+		if errRoundTrip != nil {
+			netConn.Close()
+
+			return nil, fmt.Errorf("redis %s: %w", command[0], errRoundTrip)
+		}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,224 @@
+package cashstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore/fakeredis"
+)
+
+const redisPassword = "secret"
+
+func startRedis(t *testing.T) *fakeredis.Server {
+	t.Helper()
+
+	server, errStart := fakeredis.Start("127.0.0.1:0", redisPassword)
+	if errStart != nil {
+		t.Fatalf("start fakeredis: %v", errStart)
+	}
+
+	t.Cleanup(func() { _ = server.Close() })
+
+	return server
+}
+
+func newRedis(t *testing.T, server *fakeredis.Server, options cashstore.RedisOptions) *cashstore.Redis {
+	t.Helper()
+
+	options.Addr = server.Addr()
+	options.Password = redisPassword
+	redis := cashstore.NewRedis(options)
+
+	t.Cleanup(func() { _ = redis.Close() })
+
+	return redis
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	redis := newRedis(t, startRedis(t), cashstore.RedisOptions{})
+
+	if errPing := redis.Ping(ctx); errPing != nil {
+		t.Fatalf("ping: %v", errPing)
+	}
+
+	if _, ok, errGet := redis.Get(ctx, "a"); errGet != nil || ok {
+		t.Fatalf("get missing = %v, %v", ok, errGet)
+	}
+
+	if errSet := redis.Set(ctx, "a", []byte("1"), 0); errSet != nil {
+		t.Fatalf("set: %v", errSet)
+	}
+
+	if value, ok, errGet := redis.Get(ctx, "a"); errGet != nil || !ok || string(value) != "1" {
+		t.Fatalf("get a = %q, %v, %v", value, ok, errGet)
+	}
+
+	if written, errSetNX := redis.SetNX(ctx, "a", []byte("2"), 0); errSetNX != nil || written {
+		t.Fatalf("setnx existing = %v, %v", written, errSetNX)
+	}
+
+	if written, errSetNX := redis.SetNX(ctx, "b", []byte("2"), 0); errSetNX != nil || !written {
+		t.Fatalf("setnx new = %v, %v", written, errSetNX)
+	}
+
+	if errDelete := redis.Delete(ctx, "a", "b", "missing"); errDelete != nil {
+		t.Fatalf("delete: %v", errDelete)
+	}
+
+	if redis.Check("a") || redis.Check("b") {
+		t.Fatal("keys left after delete")
+	}
+
+	// пустое значение тоже значение
+	redis.Cache("empty")
+
+	if !redis.Check("empty") {
+		t.Fatal("cached key without value missing")
+	}
+}
+
+func TestRedisCompareAndDelete(t *testing.T) {
+	ctx := context.Background()
+	redis := newRedis(t, startRedis(t), cashstore.RedisOptions{Prefix: "svc:"})
+
+	if errSet := redis.Set(ctx, "a", []byte("owner-1"), 0); errSet != nil {
+		t.Fatalf("set: %v", errSet)
+	}
+
+	if deleted, errCompareAndDelete := redis.CompareAndDelete(ctx, "a", []byte("owner-2")); errCompareAndDelete != nil ||
+		deleted || !redis.Check("a") {
+		t.Fatalf("compare and delete another value = %v, %v", deleted, errCompareAndDelete)
+	}
+
+	if deleted, errCompareAndDelete := redis.CompareAndDelete(ctx, "a", []byte("owner-1")); errCompareAndDelete != nil ||
+		!deleted || redis.Check("a") {
+		t.Fatalf("compare and delete same value = %v, %v", deleted, errCompareAndDelete)
+	}
+
+	if deleted, errCompareAndDelete := redis.CompareAndDelete(ctx, "a", []byte("owner-1")); errCompareAndDelete != nil ||
+		deleted {
+		t.Fatalf("compare and delete missing key = %v, %v", deleted, errCompareAndDelete)
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	ctx := context.Background()
+	server := startRedis(t)
+	redis := newRedis(t, server, cashstore.RedisOptions{})
+
+	now := time.Now()
+	server.SetNow(func() time.Time { return now })
+
+	if errSet := redis.Set(ctx, "a", []byte("1"), time.Second); errSet != nil {
+		t.Fatalf("set: %v", errSet)
+	}
+
+	// ttl меньше миллисекунды не превращается в ключ без срока жизни
+	if errSet := redis.Set(ctx, "tiny", []byte("1"), time.Microsecond); errSet != nil {
+		t.Fatalf("set tiny: %v", errSet)
+	}
+
+	now = now.Add(time.Millisecond)
+
+	if redis.Check("tiny") {
+		t.Fatal("tiny ttl not applied")
+	}
+
+	if !redis.Check("a") {
+		t.Fatal("a expired before ttl")
+	}
+
+	now = now.Add(time.Second)
+
+	if redis.Check("a") {
+		t.Fatal("a not expired after ttl")
+	}
+
+	if written, _ := redis.SetNX(ctx, "a", []byte("2"), 0); !written {
+		t.Fatal("setnx on expired key not written")
+	}
+}
+
+func TestRedisPrefixAndDB(t *testing.T) {
+	ctx := context.Background()
+	server := startRedis(t)
+	prefixed := newRedis(t, server, cashstore.RedisOptions{Prefix: "svc:", DB: 1})
+	raw := newRedis(t, server, cashstore.RedisOptions{DB: 1})
+	otherDB := newRedis(t, server, cashstore.RedisOptions{Prefix: "svc:"})
+
+	if errSet := prefixed.Set(ctx, "a", []byte("1"), 0); errSet != nil {
+		t.Fatalf("set: %v", errSet)
+	}
+
+	if !raw.Check("svc:a") || raw.Check("a") {
+		t.Fatal("prefix not applied to the key")
+	}
+
+	if otherDB.Check("a") {
+		t.Fatal("key visible from another db")
+	}
+}
+
+func TestRedisErrors(t *testing.T) {
+	ctx := context.Background()
+	server := startRedis(t)
+
+	wrongPassword := cashstore.NewRedis(cashstore.RedisOptions{Addr: server.Addr(), Password: "wrong"})
+	defer wrongPassword.Close()
+
+	var redisError cashstore.RedisError
+	if errPing := wrongPassword.Ping(ctx); !errors.As(errPing, &redisError) {
+		t.Fatalf("ping with wrong password = %v, want RedisError", errPing)
+	}
+
+	limited := newRedis(t, server, cashstore.RedisOptions{MaxValueSize: 2})
+	if errSet := limited.Set(ctx, "a", []byte("123"), 0); !errors.Is(errSet, cashstore.ErrValueTooLarge) {
+		t.Fatalf("set too large = %v, want ErrValueTooLarge", errSet)
+	}
+
+	// ответ-ошибка не ломает соединение в пуле
+	if _, errDo := limited.Do(ctx, []byte("NOSUCHCOMMAND")); !errors.As(errDo, &redisError) {
+		t.Fatalf("unknown command = %v, want RedisError", errDo)
+	}
+
+	if errPing := limited.Ping(ctx); errPing != nil {
+		t.Fatalf("ping after error reply: %v", errPing)
+	}
+
+	if errClose := limited.Close(); errClose != nil {
+		t.Fatalf("close: %v", errClose)
+	}
+
+	if errPing := limited.Ping(ctx); !errors.Is(errPing, cashstore.ErrClosed) {
+		t.Fatalf("ping after close = %v, want ErrClosed", errPing)
+	}
+}
+
+func TestOpenRedis(t *testing.T) {
+	ctx := context.Background()
+	server := startRedis(t)
+
+	cache, errOpen := cashstore.Open("redis://:"+redisPassword+"@"+server.Addr()+"/2?prefix=svc:&pool=2", cashstore.Options{})
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+
+	defer cache.Close()
+
+	if errSet := cache.Set(ctx, "a", []byte("1"), 0); errSet != nil {
+		t.Fatalf("set: %v", errSet)
+	}
+
+	raw := newRedis(t, server, cashstore.RedisOptions{DB: 2})
+	if !raw.Check("svc:a") {
+		t.Fatal("db or prefix from url not applied")
+	}
+
+	if _, errOpen := cashstore.Open("memcached://localhost", cashstore.Options{}); !errors.Is(errOpen, cashstore.ErrUnknownScheme) {
+		t.Fatalf("open unknown scheme = %v, want ErrUnknownScheme", errOpen)
+	}
+}
@@ -0,0 +1,203 @@
+package cashstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var _ Cache = (*Remote)(nil)
+
+var ErrRemoteStatus = errors.New("unexpected remote cache response status")
+
+const (
+	remoteGet              = "get"
+	remoteSet              = "set"
+	remoteSetNX            = "setnx"
+	remoteDelete           = "delete"
+	remoteCompareAndDelete = "compare-and-delete"
+)
+
+type remoteRequest struct {
+	Op    string        `json:"op"`
+	Keys  []string      `json:"keys"`
+	Value []byte        `json:"value,omitempty"`
+	TTL   time.Duration `json:"ttl,omitempty"`
+}
+
+type remoteResponse struct {
+	Value []byte `json:"value,omitempty"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Remote кэш другого процесса через его Handler. Нужен, когда кэш живёт в памяти сервиса (memory://),
+// а сбросить его должен отдельный процесс, например CLI после изменения в базе.
+type Remote struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+// NewRemote url адрес Handler, header добавляется к каждому запросу, например для авторизации.
+func NewRemote(url string, client *http.Client, header http.Header) *Remote {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Remote{url: url, client: client, header: header}
+}
+
+func (r *Remote) Cache(key string) {
+	_ = r.Set(context.Background(), key, nil, 0)
+}
+
+func (r *Remote) Check(key string) bool {
+	_, ok, _ := r.Get(context.Background(), key)
+
+	return ok
+}
+
+func (r *Remote) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, errDo := r.do(ctx, remoteRequest{Op: remoteGet, Keys: []string{key}})
+	if errDo != nil {
+		return nil, false, errDo
+	}
+
+	return resp.Value, resp.OK, nil
+}
+
+func (r *Remote) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, errDo := r.do(ctx, remoteRequest{Op: remoteSet, Keys: []string{key}, Value: value, TTL: ttl})
+
+	return errDo
+}
+
+func (r *Remote) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	resp, errDo := r.do(ctx, remoteRequest{Op: remoteSetNX, Keys: []string{key}, Value: value, TTL: ttl})
+	if errDo != nil {
+		return false, errDo
+	}
+
+	return resp.OK, nil
+}
+
+func (r *Remote) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, errDo := r.do(ctx, remoteRequest{Op: remoteDelete, Keys: keys})
+
+	return errDo
+}
+
+func (r *Remote) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	resp, errDo := r.do(ctx, remoteRequest{Op: remoteCompareAndDelete, Keys: []string{key}, Value: value})
+	if errDo != nil {
+		return false, errDo
+	}
+
+	return resp.OK, nil
+}
+
+// Close кэш принадлежит другому процессу и не закрывается.
+func (r *Remote) Close() error {
+	return nil
+}
+
+func (r *Remote) do(ctx context.Context, in remoteRequest) (*remoteResponse, error) {
+	body, errMarshal := json.Marshal(in)
+	if errMarshal != nil {
+		return nil, errMarshal //nolint:wrapcheck // intentional
+	}
+
+	req, errNewRequest := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if errNewRequest != nil {
+		return nil, fmt.Errorf("new request: %w", errNewRequest)
+	}
+
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, errDo := r.client.Do(req)
+	if errDo != nil {
+		return nil, fmt.Errorf("remote cache: %w", errDo)
+	}
+
+	defer httpResp.Body.Close()
+
+	var resp remoteResponse
+	if errDecode := json.NewDecoder(httpResp.Body).Decode(&resp); errDecode != nil {
+		return nil, fmt.Errorf("%w: %d", ErrRemoteStatus, httpResp.StatusCode)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d %s", ErrRemoteStatus, httpResp.StatusCode, resp.Error)
+	}
+
+	return &resp, nil
+}
+
+// Handler отдаёт cache для Remote. Вешается только на внутренний листенер: запросы меняют кэш сервиса.
+func Handler(cache Cache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_ = json.NewEncoder(w).Encode(remoteResponse{Error: "method not allowed"})
+
+			return
+		}
+
+		var in remoteRequest
+		if errDecode := json.NewDecoder(req.Body).Decode(&in); errDecode != nil || len(in.Keys) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(remoteResponse{Error: "bad request"})
+
+			return
+		}
+
+		var (
+			resp    remoteResponse
+			errExec error
+		)
+
+		switch in.Op {
+		case remoteGet:
+			resp.Value, resp.OK, errExec = cache.Get(req.Context(), in.Keys[0])
+		case remoteSet:
+			errExec = cache.Set(req.Context(), in.Keys[0], in.Value, in.TTL)
+			resp.OK = errExec == nil
+		case remoteSetNX:
+			resp.OK, errExec = cache.SetNX(req.Context(), in.Keys[0], in.Value, in.TTL)
+		case remoteDelete:
+			errExec = cache.Delete(req.Context(), in.Keys...)
+			resp.OK = errExec == nil
+		case remoteCompareAndDelete:
+			resp.OK, errExec = cache.CompareAndDelete(req.Context(), in.Keys[0], in.Value)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(remoteResponse{Error: "unknown op " + in.Op})
+
+			return
+		}
+
+		if errExec != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(remoteResponse{Error: errExec.Error()})
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,72 @@
+package cashstore_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+)
+
+func TestRemote(t *testing.T) {
+	ctx := context.Background()
+	lru := cashstore.NewLRU(0, 0)
+
+	server := httptest.NewServer(cashstore.Handler(lru))
+	defer server.Close()
+
+	remote := cashstore.NewRemote(server.URL, server.Client(), nil)
+
+	if errSet := remote.Set(ctx, "a", []byte("1"), time.Minute); errSet != nil {
+		t.Fatalf("set: %v", errSet)
+	}
+
+	// запись видна в кэше сервиса
+	if value, ok, _ := lru.Get(ctx, "a"); !ok || string(value) != "1" {
+		t.Fatalf("lru a = %q, %v", value, ok)
+	}
+
+	if value, ok, errGet := remote.Get(ctx, "a"); errGet != nil || !ok || string(value) != "1" {
+		t.Fatalf("get a = %q, %v, %v", value, ok, errGet)
+	}
+
+	if _, ok, errGet := remote.Get(ctx, "missing"); errGet != nil || ok {
+		t.Fatalf("get missing = %v, %v", ok, errGet)
+	}
+
+	if written, errSetNX := remote.SetNX(ctx, "a", []byte("2"), 0); errSetNX != nil || written {
+		t.Fatalf("setnx existing = %v, %v", written, errSetNX)
+	}
+
+	if written, errSetNX := remote.SetNX(ctx, "b", []byte("2"), 0); errSetNX != nil || !written {
+		t.Fatalf("setnx new = %v, %v", written, errSetNX)
+	}
+
+	if deleted, errCompareAndDelete := remote.CompareAndDelete(ctx, "b", []byte("1")); errCompareAndDelete != nil ||
+		deleted {
+		t.Fatalf("compare and delete another value = %v, %v", deleted, errCompareAndDelete)
+	}
+
+	if errDelete := remote.Delete(ctx, "a", "b"); errDelete != nil {
+		t.Fatalf("delete: %v", errDelete)
+	}
+
+	if lru.Len() != 0 {
+		t.Fatalf("lru len = %d after delete", lru.Len())
+	}
+}
+
+func TestRemoteUnavailable(t *testing.T) {
+	server := httptest.NewServer(cashstore.Handler(cashstore.NewLRU(0, 0)))
+	remote := cashstore.NewRemote(server.URL, server.Client(), nil)
+	server.Close()
+
+	if errDelete := remote.Delete(context.Background(), "a"); errDelete == nil {
+		t.Fatal("delete on a stopped server succeeded")
+	}
+
+	if remote.Check("a") {
+		t.Fatal("check on a stopped server = true")
+	}
+}
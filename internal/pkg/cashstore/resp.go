@@ -0,0 +1,121 @@
+package cashstore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrProtocol = errors.New("redis protocol error")
+
+// RedisError ответ сервера с ошибкой, например WRONGTYPE или NOAUTH.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// WriteCommand команда RESP: массив bulk-строк.
+func WriteCommand(w *bufio.Writer, args ...[]byte) error {
+	if _, errWrite := fmt.Fprintf(w, "*%d\r\n", len(args)); errWrite != nil {
+		return errWrite //nolint:wrapcheck // intentional
+	}
+
+	for _, arg := range args {
+		if _, errWrite := fmt.Fprintf(w, "$%d\r\n", len(arg)); errWrite != nil {
+			return errWrite //nolint:wrapcheck // intentional
+		}
+
+		if _, errWrite := w.Write(arg); errWrite != nil {
+			return errWrite //nolint:wrapcheck // intentional
+		}
+
+		if _, errWrite := w.WriteString("\r\n"); errWrite != nil {
+			return errWrite //nolint:wrapcheck // intentional
+		}
+	}
+
+	return w.Flush() //nolint:wrapcheck // intentional
+}
+
+// ReadReply разбирает один ответ RESP2. Простая строка и bulk-строка возвращаются как []byte,
+// nil bulk-строка как nil, число как int64, массив как []interface{}, ошибка сервера как RedisError.
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, errReadLine := readLine(r)
+	if errReadLine != nil {
+		return nil, errReadLine
+	}
+
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%w: empty line", ErrProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return RedisError(line[1:]), nil
+	case ':':
+		value, errParseInt := strconv.ParseInt(string(line[1:]), 10, 64)
+		if errParseInt != nil {
+			return nil, fmt.Errorf("%w: integer %q", ErrProtocol, line[1:])
+		}
+
+		return value, nil
+	case '$':
+		size, errAtoi := strconv.Atoi(string(line[1:]))
+		if errAtoi != nil || size < -1 {
+			return nil, fmt.Errorf("%w: bulk length %q", ErrProtocol, line[1:])
+		}
+
+		if size == -1 {
+			return nil, nil
+		}
+
+		buf := make([]byte, size+2) //nolint:gomnd // завершающий \r\n
+		if _, errReadFull := io.ReadFull(r, buf); errReadFull != nil {
+			return nil, errReadFull //nolint:wrapcheck // intentional
+		}
+
+		return buf[:size], nil
+	case '*':
+		count, errAtoi := strconv.Atoi(string(line[1:]))
+		if errAtoi != nil || count < -1 {
+			return nil, fmt.Errorf("%w: array length %q", ErrProtocol, line[1:])
+		}
+
+		if count == -1 {
+			return nil, nil
+		}
+
+		items := make([]interface{}, 0, count)
+
+		for i := 0; i < count; i++ {
+			item, errReadReply := ReadReply(r)
+			if errReadReply != nil {
+				return nil, errReadReply
+			}
+
+			items = append(items, item)
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", ErrProtocol, line[0])
+	}
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, errReadSlice := r.ReadSlice('\n')
+	if errReadSlice != nil {
+		return nil, errReadSlice //nolint:wrapcheck // intentional
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%w: line without CRLF", ErrProtocol)
+	}
+
+	return append([]byte(nil), line[:len(line)-2]...), nil
+}
@@ -0,0 +1,216 @@
+// Package config настройки сервиса и команд: значения по умолчанию, поверх них JSON файл,
+// поверх файла переменные окружения.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var errBadDuration = errors.New("duration must be a string like \"1m30s\"")
+
+// Duration time.Duration, в JSON записывается строкой: "30s", "24h".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String()) //nolint:wrapcheck // intentional
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if errUnmarshal := json.Unmarshal(data, &value); errUnmarshal != nil {
+		return errBadDuration
+	}
+
+	parsed, errParseDuration := time.ParseDuration(value)
+	if errParseDuration != nil {
+		return fmt.Errorf("%w: %q", errBadDuration, value)
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+type Config struct {
+	Server   Server   `json:"server"`
+	TLS      TLS      `json:"tls"`
+	Database Database `json:"database"`
+	Tracing  Tracing  `json:"tracing"`
+	Logging  Logging  `json:"logging"`
+	Cache    Cache    `json:"cache"`
+	Policies Policies `json:"policies"`
+	Audit    Audit    `json:"audit"`
+	Outbox   Outbox   `json:"outbox"`
+	Ledger   Ledger   `json:"ledger"`
+}
+
+type Server struct {
+	// Addr публичное JSON-RPC API, за nginx.
+	Addr string `json:"addr"`
+	// AdminAddr административное API и /metrics, наружу не публикуется.
+	AdminAddr string `json:"admin_addr"`
+	// AdminToken общий секрет листенера админки: без него /admin/ отвечает 401.
+	AdminToken        string   `json:"admin_token"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	// HealthTimeout на все проверки /readyz вместе.
+	HealthTimeout Duration `json:"health_timeout"`
+	// DrainDelay сколько /readyz отдаёт draining до остановки приёма соединений.
+	DrainDelay Duration `json:"drain_delay"`
+	// ShutdownTimeout сколько ждать незавершённые запросы, дальше их контекст отменяется.
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	MaxBatchSize    int      `json:"max_batch_size"`
+}
+
+// TLS для публичного порта. Без сертификата порт слушает http, TLS завершает nginx.
+type TLS struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// ClientCAFile если задан, клиент обязан предъявить сертификат, подписанный этим CA.
+	ClientCAFile string `json:"client_ca_file"`
+	// MinVersion "1.2" или "1.3".
+	MinVersion string `json:"min_version"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+type Database struct {
+	// URL строка подключения lib/pq, пустая - параметры берутся из PG* переменных окружения.
+	URL string `json:"url"`
+	// MaxOpenConns 0 - без ограничения.
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time"`
+}
+
+// Tracing клиент jaeger. Переменные JAEGER_*, которых здесь нет, клиент по-прежнему читает сам.
+type Tracing struct {
+	Disabled    bool   `json:"disabled"`
+	ServiceName string `json:"service_name"`
+	// Endpoint коллектор по http, без него span'ы уходят агенту по udp.
+	Endpoint  string `json:"endpoint"`
+	User      string `json:"user"`
+	Password  string `json:"password"`
+	AgentHost string `json:"agent_host"`
+	AgentPort int    `json:"agent_port"`
+	// SamplerType const, probabilistic, ratelimiting или remote, пустой - remote.
+	SamplerType  string  `json:"sampler_type"`
+	SamplerParam float64 `json:"sampler_param"`
+}
+
+type Logging struct {
+	// Level debug, info, warn или error.
+	Level string `json:"level"`
+	// Format json или console.
+	Format string `json:"format"`
+}
+
+type Cache struct {
+	// URL memory:// или redis://[:password@]host:port[/db][?prefix=...], пустой - кэш выключен.
+	URL        string   `json:"url"`
+	TTL        Duration `json:"ttl"`
+	MaxEntries int      `json:"max_entries"`
+}
+
+// Policies бизнес-правила, которые не задаются банковской группой оператора.
+type Policies struct {
+	// StartingBalance стартовый баланс игроков операторов без банковской группы.
+	StartingBalance int `json:"starting_balance"`
+	// IdempotencyTTL сколько помнить выполненный transactionRef.
+	IdempotencyTTL Duration `json:"idempotency_ttl"`
+	// IdempotencyWait сколько ждать выполняющийся дубль, 0 - дубль сразу получает ошибку.
+	IdempotencyWait Duration `json:"idempotency_wait"`
+}
+
+type Audit struct {
+	// RetentionDays сколько дней хранить журнал вызовов, 0 - записи не удаляются.
+	RetentionDays int `json:"retention_days"`
+	// Redact поля параметров и ответов, которые не попадают в журнал.
+	Redact []string `json:"redact"`
+}
+
+// Outbox куда relay отправляет события, без обоих полей relay не запускается.
+type Outbox struct {
+	WebhookURL string `json:"webhook_url"`
+	// File путь к файлу, stdout - вывод в консоль.
+	File string `json:"file"`
+}
+
+type Ledger struct {
+	// CheckpointKey ключ подписи снимков цепочки платежей, без него снимки не снимаются.
+	CheckpointKey string `json:"checkpoint_key"`
+}
+
+// Default значения, с которыми сервис работал до появления конфигурации.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8086",
+			AdminAddr:         ":8087",
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			HealthTimeout:     Duration(2 * time.Second),
+			DrainDelay:        Duration(5 * time.Second),
+			ShutdownTimeout:   Duration(20 * time.Second),
+			MaxBatchSize:      20, //nolint:gomnd // rpcbatch.DefaultMaxBatchSize
+		},
+		TLS: TLS{MinVersion: "1.2"},
+		Database: Database{
+			MaxIdleConns: 2, //nolint:gomnd // как в database/sql по умолчанию
+		},
+		Tracing: Tracing{ServiceName: "SeamlessV2ServiceServer"},
+		Logging: Logging{Level: "info", Format: "json"},
+		Cache:   Cache{TTL: Duration(30 * time.Second)},
+		Policies: Policies{
+			StartingBalance: 10000, //nolint:gomnd // seamlessv2.DefaultStartingBalance
+			IdempotencyTTL:  Duration(24 * time.Hour),
+		},
+	}
+}
+
+// Load значения по умолчанию, поверх них файл path (пустой - без файла), поверх файла окружение.
+// Результат проверяется Validate.
+func Load(path string) (Config, error) {
+	config, errRead := Read(path)
+	if errRead != nil {
+		return Config{}, errRead
+	}
+
+	if errValidate := config.Validate(); errValidate != nil {
+		return Config{}, errValidate
+	}
+
+	return config, nil
+}
+
+// Read как Load, но без проверки: команда config check показывает и неверную конфигурацию.
+func Read(path string) (Config, error) {
+	config := Default()
+
+	if path != "" {
+		raw, errReadFile := os.ReadFile(path)
+		if errReadFile != nil {
+			return Config{}, fmt.Errorf("read config: %w", errReadFile)
+		}
+
+		// неизвестное поле скорее всего опечатка, молча её пропускать нельзя
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+
+		if errDecode := decoder.Decode(&config); errDecode != nil {
+			return Config{}, fmt.Errorf("parse config %s: %w", path, errDecode)
+		}
+	}
+
+	if errApplyEnv := applyEnv(&config, os.LookupEnv); errApplyEnv != nil {
+		return Config{}, errApplyEnv
+	}
+
+	return config, nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envVar переменная окружения, которая переопределяет поле конфигурации.
+type envVar struct {
+	name string
+	set  func(value string) error
+}
+
+// envVars переменные, которые сервис читал до появления конфигурации, сохранили свои имена.
+func envVars(c *Config) []envVar {
+	return []envVar{
+		{"SERVER_ADDR", setString(&c.Server.Addr)},
+		{"ADMIN_ADDR", setString(&c.Server.AdminAddr)},
+		{"ADMIN_TOKEN", setString(&c.Server.AdminToken)},
+		{"SERVER_READ_HEADER_TIMEOUT", setDuration(&c.Server.ReadHeaderTimeout)},
+		{"HEALTH_TIMEOUT", setDuration(&c.Server.HealthTimeout)},
+		{"SHUTDOWN_DRAIN_DELAY", setDuration(&c.Server.DrainDelay)},
+		{"SHUTDOWN_TIMEOUT", setDuration(&c.Server.ShutdownTimeout)},
+		{"RPC_MAX_BATCH_SIZE", setInt(&c.Server.MaxBatchSize)},
+
+		{"TLS_CERT_FILE", setString(&c.TLS.CertFile)},
+		{"TLS_KEY_FILE", setString(&c.TLS.KeyFile)},
+		{"TLS_CLIENT_CA_FILE", setString(&c.TLS.ClientCAFile)},
+		{"TLS_MIN_VERSION", setString(&c.TLS.MinVersion)},
+
+		{"CONNECTION_STRING", setString(&c.Database.URL)},
+		{"DB_MAX_OPEN_CONNS", setInt(&c.Database.MaxOpenConns)},
+		{"DB_MAX_IDLE_CONNS", setInt(&c.Database.MaxIdleConns)},
+		{"DB_CONN_MAX_LIFETIME", setDuration(&c.Database.ConnMaxLifetime)},
+		{"DB_CONN_MAX_IDLE_TIME", setDuration(&c.Database.ConnMaxIdleTime)},
+
+		{"JAEGER_DISABLED", setBool(&c.Tracing.Disabled)},
+		{"JAEGER_SERVICE_NAME", setString(&c.Tracing.ServiceName)},
+		{"JAEGER_ENDPOINT", setString(&c.Tracing.Endpoint)},
+		{"JAEGER_USER", setString(&c.Tracing.User)},
+		{"JAEGER_PASSWORD", setString(&c.Tracing.Password)},
+		{"JAEGER_AGENT_HOST", setString(&c.Tracing.AgentHost)},
+		{"JAEGER_AGENT_PORT", setInt(&c.Tracing.AgentPort)},
+		{"JAEGER_SAMPLER_TYPE", setString(&c.Tracing.SamplerType)},
+		{"JAEGER_SAMPLER_PARAM", setFloat(&c.Tracing.SamplerParam)},
+
+		{"LOG_LEVEL", setString(&c.Logging.Level)},
+		{"LOG_FORMAT", setString(&c.Logging.Format)},
+
+		{"CACHE_URL", setString(&c.Cache.URL)},
+		{"CACHE_TTL", setDuration(&c.Cache.TTL)},
+		{"CACHE_MAX_ENTRIES", setInt(&c.Cache.MaxEntries)},
+
+		{"STARTING_BALANCE", setInt(&c.Policies.StartingBalance)},
+		{"IDEMPOTENCY_TTL", setDuration(&c.Policies.IdempotencyTTL)},
+		{"IDEMPOTENCY_WAIT", setDuration(&c.Policies.IdempotencyWait)},
+
+		{"RPC_AUDIT_RETENTION_DAYS", setInt(&c.Audit.RetentionDays)},
+		{"RPC_AUDIT_REDACT", setList(&c.Audit.Redact)},
+
+		{"OUTBOX_WEBHOOK_URL", setString(&c.Outbox.WebhookURL)},
+		{"OUTBOX_FILE", setString(&c.Outbox.File)},
+
+		{"LEDGER_CHECKPOINT_KEY", setString(&c.Ledger.CheckpointKey)},
+	}
+}
+
+// applyEnv пустая переменная считается незаданной, как и раньше при чтении через os.Getenv.
+func applyEnv(c *Config, lookup func(name string) (string, bool)) error {
+	for _, variable := range envVars(c) {
+		value, ok := lookup(variable.name)
+		if !ok || value == "" {
+			continue
+		}
+
+		if errSet := variable.set(value); errSet != nil {
+			return fmt.Errorf("parse %s=%q: %w", variable.name, value, errSet)
+		}
+	}
+
+	return nil
+}
+
+func setString(field *string) func(string) error {
+	return func(value string) error {
+		*field = value
+
+		return nil
+	}
+}
+
+func setInt(field *int) func(string) error {
+	return func(value string) error {
+		parsed, errAtoi := strconv.Atoi(value)
+		if errAtoi != nil {
+			return errAtoi //nolint:wrapcheck // intentional
+		}
+
+		*field = parsed
+
+		return nil
+	}
+}
+
+func setFloat(field *float64) func(string) error {
+	return func(value string) error {
+		parsed, errParseFloat := strconv.ParseFloat(value, 64)
+		if errParseFloat != nil {
+			return errParseFloat //nolint:wrapcheck // intentional
+		}
+
+		*field = parsed
+
+		return nil
+	}
+}
+
+func setBool(field *bool) func(string) error {
+	return func(value string) error {
+		parsed, errParseBool := strconv.ParseBool(value)
+		if errParseBool != nil {
+			return errParseBool //nolint:wrapcheck // intentional
+		}
+
+		*field = parsed
+
+		return nil
+	}
+}
+
+func setDuration(field *Duration) func(string) error {
+	return func(value string) error {
+		parsed, errParseDuration := time.ParseDuration(value)
+		if errParseDuration != nil {
+			return errParseDuration //nolint:wrapcheck // intentional
+		}
+
+		*field = Duration(parsed)
+
+		return nil
+	}
+}
+
+// setList значения через запятую, пустые пропускаются.
+func setList(field *[]string) func(string) error {
+	return func(value string) error {
+		var items []string
+
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		*field = items
+
+		return nil
+	}
+}
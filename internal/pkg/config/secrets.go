@@ -0,0 +1,54 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// mask тот же заполнитель, что подставляет url.URL.Redacted.
+const mask = "xxxxx"
+
+// dsnPassword пароль в строке подключения вида "host=... password=...".
+var dsnPassword = regexp.MustCompile(`(password=)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Masked копия для вывода: пароли и ключи заменены на xxxxx, в адресах скрыт только пароль,
+// чтобы по выводу было видно, куда сервис подключается.
+func (c Config) Masked() Config {
+	c.Server.AdminToken = maskValue(c.Server.AdminToken)
+	c.Database.URL = maskURL(c.Database.URL)
+	c.Tracing.Endpoint = maskURL(c.Tracing.Endpoint)
+	c.Tracing.Password = maskValue(c.Tracing.Password)
+	c.Cache.URL = maskURL(c.Cache.URL)
+	c.Outbox.WebhookURL = maskURL(c.Outbox.WebhookURL)
+	c.Ledger.CheckpointKey = maskValue(c.Ledger.CheckpointKey)
+
+	return c
+}
+
+func maskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return mask
+}
+
+func maskURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	parsed, errParse := url.Parse(rawURL)
+	if errParse != nil || parsed.Scheme == "" || parsed.Opaque != "" {
+		return dsnPassword.ReplaceAllString(rawURL, "${1}"+mask)
+	}
+
+	// lib/pq принимает пароль и параметром: postgres://host/db?password=...
+	query := parsed.Query()
+	if query.Has("password") {
+		query.Set("password", mask)
+		parsed.RawQuery = query.Encode()
+	}
+
+	return parsed.Redacted()
+}
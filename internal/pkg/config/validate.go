@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var ErrInvalid = errors.New("invalid config")
+
+const maxPort = 65535
+
+// Validate проверяет все поля сразу и перечисляет все ошибки, чтобы их не приходилось исправлять по одной.
+func (c Config) Validate() error {
+	var problems []string
+
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is required")
+	check(c.Server.AdminAddr != "", "server.admin_addr is required")
+	check(c.Server.Addr != c.Server.AdminAddr, "server.addr and server.admin_addr must differ")
+	check(c.Server.AdminToken != "", "server.admin_token is required")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.HealthTimeout > 0, "server.health_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.MaxBatchSize > 0, "server.max_batch_size must be positive")
+
+	check(c.TLS.CertFile == "" || c.TLS.KeyFile != "", "tls.key_file is required with tls.cert_file")
+	check(c.TLS.KeyFile == "" || c.TLS.CertFile != "", "tls.cert_file is required with tls.key_file")
+	check(c.TLS.ClientCAFile == "" || c.TLS.Enabled(), "tls.client_ca_file requires tls.cert_file")
+	check(c.TLS.MinVersion == "1.2" || c.TLS.MinVersion == "1.3", "tls.min_version must be 1.2 or 1.3")
+
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(
+		c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns must not exceed database.max_open_conns",
+	)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time must not be negative")
+
+	check(c.Tracing.Disabled || c.Tracing.ServiceName != "", "tracing.service_name is required")
+	check(c.Tracing.Endpoint == "" || isURL(c.Tracing.Endpoint, "http", "https"), "tracing.endpoint must be an http(s) url")
+	check((c.Tracing.User == "") == (c.Tracing.Password == ""), "tracing.user and tracing.password go together")
+	check(c.Tracing.AgentPort >= 0 && c.Tracing.AgentPort <= maxPort, "tracing.agent_port is out of range")
+	check(
+		oneOf(c.Tracing.SamplerType, "", "const", "probabilistic", "ratelimiting", "remote"),
+		"tracing.sampler_type must be const, probabilistic, ratelimiting or remote",
+	)
+	check(
+		c.Tracing.SamplerType != "probabilistic" || (c.Tracing.SamplerParam >= 0 && c.Tracing.SamplerParam <= 1),
+		"tracing.sampler_param must be in [0, 1] for the probabilistic sampler",
+	)
+
+	_, errParseLevel := zapcore.ParseLevel(c.Logging.Level)
+	check(errParseLevel == nil, "logging.level must be debug, info, warn or error")
+	check(oneOf(c.Logging.Format, "json", "console"), "logging.format must be json or console")
+
+	check(c.Cache.URL == "" || isURL(c.Cache.URL, "memory", "redis"), "cache.url must be a memory:// or redis:// url")
+	check(c.Cache.TTL > 0, "cache.ttl must be positive")
+	check(c.Cache.MaxEntries >= 0, "cache.max_entries must not be negative")
+
+	check(c.Policies.StartingBalance >= 0, "policies.starting_balance must not be negative")
+	check(c.Policies.IdempotencyTTL > 0, "policies.idempotency_ttl must be positive")
+	check(c.Policies.IdempotencyWait >= 0, "policies.idempotency_wait must not be negative")
+
+	check(c.Audit.RetentionDays >= 0, "audit.retention_days must not be negative")
+
+	check(c.Outbox.WebhookURL == "" || isURL(c.Outbox.WebhookURL, "http", "https"), "outbox.webhook_url must be an http(s) url")
+
+	if len(problems) != 0 {
+		return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func isURL(rawURL string, schemes ...string) bool {
+	parsed, errParse := url.Parse(rawURL)
+
+	return errParse == nil && oneOf(parsed.Scheme, schemes...)
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, item := range allowed {
+		if value == item {
+			return true
+		}
+	}
+
+	return false
+}
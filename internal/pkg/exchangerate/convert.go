@@ -0,0 +1,74 @@
+// Package exchangerate курсы валют: импорт из CSV/JSON и пересчёт сумм в минимальных единицах валют.
+package exchangerate
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrInvalidRate = errors.New("exchange rate must be a positive decimal")
+
+// ParseRate курс в десятичной записи, как он хранится в numeric: "1.0842", "0.000123".
+func ParseRate(value string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(value)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRate, value)
+	}
+
+	return rate, nil
+}
+
+// Convert пересчитывает сумму в минимальных единицах валюты from в минимальные единицы валюты to.
+// rate - сколько единиц to стоит одна единица from, digits - знаков после запятой у валют.
+// Округление до ближайшего, половина - от нуля.
+func Convert(amount int, rate *big.Rat, fromDigits, toDigits int) int {
+	result := new(big.Rat).Mul(big.NewRat(int64(amount), 1), rate)
+	result.Mul(result, pow10(toDigits-fromDigits))
+
+	return roundHalfAwayFromZero(result)
+}
+
+// ConvertDown как Convert, но дробная часть отбрасывается: округление к нулю.
+func ConvertDown(amount int, rate *big.Rat, fromDigits, toDigits int) int {
+	result := new(big.Rat).Mul(big.NewRat(int64(amount), 1), rate)
+	result.Mul(result, pow10(toDigits-fromDigits))
+
+	return int(new(big.Int).Quo(result.Num(), result.Denom()).Int64())
+}
+
+// Invert курс обратного направления.
+func Invert(rate *big.Rat) *big.Rat {
+	return new(big.Rat).Inv(rate)
+}
+
+func pow10(exp int) *big.Rat {
+	abs := exp
+	if abs < 0 {
+		abs = -abs
+	}
+
+	//nolint:gomnd // основание степени
+	value := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs)), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), value)
+	}
+
+	return new(big.Rat).SetInt(value)
+}
+
+func roundHalfAwayFromZero(value *big.Rat) int {
+	num := new(big.Int).Abs(value.Num())
+	denom := value.Denom()
+
+	quo, rem := new(big.Int).QuoRem(num, denom, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(denom) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+
+	if value.Sign() < 0 {
+		quo.Neg(quo)
+	}
+
+	return int(quo.Int64())
+}
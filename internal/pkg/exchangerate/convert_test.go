@@ -0,0 +1,43 @@
+package exchangerate_test
+
+import (
+	"testing"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/exchangerate"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		amount     int
+		rate       string
+		fromDigits int
+		toDigits   int
+		nearest    int
+		down       int
+	}{
+		{name: "exact", amount: 1000, rate: "1.5", fromDigits: 2, toDigits: 2, nearest: 1500, down: 1500},
+		{name: "below half", amount: 101, rate: "1.004", fromDigits: 2, toDigits: 2, nearest: 101, down: 101},
+		{name: "half", amount: 5, rate: "0.5", fromDigits: 2, toDigits: 2, nearest: 3, down: 2},
+		{name: "above half", amount: 199, rate: "163.25", fromDigits: 2, toDigits: 0, nearest: 325, down: 324},
+		{name: "more digits", amount: 3, rate: "1.2345", fromDigits: 0, toDigits: 3, nearest: 3704, down: 3703},
+		{name: "negative half", amount: -5, rate: "0.5", fromDigits: 2, toDigits: 2, nearest: -3, down: -2},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			rate, errParseRate := exchangerate.ParseRate(tc.rate)
+			if errParseRate != nil {
+				t.Fatal(errParseRate)
+			}
+
+			if got := exchangerate.Convert(tc.amount, rate, tc.fromDigits, tc.toDigits); got != tc.nearest {
+				t.Errorf("Convert = %d, want %d", got, tc.nearest)
+			}
+
+			if got := exchangerate.ConvertDown(tc.amount, rate, tc.fromDigits, tc.toDigits); got != tc.down {
+				t.Errorf("ConvertDown = %d, want %d", got, tc.down)
+			}
+		})
+	}
+}
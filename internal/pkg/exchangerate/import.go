@@ -0,0 +1,64 @@
+package exchangerate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// Import записывает курсы одной транзакцией: либо файл загружен целиком, либо ничего.
+func Import(ctx context.Context, db *sqlx.DB, rows []Row, source string) (int, error) {
+	tx, errBeginTxx := db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return 0, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	currencyIDs := make(map[string]int)
+
+	currencyID := func(code string) (int, error) {
+		if id, ok := currencyIDs[code]; ok {
+			return id, nil
+		}
+
+		currency, errGetCurrencyByCode := repo.GetCurrencyByCode(ctx, tx, code)
+		if errGetCurrencyByCode != nil {
+			return 0, fmt.Errorf("currency %s: %w", code, errGetCurrencyByCode)
+		}
+
+		currencyIDs[code] = currency.ID
+
+		return currency.ID, nil
+	}
+
+	for _, row := range rows {
+		fromID, errFrom := currencyID(row.From)
+		if errFrom != nil {
+			return 0, errFrom
+		}
+
+		toID, errTo := currencyID(row.To)
+		if errTo != nil {
+			return 0, errTo
+		}
+
+		if _, errUpsertExchangeRate := repo.UpsertExchangeRate(ctx, tx, repo.ExchangeRate{
+			FromCurrencyID: fromID,
+			ToCurrencyID:   toID,
+			Rate:           row.Rate,
+			EffectiveAt:    row.EffectiveAt,
+			Source:         source,
+		}); errUpsertExchangeRate != nil {
+			return 0, errUpsertExchangeRate //nolint:wrapcheck // intentional
+		}
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return 0, errCommit //nolint:wrapcheck // intentional
+	}
+
+	return len(rows), nil
+}
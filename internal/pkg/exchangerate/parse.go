@@ -0,0 +1,138 @@
+package exchangerate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRow       = errors.New("invalid exchange rate row")
+	ErrUnknownFormat    = errors.New("unknown exchange rate format, expected csv or json")
+	ErrSameCurrency     = errors.New("exchange rate between the same currency")
+	ErrInvalidEffective = errors.New("effective time must be RFC 3339 or YYYY-MM-DD")
+)
+
+// Row курс из файла импорта: одна единица From стоит Rate единиц To начиная с EffectiveAt.
+type Row struct {
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Rate        string    `json:"rate"`
+	EffectiveAt time.Time `json:"effectiveAt"`
+}
+
+// jsonRow effectiveAt в JSON допускает те же форматы, что и в CSV.
+type jsonRow struct {
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	Rate        json.Number `json:"rate"`
+	EffectiveAt string      `json:"effectiveAt"`
+}
+
+// Parse format csv или json.
+//
+// CSV: from,to,rate,effective_at, строка заголовка необязательна.
+// JSON: массив объектов {"from":"EUR","to":"SLE","rate":"24.61","effectiveAt":"2022-09-10"}.
+func Parse(format string, r io.Reader) ([]Row, error) {
+	switch format {
+	case "csv":
+		return ParseCSV(r)
+	case "json":
+		return ParseJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func ParseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	records, errReadAll := reader.ReadAll()
+	if errReadAll != nil {
+		return nil, fmt.Errorf("read csv: %w", errReadAll)
+	}
+
+	if len(records) > 0 && strings.EqualFold(records[0][0], "from") {
+		records = records[1:]
+	}
+
+	rows := make([]Row, 0, len(records))
+
+	for i, record := range records {
+		row, errNewRow := newRow(record[0], record[1], record[2], record[3])
+		if errNewRow != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, errNewRow)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func ParseJSON(r io.Reader) ([]Row, error) {
+	var records []jsonRow
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	if errDecode := decoder.Decode(&records); errDecode != nil {
+		return nil, fmt.Errorf("decode json: %w", errDecode)
+	}
+
+	rows := make([]Row, 0, len(records))
+
+	for i, record := range records {
+		row, errNewRow := newRow(record.From, record.To, record.Rate.String(), record.EffectiveAt)
+		if errNewRow != nil {
+			return nil, fmt.Errorf("item %d: %w", i, errNewRow)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func newRow(from, to, rate, effectiveAt string) (Row, error) {
+	from = strings.ToUpper(strings.TrimSpace(from))
+	to = strings.ToUpper(strings.TrimSpace(to))
+	rate = strings.TrimSpace(rate)
+
+	if from == "" || to == "" {
+		return Row{}, fmt.Errorf("%w: currency is required", ErrInvalidRow)
+	}
+
+	if from == to {
+		return Row{}, fmt.Errorf("%w: %s", ErrSameCurrency, from)
+	}
+
+	if _, errParseRate := ParseRate(rate); errParseRate != nil {
+		return Row{}, errParseRate
+	}
+
+	effective, errParseEffective := parseEffective(strings.TrimSpace(effectiveAt))
+	if errParseEffective != nil {
+		return Row{}, errParseEffective
+	}
+
+	return Row{From: from, To: to, Rate: rate, EffectiveAt: effective}, nil
+}
+
+func parseEffective(value string) (time.Time, error) {
+	if effective, errParse := time.Parse(time.RFC3339, value); errParse == nil {
+		return effective.UTC(), nil
+	}
+
+	if effective, errParse := time.Parse("2006-01-02", value); errParse == nil {
+		return effective, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidEffective, value)
+}
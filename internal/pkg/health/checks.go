@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/migrate"
+)
+
+var ErrMigrationsPending = errors.New("database schema is older than the binary")
+
+// Database база отвечает на ping.
+func Database(db *sqlx.DB) Check {
+	return func(ctx context.Context) (string, error) {
+		return "", db.PingContext(ctx) //nolint:wrapcheck // intentional
+	}
+}
+
+// Migrations в базе применены все миграции бинаря. База новее бинаря не мешает: так бывает,
+// пока при выкатке старые экземпляры ещё работают рядом с новыми.
+func Migrations(db *sqlx.DB, expected string) Check {
+	return func(ctx context.Context) (string, error) {
+		version, errCurrentVersion := migrate.CurrentVersion(ctx, db)
+		if errCurrentVersion != nil {
+			return "", errCurrentVersion //nolint:wrapcheck // intentional
+		}
+
+		if version < expected {
+			return version, fmt.Errorf("%w: %q, expected %q", ErrMigrationsPending, version, expected)
+		}
+
+		return version, nil
+	}
+}
+
+// Cache кэш отвечает на чтение. Без кэша сервис работает, но медленнее, поэтому его недоступность
+// тоже снимает готовность: пусть трафик уйдёт на экземпляры с рабочим кэшем.
+func Cache(cache cashstore.Cache) Check {
+	return func(ctx context.Context) (string, error) {
+		_, _, errGet := cache.Get(ctx, "health:ping")
+
+		return "", errGet //nolint:wrapcheck // intentional
+	}
+}
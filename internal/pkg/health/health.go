@@ -0,0 +1,174 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultTimeout = 2 * time.Second
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Check проверка компонента. detail попадает в ответ как есть, например текущая версия миграций.
+type Check func(ctx context.Context) (detail string, err error)
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health состояние сервиса для оркестратора: /healthz - процесс жив, /readyz - можно слать запросы.
+// В режиме drain готовность снимается, а запросы продолжают обслуживаться: балансировщик успевает
+// убрать экземпляр, прежде чем его остановят.
+type Health struct {
+	timeout  time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// New timeout на все проверки /readyz вместе, 0 - по умолчанию две секунды.
+func New(timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Health{timeout: timeout}
+}
+
+// Add проверки добавляются до запуска http сервера.
+func (h *Health) Add(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+func (h *Health) SetDraining(draining bool) {
+	h.draining.Store(draining)
+}
+
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+type Component struct {
+	Status    string  `json:"status"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Ready выполняет все проверки параллельно. Сервис готов, если все компоненты в порядке и он не в режиме drain.
+func (h *Health) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(h.checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, item := range h.checks {
+		wg.Add(1)
+
+		go func(item namedCheck) {
+			defer wg.Done()
+
+			started := time.Now()
+			detail, errCheck := item.check(ctx)
+
+			component := Component{
+				Status:    StatusOK,
+				Detail:    detail,
+				LatencyMS: float64(time.Since(started).Microseconds()) / 1000, //nolint:gomnd // мкс в мс
+			}
+
+			if errCheck != nil {
+				component.Status = StatusUnavailable
+				component.Error = errCheck.Error()
+			}
+
+			mu.Lock()
+			report.Components[item.name] = component
+			mu.Unlock()
+		}(item)
+	}
+
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if component.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	if h.Draining() {
+		report.Status = StatusDraining
+	}
+
+	return report
+}
+
+// LiveHandler /healthz: отвечает, пока процесс может обслуживать http, внешние зависимости не проверяет,
+// иначе оркестратор перезапускал бы сервис при каждой недоступности базы.
+func (h *Health) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, Report{Status: StatusOK})
+	})
+}
+
+// ReadyHandler /readyz: 200 если готов, 503 если какой-то компонент недоступен или включён drain.
+func (h *Health) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, h.Ready(r.Context()))
+	})
+}
+
+// DrainHandler POST включает drain, DELETE выключает, GET показывает текущий режим.
+func (h *Health) DrainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.SetDraining(true)
+		case http.MethodDelete:
+			h.SetDraining(false)
+		case http.MethodGet:
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		status := StatusOK
+		if h.Draining() {
+			status = StatusDraining
+		}
+
+		// режим переключён успешно, поэтому 200 и при включённом drain
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Report{Status: status})
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(report)
+}
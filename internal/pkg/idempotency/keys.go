@@ -0,0 +1,227 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+)
+
+var (
+	ErrInFlight  = errors.New("request with the same idempotency key is in progress")
+	ErrCompleted = errors.New("request with the same idempotency key is already completed")
+	// ErrMismatch ключ уже занят запросом с другими параметрами: это не повтор, а переиспользованный ключ.
+	ErrMismatch = errors.New("idempotency key is reused with different request parameters")
+)
+
+const (
+	defaultTTL          = 24 * time.Hour
+	defaultLeaseTTL     = 30 * time.Second
+	defaultPollInterval = 20 * time.Millisecond
+
+	keyPrefix = "idempotency:"
+)
+
+type state string
+
+const (
+	stateInFlight  state = "in_flight"
+	stateCompleted state = "completed"
+)
+
+type record struct {
+	State       state           `json:"state"`
+	Owner       string          `json:"owner,omitempty"`
+	Fingerprint string          `json:"fingerprint,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Response    json.RawMessage `json:"response,omitempty"`
+}
+
+// CompletedError такой же запрос уже выполнен, Response - сохранённый ответ оригинала, чтобы отдать
+// его повтору. errors.Is(err, ErrCompleted) для неё true.
+type CompletedError struct {
+	Response json.RawMessage
+}
+
+func (e *CompletedError) Error() string {
+	return ErrCompleted.Error()
+}
+
+func (e *CompletedError) Is(target error) bool {
+	return target == ErrCompleted
+}
+
+type Options struct {
+	// TTL сколько хранится итог выполненного запроса, дальше дубль ловит только база.
+	TTL time.Duration
+	// LeaseTTL сколько живёт захват, если владелец упал и не успел его снять.
+	LeaseTTL time.Duration
+	// Wait сколько ждать завершения параллельного дубля, 0 - сразу ErrInFlight.
+	Wait         time.Duration
+	PollInterval time.Duration
+}
+
+// Keys захват ключей идемпотентности в общем кэше. Кэш не источник истины: при его ошибке запрос
+// выполняется без захвата, а дубль отсеивает проверка в базе.
+type Keys struct {
+	cache   cashstore.Cache
+	options Options
+	logger  *zap.Logger
+}
+
+// New nil в cache - захват выключен, Claim всегда отдаёт пустой захват.
+func New(cache cashstore.Cache, options Options, logger *zap.Logger) *Keys {
+	if options.TTL <= 0 {
+		options.TTL = defaultTTL
+	}
+
+	if options.LeaseTTL <= 0 {
+		options.LeaseTTL = defaultLeaseTTL
+	}
+
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultPollInterval
+	}
+
+	return &Keys{cache: cache, options: options, logger: logger}
+}
+
+// Claim захватывает ключ до начала работы. fingerprint - отпечаток параметров запроса: ответ отдаётся
+// только повтору с тем же отпечатком, запрос с другим получает ErrMismatch. Если такой же запрос уже
+// выполнен, возвращает *CompletedError с его ответом, если выполняется - ждёт его до Options.Wait
+// и возвращает *CompletedError или ErrInFlight.
+// Захват нужно завершить через Complete после фиксации результата или снять через Release при ошибке.
+func (k *Keys) Claim(ctx context.Context, key, fingerprint string) (*Claim, error) {
+	if k == nil || k.cache == nil {
+		return &Claim{}, nil
+	}
+
+	inFlight, errMarshal := json.Marshal(record{State: stateInFlight, Owner: uuid.NewString(), Fingerprint: fingerprint})
+	if errMarshal != nil {
+		return nil, errMarshal //nolint:wrapcheck // intentional
+	}
+
+	claim := &Claim{keys: k, key: keyPrefix + key, fingerprint: fingerprint, inFlight: inFlight}
+
+	deadline := time.Now().Add(k.options.Wait)
+
+	for {
+		claimed, errSetNX := k.cache.SetNX(ctx, claim.key, inFlight, k.options.LeaseTTL)
+		if errSetNX != nil {
+			return k.unclaimed(claim.key, errSetNX), nil
+		}
+
+		if claimed {
+			claim.held = true
+
+			return claim, nil
+		}
+
+		raw, found, errGet := k.cache.Get(ctx, claim.key)
+		if errGet != nil {
+			return k.unclaimed(claim.key, errGet), nil
+		}
+
+		// ключ успели снять или он истёк - пробуем захватить снова
+		if !found {
+			continue
+		}
+
+		var current record
+		if errUnmarshal := json.Unmarshal(raw, &current); errUnmarshal != nil {
+			return k.unclaimed(claim.key, errUnmarshal), nil
+		}
+
+		if current.Fingerprint != fingerprint {
+			return nil, ErrMismatch
+		}
+
+		if current.State == stateCompleted {
+			return nil, &CompletedError{Response: current.Response}
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, ErrInFlight
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err() //nolint:wrapcheck // intentional
+		case <-time.After(k.options.PollInterval):
+		}
+	}
+}
+
+func (k *Keys) unclaimed(key string, errCache error) *Claim {
+	k.logger.Warn("idempotency cache unavailable, relying on database", zap.String("key", key), zap.Error(errCache))
+
+	return &Claim{}
+}
+
+// Claim захваченный ключ. Пустой захват (кэш выключен или недоступен) ничего не делает.
+type Claim struct {
+	keys        *Keys
+	key         string
+	fingerprint string
+	// inFlight значение захвата с уникальным владельцем, Release снимает ключ, только пока оно не изменилось
+	inFlight []byte
+	held     bool
+}
+
+// Complete сохраняет итог вместе с ответом на Options.TTL, повтор получит этот же ответ. Вызывается
+// после фиксации транзакции, когда контекст запроса может быть уже отменён, поэтому использует свой.
+func (c *Claim) Complete(response interface{}) {
+	if !c.held {
+		return
+	}
+
+	c.held = false
+
+	now := time.Now().UTC()
+
+	rawResponse, errMarshalResponse := json.Marshal(response)
+	if errMarshalResponse != nil {
+		c.keys.logger.Warn("could not marshal idempotency response", zap.String("key", c.key), zap.Error(errMarshalResponse))
+
+		// без ответа повтор получит ошибку дубля, как при проверке в базе
+		rawResponse = nil
+	}
+
+	completed, errMarshal := json.Marshal(record{
+		State:       stateCompleted,
+		Fingerprint: c.fingerprint,
+		CompletedAt: &now,
+		Response:    rawResponse,
+	})
+	if errMarshal != nil {
+		return
+	}
+
+	if errSet := c.keys.cache.Set(context.Background(), c.key, completed, c.keys.options.TTL); errSet != nil {
+		c.keys.logger.Warn("could not store idempotency outcome", zap.String("key", c.key), zap.Error(errSet))
+	}
+}
+
+// Release снимает захват, если запрос не выполнен: повтор с тем же ключом снова разрешён.
+// Чужой захват (свой истёк и ключ перехватили) не трогается.
+func (c *Claim) Release() {
+	if !c.held {
+		return
+	}
+
+	c.held = false
+
+	// проверка владельца и удаление одной операцией кэша: между ними чужой захват не появится
+	if _, errCompareAndDelete := c.keys.cache.CompareAndDelete(
+		context.Background(),
+		c.key,
+		c.inFlight,
+	); errCompareAndDelete != nil {
+		c.keys.logger.Warn("could not release idempotency key", zap.String("key", c.key), zap.Error(errCompareAndDelete))
+	}
+}
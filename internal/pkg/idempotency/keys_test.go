@@ -0,0 +1,167 @@
+package idempotency_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore/fakeredis"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/idempotency"
+)
+
+type response struct {
+	Balance int `json:"balance"`
+}
+
+func newKeys(options idempotency.Options) *idempotency.Keys {
+	return idempotency.New(cashstore.NewLRU(0, 0), options, zap.NewNop())
+}
+
+func mustClaim(t *testing.T, keys *idempotency.Keys, key string) *idempotency.Claim {
+	t.Helper()
+
+	claim, errClaim := keys.Claim(context.Background(), key, "")
+	if errClaim != nil {
+		t.Fatalf("claim %s: %v", key, errClaim)
+	}
+
+	return claim
+}
+
+func TestCompleteReplaysResponse(t *testing.T) {
+	keys := newKeys(idempotency.Options{})
+
+	mustClaim(t, keys, "a").Complete(response{Balance: 42})
+
+	_, errClaim := keys.Claim(context.Background(), "a", "")
+	if !errors.Is(errClaim, idempotency.ErrCompleted) {
+		t.Fatalf("claim completed = %v, want ErrCompleted", errClaim)
+	}
+
+	var completed *idempotency.CompletedError
+	if !errors.As(errClaim, &completed) {
+		t.Fatalf("claim completed = %T, want *CompletedError", errClaim)
+	}
+
+	var replayed response
+	if errUnmarshal := json.Unmarshal(completed.Response, &replayed); errUnmarshal != nil || replayed.Balance != 42 {
+		t.Fatalf("replayed = %+v, %v", replayed, errUnmarshal)
+	}
+}
+
+func TestClaimFingerprintMismatch(t *testing.T) {
+	keys := newKeys(idempotency.Options{})
+
+	claim, errClaim := keys.Claim(context.Background(), "a", "bet:100")
+	if errClaim != nil {
+		t.Fatalf("claim: %v", errClaim)
+	}
+
+	// пока оригинал выполняется, и после его завершения
+	if _, errClaim := keys.Claim(context.Background(), "a", "bet:200"); !errors.Is(errClaim, idempotency.ErrMismatch) {
+		t.Fatalf("claim in flight with other fingerprint = %v, want ErrMismatch", errClaim)
+	}
+
+	claim.Complete(response{Balance: 42})
+
+	if _, errClaim := keys.Claim(context.Background(), "a", "bet:200"); !errors.Is(errClaim, idempotency.ErrMismatch) {
+		t.Fatalf("claim completed with other fingerprint = %v, want ErrMismatch", errClaim)
+	}
+
+	if _, errClaim := keys.Claim(context.Background(), "a", "bet:100"); !errors.Is(errClaim, idempotency.ErrCompleted) {
+		t.Fatalf("claim completed with same fingerprint = %v, want ErrCompleted", errClaim)
+	}
+}
+
+func TestClaimInFlight(t *testing.T) {
+	keys := newKeys(idempotency.Options{})
+
+	mustClaim(t, keys, "a")
+
+	if _, errClaim := keys.Claim(context.Background(), "a", ""); !errors.Is(errClaim, idempotency.ErrInFlight) {
+		t.Fatalf("claim in flight = %v, want ErrInFlight", errClaim)
+	}
+
+	// другой ключ не занят
+	mustClaim(t, keys, "b")
+}
+
+func TestClaimWaitsForOriginal(t *testing.T) {
+	keys := newKeys(idempotency.Options{Wait: time.Second, PollInterval: time.Millisecond})
+	claim := mustClaim(t, keys, "a")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		claim.Complete(response{Balance: 7})
+	}()
+
+	var completed *idempotency.CompletedError
+	if _, errClaim := keys.Claim(context.Background(), "a", ""); !errors.As(errClaim, &completed) {
+		t.Fatalf("claim = %v, want *CompletedError after the original completed", errClaim)
+	}
+}
+
+func TestReleaseAllowsRetry(t *testing.T) {
+	keys := newKeys(idempotency.Options{})
+
+	released := mustClaim(t, keys, "a")
+	released.Release()
+
+	// после Release захват уже не держится, запоздалый Complete ничего не пишет
+	released.Complete(response{})
+
+	if _, errClaim := keys.Claim(context.Background(), "a", ""); errClaim != nil {
+		t.Fatalf("claim after release and late complete = %v", errClaim)
+	}
+}
+
+func TestReleaseKeepsForeignClaim(t *testing.T) {
+	keys := newKeys(idempotency.Options{LeaseTTL: 10 * time.Millisecond})
+
+	expired := mustClaim(t, keys, "a")
+	time.Sleep(20 * time.Millisecond)
+
+	// захват истёк и ключ перехватил повтор, запоздалый Release его не снимает
+	mustClaim(t, keys, "a")
+	expired.Release()
+
+	if _, errClaim := keys.Claim(context.Background(), "a", ""); !errors.Is(errClaim, idempotency.ErrInFlight) {
+		t.Fatalf("claim = %v, want ErrInFlight", errClaim)
+	}
+}
+
+// TestReleaseOnRedis Release на Redis идёт через EVAL скрипта сравнения и удаления.
+func TestReleaseOnRedis(t *testing.T) {
+	server, errStart := fakeredis.Start("127.0.0.1:0", "")
+	if errStart != nil {
+		t.Fatalf("start fakeredis: %v", errStart)
+	}
+	defer server.Close()
+
+	redis := cashstore.NewRedis(cashstore.RedisOptions{Addr: server.Addr()})
+	defer redis.Close()
+
+	keys := idempotency.New(redis, idempotency.Options{}, zap.NewNop())
+
+	mustClaim(t, keys, "a").Release()
+	mustClaim(t, keys, "a")
+
+	if _, errClaim := keys.Claim(context.Background(), "a", ""); !errors.Is(errClaim, idempotency.ErrInFlight) {
+		t.Fatalf("claim = %v, want ErrInFlight", errClaim)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	keys := idempotency.New(nil, idempotency.Options{}, zap.NewNop())
+
+	for i := 0; i < 2; i++ {
+		claim := mustClaim(t, keys, "a")
+		claim.Complete(response{})
+		claim.Release()
+	}
+}
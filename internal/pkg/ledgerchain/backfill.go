@@ -0,0 +1,57 @@
+package ledgerchain
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// Backfill сцепляет платежи, созданные до появления цепочки, и их откаты. Запускается один раз
+// сразу после миграции: платежи, дописанные позже, встанут в цепочку после новых звеньев.
+func Backfill(ctx context.Context, db *sqlx.DB) (int, error) {
+	var total int
+
+	for {
+		count, errBackfillBatch := backfillBatch(ctx, db)
+		if errBackfillBatch != nil {
+			return total, errBackfillBatch
+		}
+
+		if count == 0 {
+			return total, nil
+		}
+
+		total += count
+	}
+}
+
+func backfillBatch(ctx context.Context, db *sqlx.DB) (int, error) {
+	tx, errBeginTxx := db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return 0, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	payments, errGetUnchained := repo.GetUnchainedPayments(ctx, tx, batchSize)
+	if errGetUnchained != nil {
+		return 0, errGetUnchained //nolint:wrapcheck // intentional
+	}
+
+	for i := range payments {
+		if errAppend := repo.AppendLedgerLink(ctx, tx, repo.LedgerLinkPayment, &payments[i]); errAppend != nil {
+			return 0, errAppend //nolint:wrapcheck // intentional
+		}
+
+		if payments[i].RollBackAt == nil {
+			continue
+		}
+
+		if errAppend := repo.AppendLedgerLink(ctx, tx, repo.LedgerLinkRollback, &payments[i]); errAppend != nil {
+			return 0, errAppend //nolint:wrapcheck // intentional
+		}
+	}
+
+	return len(payments), tx.Commit() //nolint:wrapcheck // intentional
+}
@@ -0,0 +1,155 @@
+package ledgerchain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const (
+	defaultCheckpointInterval = time.Hour
+	// checkpointSettle звенья моложе этого в снимок не попадают, см. repo.GetSettledLedgerLinkID.
+	checkpointSettle = time.Minute
+)
+
+var ErrInvalidKey = errors.New("invalid ed25519 key")
+
+// ParsePrivateKey ключ подписи снимков: base64 от 32-байтного seed.
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	seed, errDecode := base64.StdEncoding.DecodeString(encoded)
+	if errDecode != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidKey
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParsePublicKey ключ проверки снимков в base64.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, errDecode := base64.StdEncoding.DecodeString(encoded)
+	if errDecode != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// RootHash sha256 от голов всех кошельков, отсортированных по (user_id, currency_id).
+func RootHash(heads []repo.LedgerLink) string {
+	sorted := make([]repo.LedgerLink, len(heads))
+	copy(sorted, heads)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].UserID != sorted[j].UserID {
+			return sorted[i].UserID < sorted[j].UserID
+		}
+
+		return sorted[i].CurrencyID < sorted[j].CurrencyID
+	})
+
+	h := sha256.New()
+
+	for _, head := range sorted {
+		fmt.Fprintf(h, "%d:%d:%s\n", head.UserID, head.CurrencyID, head.Hash)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checkpointMessage(lastLinkID int64, wallets int, rootHash string) []byte {
+	return []byte(fmt.Sprintf("ledger-checkpoint:v1:%d:%d:%s", lastLinkID, wallets, rootHash))
+}
+
+// VerifyCheckpoint проверяет подпись снимка, хэш здесь не пересчитывается.
+func VerifyCheckpoint(key ed25519.PublicKey, checkpoint *repo.LedgerCheckpoint) bool {
+	signature, errDecode := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if errDecode != nil {
+		return false
+	}
+
+	return ed25519.Verify(key, checkpointMessage(checkpoint.LastLinkID, checkpoint.Wallets, checkpoint.RootHash), signature)
+}
+
+// Checkpoint подписывает головы всех цепочек. Если с прошлого снимка звеньев не прибавилось, возвращает nil.
+func Checkpoint(ctx context.Context, db *sqlx.DB, key ed25519.PrivateKey) (*repo.LedgerCheckpoint, error) {
+	tx, errBeginTxx := db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	lastLinkID, errGetSettled := repo.GetSettledLedgerLinkID(ctx, tx, checkpointSettle)
+	if errGetSettled != nil {
+		return nil, errGetSettled //nolint:wrapcheck // intentional
+	}
+
+	last, errGetLast := repo.GetLastLedgerCheckpoint(ctx, tx)
+	if errGetLast != nil {
+		return nil, errGetLast //nolint:wrapcheck // intentional
+	}
+
+	if lastLinkID == 0 || (last != nil && last.LastLinkID >= lastLinkID) {
+		return nil, nil //nolint:nilnil // снимать нечего
+	}
+
+	heads, errGetHeads := repo.GetLedgerHeads(ctx, tx, lastLinkID)
+	if errGetHeads != nil {
+		return nil, errGetHeads //nolint:wrapcheck // intentional
+	}
+
+	checkpoint := &repo.LedgerCheckpoint{
+		LastLinkID: lastLinkID,
+		Wallets:    len(heads),
+		RootHash:   RootHash(heads),
+	}
+
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(
+		ed25519.Sign(key, checkpointMessage(checkpoint.LastLinkID, checkpoint.Wallets, checkpoint.RootHash)),
+	)
+
+	if errNewCheckpoint := repo.NewLedgerCheckpoint(ctx, tx, checkpoint); errNewCheckpoint != nil {
+		return nil, errNewCheckpoint //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	return checkpoint, nil
+}
+
+// RunCheckpointer раз в час снимает подписанный снимок до отмены ctx.
+func RunCheckpointer(ctx context.Context, db *sqlx.DB, key ed25519.PrivateKey, logger *zap.Logger) {
+	ticker := time.NewTicker(defaultCheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		if checkpoint, errCheckpoint := Checkpoint(ctx, db, key); errCheckpoint != nil {
+			logger.Error("ledger checkpoint failed", zap.Error(errCheckpoint))
+		} else if checkpoint != nil {
+			logger.Info(
+				"ledger checkpoint",
+				zap.Int64("last_link_id", checkpoint.LastLinkID),
+				zap.Int("wallets", checkpoint.Wallets),
+				zap.String("root_hash", checkpoint.RootHash),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package ledgerchain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const batchSize = 1000
+
+type ProblemKind string
+
+const (
+	// ProblemBrokenLink звено ссылается не на предыдущее звено кошелька.
+	ProblemBrokenLink ProblemKind = "broken_link"
+	// ProblemHashMismatch содержимое платежа или звена не совпадает с хэшем.
+	ProblemHashMismatch ProblemKind = "hash_mismatch"
+	// ProblemPaymentMissing звено ссылается на удалённый платёж.
+	ProblemPaymentMissing ProblemKind = "payment_missing"
+	// ProblemUnchainedPayment платёж без звена в цепочке.
+	ProblemUnchainedPayment ProblemKind = "unchained_payment"
+	// ProblemRollbackMismatch rollback_at платежа не совпадает с последним звеном отката.
+	ProblemRollbackMismatch ProblemKind = "rollback_mismatch"
+	// ProblemCheckpointMismatch головы цепочек не совпадают со снимком.
+	ProblemCheckpointMismatch ProblemKind = "checkpoint_mismatch"
+	// ProblemCheckpointSignature подпись снимка не проходит проверку.
+	ProblemCheckpointSignature ProblemKind = "checkpoint_signature"
+)
+
+type Problem struct {
+	Kind         ProblemKind `json:"kind"`
+	LinkID       int64       `json:"link_id,omitempty"`
+	PaymentID    int         `json:"payment_id,omitempty"`
+	UserID       int         `json:"user_id,omitempty"`
+	CurrencyID   int         `json:"currency_id,omitempty"`
+	CheckpointID int64       `json:"checkpoint_id,omitempty"`
+}
+
+type Report struct {
+	Links       int `json:"links"`
+	Wallets     int `json:"wallets"`
+	Checkpoints int `json:"checkpoints"`
+	// Problems по одной первой поломке на кошелёк в порядке звеньев, затем платежи и снимки.
+	Problems []Problem `json:"problems"`
+}
+
+type wallet struct {
+	userID, currencyID int
+}
+
+// Verify проходит все цепочки в порядке id звеньев. Для каждого кошелька фиксируется только первое
+// сломанное звено, дальше кошелёк не проверяется: все последующие хэши от него зависят.
+// Если key не nil, проверяются и подписи снимков.
+func Verify(ctx context.Context, db *sqlx.DB, key ed25519.PublicKey) (*Report, error) {
+	tx, errBeginTxx := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	checkpoints, errGetCheckpoints := repo.GetLedgerCheckpoints(ctx, tx)
+	if errGetCheckpoints != nil {
+		return nil, errGetCheckpoints //nolint:wrapcheck // intentional
+	}
+
+	report := &Report{Checkpoints: len(checkpoints)}
+	heads := make(map[wallet]repo.LedgerLink)
+	broken := make(map[wallet]bool)
+
+	// снимок сверяется, когда пройдены все звенья до его last_link_id
+	checkpointHeads := func(upToID int64) {
+		for len(checkpoints) != 0 && checkpoints[0].LastLinkID <= upToID {
+			report.Problems = append(report.Problems, checkCheckpoint(&checkpoints[0], heads, key)...)
+			checkpoints = checkpoints[1:]
+		}
+	}
+
+	var lastID int64
+
+	for {
+		links, errGetLinks := repo.GetLedgerLinksAfter(ctx, tx, lastID, batchSize)
+		if errGetLinks != nil {
+			return nil, errGetLinks //nolint:wrapcheck // intentional
+		}
+
+		if len(links) == 0 {
+			break
+		}
+
+		payments, errGetPayments := loadPayments(ctx, tx, links)
+		if errGetPayments != nil {
+			return nil, errGetPayments
+		}
+
+		for i := range links {
+			link := &links[i]
+			checkpointHeads(link.ID - 1)
+
+			walletKey := wallet{userID: link.UserID, currencyID: link.CurrencyID}
+			if !broken[walletKey] {
+				if problem := checkLink(link, heads[walletKey].Hash, payments[link.PaymentID]); problem != nil {
+					broken[walletKey] = true
+					report.Problems = append(report.Problems, *problem)
+				}
+			}
+
+			heads[walletKey] = *link
+			lastID = link.ID
+			report.Links++
+		}
+	}
+
+	checkpointHeads(lastID)
+
+	for _, checkpoint := range checkpoints {
+		// снимок ссылается на звенья, которых больше нет
+		report.Problems = append(report.Problems, Problem{
+			Kind:         ProblemCheckpointMismatch,
+			CheckpointID: checkpoint.ID,
+			LinkID:       checkpoint.LastLinkID,
+		})
+	}
+
+	report.Wallets = len(heads)
+
+	problems, errCheckPayments := checkPayments(ctx, tx)
+	if errCheckPayments != nil {
+		return nil, errCheckPayments
+	}
+
+	report.Problems = append(report.Problems, problems...)
+
+	return report, nil
+}
+
+func loadPayments(ctx context.Context, tx *sqlx.Tx, links []repo.LedgerLink) (map[int]*repo.Payment, error) {
+	ids := make([]int, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.PaymentID)
+	}
+
+	payments, errGetPayments := repo.GetPaymentsByIDs(ctx, tx, ids)
+	if errGetPayments != nil {
+		return nil, errGetPayments //nolint:wrapcheck // intentional
+	}
+
+	result := make(map[int]*repo.Payment, len(payments))
+	for i := range payments {
+		result[payments[i].ID] = &payments[i]
+	}
+
+	return result, nil
+}
+
+func checkLink(link *repo.LedgerLink, prevHash string, payment *repo.Payment) *Problem {
+	problem := &Problem{
+		LinkID:     link.ID,
+		PaymentID:  link.PaymentID,
+		UserID:     link.UserID,
+		CurrencyID: link.CurrencyID,
+	}
+
+	switch {
+	case link.PrevHash != prevHash:
+		problem.Kind = ProblemBrokenLink
+	case payment == nil:
+		problem.Kind = ProblemPaymentMissing
+	case payment.UserID != link.UserID || payment.CurrencyID != link.CurrencyID ||
+		repo.LedgerLinkHash(link, payment) != link.Hash:
+		problem.Kind = ProblemHashMismatch
+	default:
+		return nil
+	}
+
+	return problem
+}
+
+func checkCheckpoint(
+	checkpoint *repo.LedgerCheckpoint,
+	heads map[wallet]repo.LedgerLink,
+	key ed25519.PublicKey,
+) []Problem {
+	var problems []Problem
+
+	if key != nil && !VerifyCheckpoint(key, checkpoint) {
+		problems = append(problems, Problem{
+			Kind:         ProblemCheckpointSignature,
+			CheckpointID: checkpoint.ID,
+			LinkID:       checkpoint.LastLinkID,
+		})
+	}
+
+	links := make([]repo.LedgerLink, 0, len(heads))
+	for _, head := range heads {
+		links = append(links, head)
+	}
+
+	if len(links) != checkpoint.Wallets || RootHash(links) != checkpoint.RootHash {
+		problems = append(problems, Problem{
+			Kind:         ProblemCheckpointMismatch,
+			CheckpointID: checkpoint.ID,
+			LinkID:       checkpoint.LastLinkID,
+		})
+	}
+
+	return problems
+}
+
+func checkPayments(ctx context.Context, tx *sqlx.Tx) ([]Problem, error) {
+	var problems []Problem
+
+	unchained, errGetUnchained := repo.GetUnchainedPayments(ctx, tx, batchSize)
+	if errGetUnchained != nil {
+		return nil, errGetUnchained //nolint:wrapcheck // intentional
+	}
+
+	for _, payment := range unchained {
+		problems = append(problems, Problem{
+			Kind:       ProblemUnchainedPayment,
+			PaymentID:  payment.ID,
+			UserID:     payment.UserID,
+			CurrencyID: payment.CurrencyID,
+		})
+	}
+
+	mismatches, errGetMismatches := repo.GetRollbackMismatches(ctx, tx, batchSize)
+	if errGetMismatches != nil {
+		return nil, errGetMismatches //nolint:wrapcheck // intentional
+	}
+
+	for _, payment := range mismatches {
+		problems = append(problems, Problem{
+			Kind:       ProblemRollbackMismatch,
+			PaymentID:  payment.ID,
+			UserID:     payment.UserID,
+			CurrencyID: payment.CurrencyID,
+		})
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,238 @@
+package ledgerchain_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/ledgerchain"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var errUnexpectedQuery = errors.New("unexpected query")
+
+var (
+	linkColumns    = []string{"id", "created_at", "user_id", "currency_id", "payment_id", "kind", "rollback_at", "prev_hash", "hash"}
+	paymentColumns = []string{"id", "created_at", "user_id", "currency_id", "withdraw", "deposit", "transaction_ref"}
+)
+
+// chainDB отдаёт звенья и платежи на запросы Verify, снимков, платежей без звена и расхождений откатов нет.
+type chainDB struct {
+	links    []repo.LedgerLink
+	payments []repo.Payment
+}
+
+type chainConn struct {
+	db *chainDB
+}
+
+func (chainConn) Prepare(string) (driver.Stmt, error) { return nil, errUnexpectedQuery }
+func (chainConn) Close() error                        { return nil }
+func (chainConn) Begin() (driver.Tx, error)           { return chainTx{}, nil }
+
+// BeginTx Verify читает в repeatable read, фейку уровень изоляции безразличен.
+func (chainConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) { return chainTx{}, nil }
+
+func (c chainConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, "ledger_checkpoints"),
+		strings.Contains(query, "NOT EXISTS"),
+		strings.Contains(query, "IS DISTINCT FROM"):
+		return &chainRows{}, nil
+	case strings.Contains(query, "FROM billing.ledger_chain WHERE id > $1"):
+		afterID, _ := args[0].Value.(int64)
+		rows := &chainRows{columns: linkColumns}
+
+		for _, link := range c.db.links {
+			if link.ID > afterID {
+				rows.rows = append(rows.rows, []driver.Value{
+					link.ID, link.CreatedAt, int64(link.UserID), int64(link.CurrencyID), int64(link.PaymentID),
+					string(link.Kind), nil, link.PrevHash, link.Hash,
+				})
+			}
+		}
+
+		return rows, nil
+	case strings.Contains(query, "id = ANY($1)"):
+		rows := &chainRows{columns: paymentColumns}
+
+		for _, payment := range c.db.payments {
+			rows.rows = append(rows.rows, []driver.Value{
+				int64(payment.ID), *payment.CreatedAt, int64(payment.UserID), int64(payment.CurrencyID),
+				int64(payment.Withdraw), int64(payment.Deposit), payment.TransactionRef,
+			})
+		}
+
+		return rows, nil
+	}
+
+	return nil, errUnexpectedQuery
+}
+
+type chainTx struct{}
+
+func (chainTx) Commit() error   { return nil }
+func (chainTx) Rollback() error { return nil }
+
+type chainRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *chainRows) Columns() []string { return r.columns }
+func (r *chainRows) Close() error      { return nil }
+
+func (r *chainRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+var (
+	chains   = map[string]*chainDB{}
+	chainsMu sync.Mutex
+)
+
+type chainDriver struct{}
+
+func (chainDriver) Open(dsn string) (driver.Conn, error) {
+	chainsMu.Lock()
+	defer chainsMu.Unlock()
+
+	return chainConn{db: chains[dsn]}, nil
+}
+
+func init() {
+	sql.Register("ledgerchain-fake", chainDriver{})
+}
+
+// newChain три платежа одного кошелька и целая цепочка звеньев по ним.
+func newChain() *chainDB {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	chain := &chainDB{}
+
+	var prevHash string
+
+	for i := 1; i <= 3; i++ {
+		createdAt := created.Add(time.Duration(i) * time.Minute)
+		payment := repo.Payment{
+			ID:             i,
+			CreatedAt:      &createdAt,
+			UserID:         1,
+			CurrencyID:     1,
+			Withdraw:       100 * i,
+			TransactionRef: "tx-" + string(rune('0'+i)),
+		}
+
+		link := repo.LedgerLink{
+			ID:         int64(i),
+			CreatedAt:  createdAt,
+			UserID:     1,
+			CurrencyID: 1,
+			PaymentID:  i,
+			Kind:       repo.LedgerLinkPayment,
+			PrevHash:   prevHash,
+		}
+		link.Hash = repo.LedgerLinkHash(&link, &payment)
+		prevHash = link.Hash
+
+		chain.payments = append(chain.payments, payment)
+		chain.links = append(chain.links, link)
+	}
+
+	return chain
+}
+
+func TestVerify(t *testing.T) {
+	testCases := []struct {
+		name    string
+		tamper  func(chain *chainDB)
+		want    ledgerchain.ProblemKind
+		wantLnk int64
+	}{
+		{name: "intact", tamper: func(*chainDB) {}},
+		{
+			// сумма платежа изменена после того, как он попал в цепочку
+			name:    "tampered payment",
+			tamper:  func(chain *chainDB) { chain.payments[1].Withdraw = 1 },
+			want:    ledgerchain.ProblemHashMismatch,
+			wantLnk: 2,
+		},
+		{
+			// звенья 2 и 3 поменяли местами: второе по порядку ссылается не на первое
+			name: "reordered links",
+			tamper: func(chain *chainDB) {
+				chain.links[1], chain.links[2] = chain.links[2], chain.links[1]
+				chain.links[1].ID, chain.links[2].ID = 2, 3
+			},
+			want:    ledgerchain.ProblemBrokenLink,
+			wantLnk: 2,
+		},
+		{
+			// хэш звена пересчитан с подменённой ссылкой, сам по себе он сходится
+			name: "broken prev hash",
+			tamper: func(chain *chainDB) {
+				link := &chain.links[2]
+				link.PrevHash = strings.Repeat("0", 64)
+				link.Hash = repo.LedgerLinkHash(link, &chain.payments[2])
+			},
+			want:    ledgerchain.ProblemBrokenLink,
+			wantLnk: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newChain()
+			tc.tamper(chain)
+
+			chainsMu.Lock()
+			chains[t.Name()] = chain
+			chainsMu.Unlock()
+
+			db, errOpen := sqlx.Open("ledgerchain-fake", t.Name())
+			if errOpen != nil {
+				t.Fatalf("open: %v", errOpen)
+			}
+
+			defer db.Close()
+
+			report, errVerify := ledgerchain.Verify(context.Background(), db, nil)
+			if errVerify != nil {
+				t.Fatalf("verify: %v", errVerify)
+			}
+
+			if report.Links != 3 || report.Wallets != 1 {
+				t.Fatalf("report = %+v, want 3 links of 1 wallet", report)
+			}
+
+			if tc.want == "" {
+				if len(report.Problems) != 0 {
+					t.Fatalf("problems in intact chain: %+v", report.Problems)
+				}
+
+				return
+			}
+
+			// дальше первой поломки кошелёк не проверяется
+			if len(report.Problems) != 1 || report.Problems[0].Kind != tc.want || report.Problems[0].LinkID != tc.wantLnk {
+				t.Fatalf("problems = %+v, want %s at link %d", report.Problems, tc.want, tc.wantLnk)
+			}
+		})
+	}
+}
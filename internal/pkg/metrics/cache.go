@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+)
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "hits_total"),
+		"Wallet cache hits by kind of data.",
+		[]string{"kind"},
+		nil,
+	)
+	cacheMissesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "misses_total"),
+		"Wallet cache misses by kind of data, cache errors included.",
+		[]string{"kind"},
+		nil,
+	)
+	cacheErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "errors_total"),
+		"Wallet cache backend errors by kind of data.",
+		[]string{"kind"},
+		nil,
+	)
+	cacheHitRatioDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "hit_ratio"),
+		"Wallet cache hit ratio since start by kind of data.",
+		[]string{"kind"},
+		nil,
+	)
+)
+
+// CacheCollector отдаёт walletcache.Stats в Prometheus.
+type CacheCollector struct {
+	stats *walletcache.Stats
+}
+
+func NewCacheCollector(stats *walletcache.Stats) *CacheCollector {
+	return &CacheCollector{stats: stats}
+}
+
+func (c *CacheCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- cacheHitsDesc
+	descs <- cacheMissesDesc
+	descs <- cacheErrorsDesc
+	descs <- cacheHitRatioDesc
+}
+
+func (c *CacheCollector) Collect(metrics chan<- prometheus.Metric) {
+	for kind, stats := range c.stats.Snapshot() {
+		// итог Prometheus посчитает сам, иначе sum() учтёт всё дважды
+		if kind == walletcache.KindTotal {
+			continue
+		}
+
+		metrics <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits), kind)
+		metrics <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses), kind)
+		metrics <- prometheus.MustNewConstMetric(cacheErrorsDesc, prometheus.CounterValue, float64(stats.Errors), kind)
+		metrics <- prometheus.MustNewConstMetric(cacheHitRatioDesc, prometheus.GaugeValue, stats.HitRatio, kind)
+	}
+}
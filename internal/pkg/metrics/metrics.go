@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gitlab.com/pjrpc/pjrpc/v2"
+)
+
+const namespace = "wallet"
+
+// codeServerError код, который pjrpc отдаёт для ошибок без своего кода, см. pjrpc.JRPCErrServerError.
+const codeServerError = -32000
+
+// Metrics метрики вызовов JSON-RPC и денежных операций. Суммы в минимальных единицах валюты кошелька.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+
+	stake     *prometheus.CounterVec
+	win       *prometheus.CounterVec
+	rollback  *prometheus.CounterVec
+	rollbacks *prometheus.CounterVec
+}
+
+func New(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_requests_total",
+			Help:      "JSON-RPC calls by service and method.",
+		}, []string{"service", "method"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "JSON-RPC call latency by service and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"service", "method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_errors_total",
+			Help:      "JSON-RPC calls that returned an error, by JSON-RPC error code.",
+		}, []string{"service", "method", "code"}),
+		stake: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stake_amount_total",
+			Help:      "Committed stakes in minor units of the wallet currency.",
+		}, []string{"currency"}),
+		win: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "win_amount_total",
+			Help:      "Committed wins in minor units of the wallet currency.",
+		}, []string{"currency"}),
+		rollback: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rollback_amount_total",
+			Help:      "Rolled back stakes and wins in minor units of the wallet currency.",
+		}, []string{"currency", "kind"}),
+		rollbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rollbacks_total",
+			Help:      "Rolled back payments.",
+		}, []string{"currency"}),
+	}
+
+	registerer.MustRegister(m.requests, m.duration, m.errors, m.stake, m.win, m.rollback, m.rollbacks)
+
+	return m
+}
+
+// Middleware считает вызовы, их длительность и ошибки. service отличает seamless API от админки.
+func (m *Metrics) Middleware(service string) pjrpc.Middleware {
+	return func(next pjrpc.Handler) pjrpc.Handler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			method := "unknown"
+			if data, ok := pjrpc.ContextGetData(ctx); ok {
+				method = data.JRPCRequest.Method
+			}
+
+			started := time.Now()
+			res, err := next(ctx, params)
+
+			m.requests.WithLabelValues(service, method).Inc()
+			m.duration.WithLabelValues(service, method).Observe(time.Since(started).Seconds())
+
+			if err != nil {
+				m.errors.WithLabelValues(service, method, strconv.Itoa(errorCode(err))).Inc()
+			}
+
+			return res, err
+		}
+	}
+}
+
+// errorCode код ошибки, который увидит клиент: так же её преобразует pjrpc.
+func errorCode(err error) int {
+	var errResponse *pjrpc.ErrorResponse
+	if errors.As(err, &errResponse) {
+		return errResponse.Code
+	}
+
+	return codeServerError
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var _ seamlessv2.Store = (*Store)(nil)
+
+// Store считает ставки, выигрыши и откаты по валютам кошелька. Счётчики растут только после фиксации
+// транзакции, стартовый баланс новых кошельков не считается.
+type Store struct {
+	inner   seamlessv2.Store
+	metrics *Metrics
+}
+
+func NewStore(inner seamlessv2.Store, metrics *Metrics) *Store {
+	return &Store{inner: inner, metrics: metrics}
+}
+
+//nolint:ireturn // intentional
+func (s *Store) Begin(ctx context.Context) (seamlessv2.StoreTx, error) {
+	inner, errBegin := s.inner.Begin(ctx)
+	if errBegin != nil {
+		return nil, errBegin //nolint:wrapcheck // intentional
+	}
+
+	return storeTx{StoreTx: inner, metrics: s.metrics}, nil
+}
+
+type storeTx struct {
+	seamlessv2.StoreTx
+	metrics *Metrics
+}
+
+//nolint:ireturn // intentional
+func (t storeTx) Payments() seamlessv2.PaymentRepository {
+	return payments{PaymentRepository: t.StoreTx.Payments(), tx: t}
+}
+
+// currencyCode код валюты для метки, при ошибке справочника метрика пишется по id.
+func (t storeTx) currencyCode(ctx context.Context, currencyID int) string {
+	currency, errByID := t.Currencies().ByID(ctx, currencyID)
+	if errByID != nil || currency.Code == "" {
+		return "id:" + strconv.Itoa(currencyID)
+	}
+
+	return currency.Code
+}
+
+type payments struct {
+	seamlessv2.PaymentRepository
+	tx storeTx
+}
+
+func (p payments) Create(
+	ctx context.Context,
+	userID, currencyID, withdraw, deposit int,
+	transactionRef string,
+	paymentContext repo.PaymentContext,
+) (*repo.Payment, error) {
+	payment, errCreate := p.PaymentRepository.Create(
+		ctx,
+		userID,
+		currencyID,
+		withdraw,
+		deposit,
+		transactionRef,
+		paymentContext,
+	)
+	if errCreate != nil || transactionRef == seamlessv2.InitialDepositRef {
+		return payment, errCreate //nolint:wrapcheck // intentional
+	}
+
+	currency := p.tx.currencyCode(ctx, currencyID)
+
+	p.tx.AfterCommit(func() {
+		p.tx.metrics.stake.WithLabelValues(currency).Add(float64(withdraw))
+		p.tx.metrics.win.WithLabelValues(currency).Add(float64(deposit))
+	})
+
+	return payment, nil
+}
+
+func (p payments) Rollback(ctx context.Context, transactionRef string) ([]repo.Payment, error) {
+	rolledBack, errRollback := p.PaymentRepository.Rollback(ctx, transactionRef)
+	if errRollback != nil {
+		return nil, errRollback //nolint:wrapcheck // intentional
+	}
+
+	for _, payment := range rolledBack {
+		currency := p.tx.currencyCode(ctx, payment.CurrencyID)
+		withdraw, deposit := payment.Withdraw, payment.Deposit
+
+		p.tx.AfterCommit(func() {
+			p.tx.metrics.rollbacks.WithLabelValues(currency).Inc()
+			p.tx.metrics.rollback.WithLabelValues(currency, "stake").Add(float64(withdraw))
+			p.tx.metrics.rollback.WithLabelValues(currency, "win").Add(float64(deposit))
+		})
+	}
+
+	return rolledBack, nil
+}
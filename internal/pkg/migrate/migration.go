@@ -0,0 +1,100 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	upSuffix   = ".SQL"
+	downSuffix = ".down.SQL"
+	// versionParts имя файла YYYY_0MM_DD_NN_name: первые четыре части - версия, остальное - название.
+	versionParts = 4
+)
+
+var (
+	errBadFileName      = errors.New("migration file name must look like YYYY_0MM_DD_NN_name.SQL")
+	errDuplicateVersion = errors.New("several migrations share a version")
+)
+
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+	// Checksum sha256 от текста Up, по нему видно, что применённую миграцию отредактировали.
+	Checksum string
+}
+
+// Load читает миграции из каталога dir, отсортированные по версии.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, errReadDir := fs.ReadDir(fsys, dir)
+	if errReadDir != nil {
+		return nil, fmt.Errorf("read migrations: %w", errReadDir)
+	}
+
+	byVersion := make(map[string]*Migration)
+
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(fileName, upSuffix) {
+			continue
+		}
+
+		isDown := strings.HasSuffix(fileName, downSuffix)
+		base := strings.TrimSuffix(strings.TrimSuffix(fileName, downSuffix), upSuffix)
+
+		parts := strings.SplitN(base, "_", versionParts+1)
+		if len(parts) != versionParts+1 {
+			return nil, fmt.Errorf("%w: %s", errBadFileName, fileName)
+		}
+
+		content, errReadFile := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if errReadFile != nil {
+			return nil, fmt.Errorf("read %s: %w", fileName, errReadFile)
+		}
+
+		version := strings.Join(parts[:versionParts], "_")
+
+		migration, ok := byVersion[version]
+		// up и down одной миграции называются одинаково, другое название - вторая миграция с той же версией
+		if ok && migration.Name != parts[versionParts] {
+			return nil, fmt.Errorf("%w: %s_%s and %s", errDuplicateVersion, version, migration.Name, fileName)
+		}
+
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[versionParts]}
+			byVersion[version] = migration
+		}
+
+		if isDown {
+			migration.Down = string(content)
+
+			continue
+		}
+
+		checksum := sha256.Sum256(content)
+		migration.Up = string(content)
+		migration.Checksum = hex.EncodeToString(checksum[:])
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("%w: %s has only a down file", errBadFileName, migration.Version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/2022_090_12_01_payments.SQL":      {Data: []byte("create table payments ();")},
+		"migrations/2022_090_12_01_payments.down.SQL": {Data: []byte("drop table payments;")},
+		"migrations/2022_009_01_01_users.SQL":         {Data: []byte("create table users ();")},
+		"migrations/2022_090_08_02_wallets.SQL":       {Data: []byte("create table wallets ();")},
+		"migrations/README.md":                        {Data: []byte("не миграция")},
+		"migrations/nested/2022_001_01_01_skip.SQL":   {Data: []byte("select 1;")},
+	}
+
+	migrations, errLoad := Load(fsys, "migrations")
+	if errLoad != nil {
+		t.Fatalf("load: %v", errLoad)
+	}
+
+	var versions []string
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version+" "+migration.Name)
+	}
+
+	want := []string{"2022_009_01_01 users", "2022_090_08_02 wallets", "2022_090_12_01 payments"}
+	if len(versions) != len(want) {
+		t.Fatalf("migrations %v, want %v", versions, want)
+	}
+
+	for i := range want {
+		if versions[i] != want[i] {
+			t.Fatalf("migrations %v, want %v", versions, want)
+		}
+	}
+
+	payments := migrations[2]
+	if payments.Up != "create table payments ();" || payments.Down != "drop table payments;" {
+		t.Fatalf("payments = %+v", payments)
+	}
+
+	// sha256 от "create table payments ();"
+	if payments.Checksum != "4c7118688b9ab7f5688f45b111e7d5d3d83cb44507f13d479f6b63e99710bdd8" {
+		t.Fatalf("checksum = %q", payments.Checksum)
+	}
+
+	if migrations[0].Down != "" {
+		t.Fatalf("users has no down file, got %q", migrations[0].Down)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		files fstest.MapFS
+		want  error
+	}{
+		{
+			name: "duplicate version",
+			files: fstest.MapFS{
+				"migrations/2022_090_12_01_payments.SQL": {Data: []byte("select 1;")},
+				"migrations/2022_090_12_01_wallets.SQL":  {Data: []byte("select 2;")},
+			},
+			want: errDuplicateVersion,
+		},
+		{
+			name: "bad file name",
+			files: fstest.MapFS{
+				"migrations/payments.SQL": {Data: []byte("select 1;")},
+			},
+			want: errBadFileName,
+		},
+		{
+			name: "only down file",
+			files: fstest.MapFS{
+				"migrations/2022_090_12_01_payments.down.SQL": {Data: []byte("select 1;")},
+			},
+			want: errBadFileName,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			if _, errLoad := Load(tc.files, "migrations"); !errors.Is(errLoad, tc.want) {
+				t.Fatalf("load error = %v, want %v", errLoad, tc.want)
+			}
+		})
+	}
+}
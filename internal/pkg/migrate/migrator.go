@@ -0,0 +1,345 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// lockID ключ advisory lock: пока один экземпляр мигрирует, остальные ждут.
+const lockID = 2022090801
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was changed")
+	ErrNoDown           = errors.New("migration has no down file")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+)
+
+type appliedMigration struct {
+	Version   string    `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Status состояние миграции. Applied без Migration.Up - миграция есть в базе, но не в бинаре.
+type Status struct {
+	Migration
+	AppliedAt        *time.Time
+	ChecksumMismatch bool
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+func New(db *sqlx.DB, migrations []Migration, logger *zap.Logger) *Migrator {
+	return &Migrator{db: db, migrations: migrations, logger: logger}
+}
+
+// withLock держит advisory lock на отдельном соединении, все миграции выполняются на нём же.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, errConnx := m.db.Connx(ctx)
+	if errConnx != nil {
+		return errConnx //nolint:wrapcheck // intentional
+	}
+
+	defer conn.Close()
+
+	if _, errLock := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); errLock != nil {
+		return fmt.Errorf("lock: %w", errLock)
+	}
+
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID) //nolint:errcheck // закрытие соединения тоже снимает lock
+
+	if _, errCreate := conn.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS public.schema_migrations (
+			version    text primary key,
+			name       text      not null,
+			checksum   text      not null,
+			applied_at timestamp not null default now()
+		)`,
+	); errCreate != nil {
+		return fmt.Errorf("create schema_migrations: %w", errCreate)
+	}
+
+	return fn(conn)
+}
+
+func applied(ctx context.Context, conn *sqlx.Conn) (map[string]appliedMigration, error) {
+	var rows []appliedMigration
+	if errSelect := conn.SelectContext(
+		ctx,
+		&rows,
+		"SELECT * FROM public.schema_migrations ORDER BY version",
+	); errSelect != nil {
+		return nil, errSelect //nolint:wrapcheck // intentional
+	}
+
+	result := make(map[string]appliedMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+
+	return result, nil
+}
+
+// Up применяет steps ещё не применённых миграций, 0 - все. Каждая миграция в своей транзакции.
+// Если уже применённую миграцию изменили, ничего не применяется.
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+
+	errWithLock := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		var errUp error
+		done, errUp = m.up(ctx, conn, steps)
+
+		return errUp
+	})
+
+	return done, errWithLock
+}
+
+func (m *Migrator) up(ctx context.Context, conn *sqlx.Conn, steps int) ([]Migration, error) {
+	appliedMigrations, errApplied := applied(ctx, conn)
+	if errApplied != nil {
+		return nil, errApplied
+	}
+
+	for _, migration := range m.migrations {
+		if row, ok := appliedMigrations[migration.Version]; ok && row.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("%w: %s_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	var done []Migration
+
+	for _, migration := range m.migrations {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		if _, ok := appliedMigrations[migration.Version]; ok {
+			continue
+		}
+
+		if errApply := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+			if _, errExec := tx.ExecContext(ctx, migration.Up); errExec != nil {
+				return errExec //nolint:wrapcheck // intentional
+			}
+
+			_, errInsert := tx.ExecContext(
+				ctx,
+				"INSERT INTO public.schema_migrations(version, name, checksum) VALUES ($1, $2, $3)",
+				migration.Version,
+				migration.Name,
+				migration.Checksum,
+			)
+
+			return errInsert //nolint:wrapcheck // intentional
+		}); errApply != nil {
+			return done, fmt.Errorf("apply %s_%s: %w", migration.Version, migration.Name, errApply)
+		}
+
+		m.logger.Info("migration applied", zap.String("version", migration.Version), zap.String("name", migration.Name))
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down откатывает steps последних применённых миграций, 0 - одну.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+
+	errWithLock := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		var errDown error
+		done, errDown = m.down(ctx, conn, steps)
+
+		return errDown
+	})
+
+	return done, errWithLock
+}
+
+func (m *Migrator) down(ctx context.Context, conn *sqlx.Conn, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	appliedMigrations, errApplied := applied(ctx, conn)
+	if errApplied != nil {
+		return nil, errApplied
+	}
+
+	var done []Migration
+
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := appliedMigrations[migration.Version]; !ok {
+			continue
+		}
+
+		if migration.Down == "" {
+			return done, fmt.Errorf("%w: %s_%s", ErrNoDown, migration.Version, migration.Name)
+		}
+
+		if errRevert := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+			if _, errExec := tx.ExecContext(ctx, migration.Down); errExec != nil {
+				return errExec //nolint:wrapcheck // intentional
+			}
+
+			_, errDelete := tx.ExecContext(ctx, "DELETE FROM public.schema_migrations WHERE version = $1", migration.Version)
+
+			return errDelete //nolint:wrapcheck // intentional
+		}); errRevert != nil {
+			return done, fmt.Errorf("revert %s_%s: %w", migration.Version, migration.Name, errRevert)
+		}
+
+		m.logger.Info("migration reverted", zap.String("version", migration.Version), zap.String("name", migration.Name))
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Redo откатывает и заново применяет последнюю миграцию под одной блокировкой.
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+
+	errWithLock := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		reverted, errDown := m.down(ctx, conn, 1)
+		if errDown != nil || len(reverted) == 0 {
+			return errDown
+		}
+
+		if _, errUp := m.up(ctx, conn, 1); errUp != nil {
+			return errUp
+		}
+
+		redone = &reverted[0]
+
+		return nil
+	})
+
+	return redone, errWithLock
+}
+
+// Baseline отмечает миграции до version включительно применёнными, не выполняя их.
+// Нужен для баз, накатанных старым migrate.sh без учёта версий.
+func (m *Migrator) Baseline(ctx context.Context, version string) ([]Migration, error) {
+	var done []Migration
+
+	errWithLock := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		known := false
+
+		for _, migration := range m.migrations {
+			known = known || migration.Version == version
+		}
+
+		if !known {
+			return fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+
+			result, errInsert := conn.ExecContext(
+				ctx,
+				`INSERT INTO public.schema_migrations(version, name, checksum) VALUES ($1, $2, $3)
+				ON CONFLICT (version) DO NOTHING`,
+				migration.Version,
+				migration.Name,
+				migration.Checksum,
+			)
+			if errInsert != nil {
+				return errInsert //nolint:wrapcheck // intentional
+			}
+
+			if count, _ := result.RowsAffected(); count != 0 {
+				done = append(done, migration)
+			}
+		}
+
+		return nil
+	})
+
+	return done, errWithLock
+}
+
+// Status все миграции бинаря и базы в порядке версий.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	errWithLock := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		appliedMigrations, errApplied := applied(ctx, conn)
+		if errApplied != nil {
+			return errApplied
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+
+			if row, ok := appliedMigrations[migration.Version]; ok {
+				appliedAt := row.AppliedAt
+				status.AppliedAt = &appliedAt
+				status.ChecksumMismatch = row.Checksum != migration.Checksum
+
+				delete(appliedMigrations, migration.Version)
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		for _, row := range appliedMigrations {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, Status{
+				Migration: Migration{Version: row.Version, Name: row.Name, Checksum: row.Checksum},
+				AppliedAt: &appliedAt,
+			})
+		}
+
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+		return nil
+	})
+
+	return statuses, errWithLock
+}
+
+// CurrentVersion последняя применённая версия, пустая строка если миграций ещё не было.
+func CurrentVersion(ctx context.Context, db *sqlx.DB) (string, error) {
+	var version string
+	err := db.GetContext(
+		ctx,
+		&version,
+		`SELECT coalesce(max(version), '') FROM public.schema_migrations`,
+	)
+
+	return version, err //nolint:wrapcheck // intentional
+}
+
+func inTx(ctx context.Context, conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, errBeginTxx := conn.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if errFn := fn(tx); errFn != nil {
+		return errFn
+	}
+
+	return tx.Commit() //nolint:wrapcheck // intentional
+}
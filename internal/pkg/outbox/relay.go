@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultInterval  = time.Second
+	defaultBaseDelay = time.Second
+	defaultMaxDelay  = 5 * time.Minute
+)
+
+// Relay переносит события из billing.outbox в Sink. Событие помечается опубликованным
+// только после успешной отправки, так что доставка at-least-once. Порядок событий одного игрока
+// сохраняется: relay работает под advisory lock в одном инстансе, а после ошибки событие
+// откладывается с экспоненциальной задержкой и остальные события этого игрока ждут его.
+type Relay struct {
+	db        *sqlx.DB
+	sink      Sink
+	logger    *zap.Logger
+	batchSize int
+	interval  time.Duration
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	now       func() time.Time
+}
+
+func NewRelay(db *sqlx.DB, sink Sink, logger *zap.Logger) *Relay {
+	return &Relay{
+		db:        db,
+		sink:      sink,
+		logger:    logger,
+		batchSize: defaultBatchSize,
+		interval:  defaultInterval,
+		baseDelay: defaultBaseDelay,
+		maxDelay:  defaultMaxDelay,
+		now:       time.Now,
+	}
+}
+
+// Run опрашивает outbox до отмены ctx.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		published, errFlush := r.Flush(ctx)
+		if errFlush != nil {
+			r.logger.Error("outbox relay flush failed", zap.Error(errFlush))
+		}
+
+		// полная пачка значит что в outbox скорее всего есть ещё события, ждать тика не нужно
+		if errFlush == nil && published == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush публикует одну пачку событий и возвращает количество опубликованных.
+// Публикация идёт вне транзакции: медленный sink не держит транзакцию открытой,
+// каждое событие отмечается своей короткой транзакцией сразу после отправки.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	conn, errConnx := r.db.Connx(ctx)
+	if errConnx != nil {
+		return 0, errConnx //nolint:wrapcheck // intentional
+	}
+
+	defer conn.Close()
+
+	locked, errTryLockOutbox := repo.TryLockOutbox(ctx, conn)
+	if errTryLockOutbox != nil || !locked {
+		return 0, errTryLockOutbox //nolint:wrapcheck // intentional
+	}
+
+	defer repo.UnlockOutbox(context.Background(), conn) //nolint:errcheck // закрытие соединения тоже снимает lock
+
+	var events []repo.OutboxEvent
+
+	if errGetUnpublished := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		var errGet error
+		events, errGet = repo.GetUnpublishedOutboxEvents(ctx, tx, r.batchSize)
+
+		return errGet //nolint:wrapcheck // intentional
+	}); errGetUnpublished != nil {
+		return 0, errGetUnpublished
+	}
+
+	var (
+		published int
+		blocked   = make(map[int]struct{})
+	)
+
+	for _, event := range events {
+		// более раннее событие игрока в этой пачке не опубликовано, следующие ждут вместе с ним
+		if _, ok := blocked[event.UserID]; ok {
+			continue
+		}
+
+		if errPublish := r.sink.Publish(ctx, toEvent(event)); errPublish != nil {
+			blocked[event.UserID] = struct{}{}
+
+			r.logger.Warn(
+				"outbox event publish failed",
+				zap.Int64("event_id", event.ID),
+				zap.Int("user_id", event.UserID),
+				zap.Int("attempt", event.Attempts+1),
+				zap.Error(errPublish),
+			)
+
+			if errMarkFailed := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				return repo.MarkOutboxEventFailed( //nolint:wrapcheck // intentional
+					ctx,
+					tx,
+					event.ID,
+					r.now().Add(r.backoff(event.Attempts)),
+					errPublish.Error(),
+				)
+			}); errMarkFailed != nil {
+				return published, errMarkFailed
+			}
+
+			continue
+		}
+
+		if errMarkPublished := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+			return repo.MarkOutboxEventPublished(ctx, tx, event.ID) //nolint:wrapcheck // intentional
+		}); errMarkPublished != nil {
+			return published, errMarkPublished
+		}
+
+		published++
+	}
+
+	return published, nil
+}
+
+// backoff baseDelay * 2^attempts, но не больше maxDelay.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.baseDelay
+	for i := 0; i < attempts && delay < r.maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > r.maxDelay {
+		delay = r.maxDelay
+	}
+
+	return delay
+}
+
+func inTx(ctx context.Context, conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, errBeginTxx := conn.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if errFn := fn(tx); errFn != nil {
+		return errFn
+	}
+
+	return tx.Commit() //nolint:wrapcheck // intentional
+}
+
+func toEvent(event repo.OutboxEvent) Event {
+	return Event{
+		ID:        event.ID,
+		Type:      string(event.EventType),
+		UserID:    event.UserID,
+		CreatedAt: event.CreatedAt,
+		Payload:   event.Payload,
+	}
+}
@@ -0,0 +1,228 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+var (
+	errUnexpectedQuery = errors.New("unexpected query")
+	errSink            = errors.New("sink unavailable")
+)
+
+var outboxColumns = []string{
+	"id", "created_at", "user_id", "event_type", "payload", "published_at", "attempts", "last_error", "next_attempt_at",
+}
+
+type statement struct {
+	query string
+	args  []driver.NamedValue
+}
+
+// fakeDB отдаёт events на выборку из outbox, всегда даёт advisory lock и запоминает остальные запросы.
+type fakeDB struct {
+	mu         sync.Mutex
+	events     [][]driver.Value
+	statements []statement
+}
+
+func (d *fakeDB) executed(match string) []statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var result []statement
+
+	for _, s := range d.statements {
+		if strings.Contains(s.query, match) {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errUnexpectedQuery }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "pg_try_advisory_lock"):
+		return &fakeRows{columns: []string{"locked"}, rows: [][]driver.Value{{true}}}, nil
+	case strings.Contains(query, "FROM billing.outbox"):
+		rows := c.db.events
+		c.db.events = nil
+
+		return &fakeRows{columns: outboxColumns, rows: rows}, nil
+	}
+
+	return nil, errUnexpectedQuery
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.statements = append(c.db.statements, statement{query: query, args: args})
+
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+var (
+	fakeDBs   = map[string]*fakeDB{}
+	fakeDBsMu sync.Mutex
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+
+	return fakeConn{db: fakeDBs[dsn]}, nil
+}
+
+func init() {
+	sql.Register("outbox-fake", fakeDriver{})
+}
+
+// failingSink не принимает события из failing, остальные передаёт в ChannelSink.
+type failingSink struct {
+	*ChannelSink
+	failing map[int64]struct{}
+}
+
+func (s failingSink) Publish(ctx context.Context, event Event) error {
+	if _, ok := s.failing[event.ID]; ok {
+		return errSink
+	}
+
+	return s.ChannelSink.Publish(ctx, event)
+}
+
+func outboxRow(id int64, userID int) []driver.Value {
+	return []driver.Value{
+		id, time.Now(), int64(userID), "payment", []byte(`{}`), nil, int64(0), "", time.Now(),
+	}
+}
+
+// TestFlushBlocksFailedPlayer событие, которое не удалось опубликовать, держит следующие события
+// своего игрока, но не события других игроков.
+func TestFlushBlocksFailedPlayer(t *testing.T) {
+	db := &fakeDB{events: [][]driver.Value{
+		outboxRow(1, 10),
+		outboxRow(2, 20),
+		outboxRow(3, 10),
+		outboxRow(4, 20),
+	}}
+
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = db
+	fakeDBsMu.Unlock()
+
+	sqlDB, errOpen := sqlx.Open("outbox-fake", t.Name())
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+
+	defer sqlDB.Close()
+
+	channel := NewChannelSink(10)
+	now := time.Unix(1700000000, 0)
+
+	relay := NewRelay(sqlDB, failingSink{ChannelSink: channel, failing: map[int64]struct{}{1: {}}}, zap.NewNop())
+	relay.now = func() time.Time { return now }
+
+	published, errFlush := relay.Flush(context.Background())
+	if errFlush != nil || published != 2 {
+		t.Fatalf("flush = %d, %v, want 2 published", published, errFlush)
+	}
+
+	close(channel.events)
+
+	var ids []int64
+	for event := range channel.Events() {
+		ids = append(ids, event.ID)
+	}
+
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 4 {
+		t.Fatalf("published events %v, want [2 4]", ids)
+	}
+
+	failed := db.executed("next_attempt_at = $2")
+	if len(failed) != 1 || failed[0].args[0].Value != int64(1) {
+		t.Fatalf("failed marks %+v, want event 1", failed)
+	}
+
+	if nextAttemptAt, _ := failed[0].args[1].Value.(time.Time); !nextAttemptAt.Equal(now.Add(defaultBaseDelay)) {
+		t.Fatalf("next attempt at %v, want %v", failed[0].args[1].Value, now.Add(defaultBaseDelay))
+	}
+
+	marked := db.executed("published_at = now()")
+	if len(marked) != 2 {
+		t.Fatalf("%d events marked published, want 2", len(marked))
+	}
+
+	for _, published := range marked {
+		if id := published.args[0].Value; id != int64(2) && id != int64(4) {
+			t.Fatalf("event %v marked published", id)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	relay := NewRelay(nil, nil, zap.NewNop())
+
+	for attempts, want := range map[int]time.Duration{
+		0:  defaultBaseDelay,
+		1:  2 * defaultBaseDelay,
+		4:  16 * defaultBaseDelay,
+		9:  defaultMaxDelay,
+		50: defaultMaxDelay,
+	} {
+		if got := relay.backoff(attempts); got != want {
+			t.Fatalf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event событие кошелька в том виде, в котором оно уходит во внешние системы.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    int             `json:"user_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Sink получатель событий. Доставка at-least-once, поэтому получатель должен
+// дедуплицировать события по ID.
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+var ErrUnexpectedStatus = errors.New("unexpected webhook response status")
+
+// WebhookSink отправляет каждое событие отдельным POST запросом.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	if client == nil {
+		const defaultTimeout = 10 * time.Second
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return &WebhookSink{url: url, client: client}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	body, errMarshal := json.Marshal(event)
+	if errMarshal != nil {
+		return fmt.Errorf("marshal event: %w", errMarshal)
+	}
+
+	req, errNewRequest := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if errNewRequest != nil {
+		return fmt.Errorf("new request: %w", errNewRequest)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(event.ID, 10))
+
+	resp, errDo := s.client.Do(req)
+	if errDo != nil {
+		return fmt.Errorf("post event: %w", errDo)
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// WriterSink пишет события строками JSON, подходит для stdout и файлов.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Publish(_ context.Context, event Event) error {
+	line, errMarshal := json.Marshal(event)
+	if errMarshal != nil {
+		return fmt.Errorf("marshal event: %w", errMarshal)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, errWrite := s.w.Write(append(line, '\n')); errWrite != nil {
+		return fmt.Errorf("write event: %w", errWrite)
+	}
+
+	return nil
+}
+
+// ChannelSink передаёт события в канал внутри процесса, используется в тестах.
+type ChannelSink struct {
+	events chan Event
+}
+
+func NewChannelSink(size int) *ChannelSink {
+	return &ChannelSink{events: make(chan Event, size)}
+}
+
+func (s *ChannelSink) Events() <-chan Event {
+	return s.events
+}
+
+func (s *ChannelSink) Publish(ctx context.Context, event Event) error {
+	select {
+	case s.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck // intentional
+	}
+}
@@ -0,0 +1,218 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+type Bucket string
+
+const (
+	BucketMatched           Bucket = "matched"
+	BucketMissingInWallet   Bucket = "missing_in_wallet"
+	BucketMissingInProvider Bucket = "missing_in_provider"
+	BucketAmountMismatch    Bucket = "amount_mismatch"
+	BucketRollbackMismatch  Bucket = "rollback_mismatch"
+)
+
+type Discrepancy struct {
+	Bucket         Bucket `json:"bucket"`
+	TransactionRef string `json:"transactionRef"`
+	Currency       string `json:"currency"`
+	GameID         string `json:"gameId"`
+	Provider       *Entry `json:"provider,omitempty"`
+	Wallet         *Entry `json:"wallet,omitempty"`
+}
+
+// Total итоги бакета по валюте и игре, суммы провайдера и кошелька считаются отдельно.
+type Total struct {
+	Bucket           Bucket `json:"bucket"`
+	Currency         string `json:"currency"`
+	GameID           string `json:"gameId"`
+	Count            int    `json:"count"`
+	ProviderWithdraw int    `json:"providerWithdraw"`
+	ProviderDeposit  int    `json:"providerDeposit"`
+	WalletWithdraw   int    `json:"walletWithdraw"`
+	WalletDeposit    int    `json:"walletDeposit"`
+}
+
+type Report struct {
+	Matched       int           `json:"matched"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+	Totals        []Total       `json:"totals"`
+}
+
+type totalKey struct {
+	bucket   Bucket
+	currency string
+	gameID   string
+}
+
+// Reconcile сопоставляет выписку провайдера с платежами кошелька по transactionRef.
+// Одна транзакция может одновременно попасть в amount_mismatch и rollback_mismatch.
+// Повторы transactionRef в выписке не суммируются, учитывается первая строка.
+func Reconcile(statement, ledger []Entry) *Report {
+	wallet := make(map[string]*Entry, len(ledger))
+	for i := range ledger {
+		if _, ok := wallet[ledger[i].TransactionRef]; !ok {
+			wallet[ledger[i].TransactionRef] = &ledger[i]
+		}
+	}
+
+	report := &Report{Discrepancies: []Discrepancy{}}
+	totals := make(map[totalKey]*Total)
+	seen := make(map[string]struct{}, len(statement))
+
+	add := func(bucket Bucket, provider, walletEntry *Entry) {
+		side := provider
+		if side == nil {
+			side = walletEntry
+		}
+
+		if bucket != BucketMatched {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Bucket:         bucket,
+				TransactionRef: side.TransactionRef,
+				Currency:       side.Currency,
+				GameID:         side.GameID,
+				Provider:       provider,
+				Wallet:         walletEntry,
+			})
+		}
+
+		key := totalKey{bucket: bucket, currency: side.Currency, gameID: side.GameID}
+
+		total, ok := totals[key]
+		if !ok {
+			total = &Total{Bucket: bucket, Currency: side.Currency, GameID: side.GameID}
+			totals[key] = total
+		}
+
+		total.Count++
+
+		if provider != nil {
+			total.ProviderWithdraw += provider.Withdraw
+			total.ProviderDeposit += provider.Deposit
+		}
+
+		if walletEntry != nil {
+			total.WalletWithdraw += walletEntry.Withdraw
+			total.WalletDeposit += walletEntry.Deposit
+		}
+	}
+
+	for i := range statement {
+		provider := &statement[i]
+		if _, ok := seen[provider.TransactionRef]; ok {
+			continue
+		}
+
+		seen[provider.TransactionRef] = struct{}{}
+
+		walletEntry, ok := wallet[provider.TransactionRef]
+		if !ok {
+			add(BucketMissingInWallet, provider, nil)
+
+			continue
+		}
+
+		amountOK := provider.Withdraw == walletEntry.Withdraw && provider.Deposit == walletEntry.Deposit
+		rollbackOK := provider.RolledBack == walletEntry.RolledBack
+
+		if !amountOK {
+			add(BucketAmountMismatch, provider, walletEntry)
+		}
+
+		if !rollbackOK {
+			add(BucketRollbackMismatch, provider, walletEntry)
+		}
+
+		if amountOK && rollbackOK {
+			report.Matched++
+			add(BucketMatched, provider, walletEntry)
+		}
+	}
+
+	for i := range ledger {
+		if _, ok := seen[ledger[i].TransactionRef]; !ok {
+			seen[ledger[i].TransactionRef] = struct{}{}
+			add(BucketMissingInProvider, nil, &ledger[i])
+		}
+	}
+
+	sort.SliceStable(report.Discrepancies, func(i, j int) bool {
+		a, b := report.Discrepancies[i], report.Discrepancies[j]
+		if a.Bucket != b.Bucket {
+			return a.Bucket < b.Bucket
+		}
+
+		return a.TransactionRef < b.TransactionRef
+	})
+
+	report.Totals = make([]Total, 0, len(totals))
+	for _, total := range totals {
+		report.Totals = append(report.Totals, *total)
+	}
+
+	sort.Slice(report.Totals, func(i, j int) bool {
+		a, b := report.Totals[i], report.Totals[j]
+		if a.Bucket != b.Bucket {
+			return a.Bucket < b.Bucket
+		}
+
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+
+		return a.GameID < b.GameID
+	})
+
+	return report
+}
+
+// WriteCSV пишет расхождения построчно, итоги в csv не попадают.
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+
+	if errWrite := writer.Write([]string{
+		"bucket", "transaction_ref", "currency", "game_id",
+		"provider_withdraw", "provider_deposit", "provider_rolled_back",
+		"wallet_withdraw", "wallet_deposit", "wallet_rolled_back",
+	}); errWrite != nil {
+		return fmt.Errorf("write header: %w", errWrite)
+	}
+
+	for _, discrepancy := range report.Discrepancies {
+		record := []string{
+			string(discrepancy.Bucket),
+			discrepancy.TransactionRef,
+			discrepancy.Currency,
+			discrepancy.GameID,
+		}
+		record = append(record, entryColumns(discrepancy.Provider)...)
+		record = append(record, entryColumns(discrepancy.Wallet)...)
+
+		if errWrite := writer.Write(record); errWrite != nil {
+			return fmt.Errorf("write %s: %w", discrepancy.TransactionRef, errWrite)
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error() //nolint:wrapcheck // intentional
+}
+
+func entryColumns(entry *Entry) []string {
+	if entry == nil {
+		return []string{"", "", ""}
+	}
+
+	return []string{
+		strconv.Itoa(entry.Withdraw),
+		strconv.Itoa(entry.Deposit),
+		strconv.FormatBool(entry.RolledBack),
+	}
+}
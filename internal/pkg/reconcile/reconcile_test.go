@@ -0,0 +1,152 @@
+package reconcile_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	testCases := []struct {
+		name          string
+		statement     []reconcile.Entry
+		ledger        []reconcile.Entry
+		wantMatched   int
+		wantBuckets   []reconcile.Bucket
+		wantRefs      []string
+		wantTotalsLen int
+	}{
+		{
+			// ставка в валюте игры: кошелёк отдаёт суммы и валюту провайдера, а не пересчитанные в EUR
+			name: "converted payment",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "USD", GameID: "g", Withdraw: 110, Deposit: 55},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "USD", GameID: "g", Withdraw: 110, Deposit: 55},
+			},
+			wantMatched:   1,
+			wantTotalsLen: 1,
+		},
+		{
+			name: "missing in ledger",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+				{TransactionRef: "tx-2", Currency: "EUR", GameID: "g", Withdraw: 100},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+			},
+			wantMatched:   1,
+			wantBuckets:   []reconcile.Bucket{reconcile.BucketMissingInWallet},
+			wantRefs:      []string{"tx-2"},
+			wantTotalsLen: 2,
+		},
+		{
+			name: "missing in statement",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+				{TransactionRef: "tx-2", Currency: "EUR", GameID: "g", Deposit: 40},
+			},
+			wantMatched:   1,
+			wantBuckets:   []reconcile.Bucket{reconcile.BucketMissingInProvider},
+			wantRefs:      []string{"tx-2"},
+			wantTotalsLen: 2,
+		},
+		{
+			name: "amount mismatch",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100, Deposit: 50},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100, Deposit: 5},
+			},
+			wantBuckets:   []reconcile.Bucket{reconcile.BucketAmountMismatch},
+			wantRefs:      []string{"tx-1"},
+			wantTotalsLen: 1,
+		},
+		{
+			name: "rolled back on both sides",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100, RolledBack: true},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100, RolledBack: true},
+			},
+			wantMatched:   1,
+			wantTotalsLen: 1,
+		},
+		{
+			name: "rolled back only by provider",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100, RolledBack: true},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+			},
+			wantBuckets:   []reconcile.Bucket{reconcile.BucketRollbackMismatch},
+			wantRefs:      []string{"tx-1"},
+			wantTotalsLen: 1,
+		},
+		{
+			// транзакция попадает в оба бакета сразу
+			name: "amount and rollback mismatch",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 90, RolledBack: true},
+			},
+			wantBuckets:   []reconcile.Bucket{reconcile.BucketAmountMismatch, reconcile.BucketRollbackMismatch},
+			wantRefs:      []string{"tx-1", "tx-1"},
+			wantTotalsLen: 2,
+		},
+		{
+			// повтор строки выписки не считается второй транзакцией
+			name: "repeated statement row",
+			statement: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 999},
+			},
+			ledger: []reconcile.Entry{
+				{TransactionRef: "tx-1", Currency: "EUR", GameID: "g", Withdraw: 100},
+			},
+			wantMatched:   1,
+			wantTotalsLen: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			report := reconcile.Reconcile(tc.statement, tc.ledger)
+
+			if report.Matched != tc.wantMatched {
+				t.Fatalf("matched = %d, want %d", report.Matched, tc.wantMatched)
+			}
+
+			var (
+				buckets []reconcile.Bucket
+				refs    []string
+			)
+
+			for _, discrepancy := range report.Discrepancies {
+				buckets = append(buckets, discrepancy.Bucket)
+				refs = append(refs, discrepancy.TransactionRef)
+			}
+
+			if !reflect.DeepEqual(buckets, tc.wantBuckets) || !reflect.DeepEqual(refs, tc.wantRefs) {
+				t.Fatalf("discrepancies %v %v, want %v %v", buckets, refs, tc.wantBuckets, tc.wantRefs)
+			}
+
+			if len(report.Totals) != tc.wantTotalsLen {
+				t.Fatalf("totals = %+v, want %d", report.Totals, tc.wantTotalsLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Entry строка выписки провайдера или платёж кошелька в общем для сверки виде.
+type Entry struct {
+	TransactionRef string `json:"transactionRef"`
+	Currency       string `json:"currency"`
+	GameID         string `json:"gameId"`
+	Withdraw       int    `json:"withdraw"`
+	Deposit        int    `json:"deposit"`
+	RolledBack     bool   `json:"rolledBack"`
+}
+
+var (
+	ErrUnknownFormat       = errors.New("unknown statement format")
+	ErrMissingColumn       = errors.New("statement has no required column")
+	ErrEmptyTransactionRef = errors.New("statement row without transactionRef")
+)
+
+// csvColumns допустимые названия колонок csv выписки, регистр и подчёркивания не важны.
+var csvColumns = map[string]string{
+	"transactionref": "transactionRef",
+	"currency":       "currency",
+	"gameid":         "gameId",
+	"withdraw":       "withdraw",
+	"deposit":        "deposit",
+	"rolledback":     "rolledBack",
+}
+
+// ParseStatement читает выписку в формате "csv" (с заголовком) или "json" (массив объектов).
+func ParseStatement(r io.Reader, format string) ([]Entry, error) {
+	var (
+		entries []Entry
+		err     error
+	)
+
+	switch format {
+	case "csv":
+		entries, err = parseCSV(r)
+	case "json":
+		err = json.NewDecoder(r).Decode(&entries)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		if entry.TransactionRef == "" {
+			return nil, fmt.Errorf("%w: row %d", ErrEmptyTransactionRef, i+1)
+		}
+	}
+
+	return entries, nil
+}
+
+func parseCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, errRead := reader.Read()
+	if errRead != nil {
+		return nil, fmt.Errorf("read header: %w", errRead)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if column, ok := csvColumns[strings.ToLower(strings.ReplaceAll(name, "_", ""))]; ok {
+			index[column] = i
+		}
+	}
+
+	for _, column := range []string{"transactionRef", "withdraw", "deposit"} {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, column)
+		}
+	}
+
+	var entries []Entry
+
+	for line := 2; ; line++ {
+		record, errRecord := reader.Read()
+		if errors.Is(errRecord, io.EOF) {
+			return entries, nil
+		}
+
+		if errRecord != nil {
+			return nil, fmt.Errorf("read line %d: %w", line, errRecord)
+		}
+
+		entry, errEntry := csvEntry(record, index)
+		if errEntry != nil {
+			return nil, fmt.Errorf("line %d: %w", line, errEntry)
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+func csvEntry(record []string, index map[string]int) (Entry, error) {
+	field := func(column string) string {
+		if i, ok := index[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+
+		return ""
+	}
+
+	entry := Entry{
+		TransactionRef: field("transactionRef"),
+		Currency:       field("currency"),
+		GameID:         field("gameId"),
+	}
+
+	var err error
+
+	if entry.Withdraw, err = strconv.Atoi(field("withdraw")); err != nil {
+		return entry, fmt.Errorf("withdraw: %w", err)
+	}
+
+	if entry.Deposit, err = strconv.Atoi(field("deposit")); err != nil {
+		return entry, fmt.Errorf("deposit: %w", err)
+	}
+
+	if rolledBack := field("rolledBack"); rolledBack != "" {
+		if entry.RolledBack, err = strconv.ParseBool(rolledBack); err != nil {
+			return entry, fmt.Errorf("rolledBack: %w", err)
+		}
+	}
+
+	return entry, nil
+}
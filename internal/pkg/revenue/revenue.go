@@ -0,0 +1,89 @@
+package revenue
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const defaultRefreshInterval = 5 * time.Minute
+
+// Refresh инкрементально обновляет billing.revenue_daily и возвращает число пересчитанных дней.
+func Refresh(ctx context.Context, db *sqlx.DB) (int, error) {
+	tx, errBeginTxx := db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return 0, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	days, errRefresh := repo.RefreshRevenueDaily(ctx, tx)
+	if errRefresh != nil {
+		return 0, errRefresh //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return 0, errCommit //nolint:wrapcheck // intentional
+	}
+
+	return days, nil
+}
+
+// RunRefresher периодически обновляет агрегаты до отмены ctx.
+func RunRefresher(ctx context.Context, db *sqlx.DB, logger *zap.Logger) {
+	ticker := time.NewTicker(defaultRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if days, errRefresh := Refresh(ctx, db); errRefresh != nil {
+			logger.Error("revenue rollup refresh failed", zap.Error(errRefresh))
+		} else {
+			logger.Debug("revenue rollup refreshed", zap.Int("days", days))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WriteCSV выгрузка дневных агрегатов, суммы в минорных единицах валюты.
+func WriteCSV(w io.Writer, rows []repo.RevenueDaily) error {
+	writer := csv.NewWriter(w)
+
+	if errWrite := writer.Write([]string{
+		"day", "operator_id", "game_id", "currency", "bets", "stake", "win", "ggr", "adjustments", "ngr",
+	}); errWrite != nil {
+		return fmt.Errorf("write header: %w", errWrite)
+	}
+
+	for _, row := range rows {
+		if errWrite := writer.Write([]string{
+			row.Day.Format("2006-01-02"),
+			strconv.Itoa(row.OperatorID),
+			row.GameID,
+			row.Currency,
+			strconv.Itoa(row.Bets),
+			strconv.FormatInt(row.Stake, 10),
+			strconv.FormatInt(row.Win, 10),
+			strconv.FormatInt(row.GGR, 10),
+			strconv.FormatInt(row.Adjustments, 10),
+			strconv.FormatInt(row.NGR, 10),
+		}); errWrite != nil {
+			return fmt.Errorf("write row: %w", errWrite)
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error() //nolint:wrapcheck // intentional
+}
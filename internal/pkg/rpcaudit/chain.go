@@ -0,0 +1,143 @@
+package rpcaudit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const verifyBatchSize = 1000
+
+// Hash sha256 от хэша предыдущей записи и всех полей записи. Поля разделяются нулевым байтом,
+// время берётся в UTC с точностью до микросекунд, как его хранит postgres.
+func Hash(audit *repo.RPCAudit) string {
+	h := sha256.New()
+
+	for _, part := range []string{
+		audit.PrevHash,
+		audit.CreatedAt.UTC().Format(time.RFC3339Nano),
+		audit.Method,
+		strconv.Itoa(audit.CallerID),
+		audit.RemoteAddr,
+		audit.RequestID,
+		audit.Params,
+		audit.Response,
+		audit.Error,
+		strconv.FormatInt(audit.LatencyMicros, 10),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Store журнал вызовов в billing.rpc_audit.
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// Append дописывает запись в конец цепочки, заполняя PrevHash и Hash.
+func (s *Store) Append(ctx context.Context, audit *repo.RPCAudit) error {
+	return s.AppendBatch(ctx, []*repo.RPCAudit{audit})
+}
+
+// AppendBatch дописывает записи по порядку одной транзакцией: блокировка цепочки берётся один раз на пачку.
+func (s *Store) AppendBatch(ctx context.Context, audits []*repo.RPCAudit) error {
+	tx, errBeginTxx := s.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if errLock := repo.LockRPCAudit(ctx, tx); errLock != nil {
+		return errLock //nolint:wrapcheck // intentional
+	}
+
+	prevHash, errGetLastHash := repo.GetLastRPCAuditHash(ctx, tx)
+	if errGetLastHash != nil {
+		return errGetLastHash //nolint:wrapcheck // intentional
+	}
+
+	for _, audit := range audits {
+		audit.CreatedAt = audit.CreatedAt.UTC().Truncate(time.Microsecond)
+		audit.PrevHash = prevHash
+		audit.Hash = Hash(audit)
+
+		if errNewRPCAudit := repo.NewRPCAudit(ctx, tx, audit); errNewRPCAudit != nil {
+			return errNewRPCAudit //nolint:wrapcheck // intentional
+		}
+
+		prevHash = audit.Hash
+	}
+
+	return tx.Commit() //nolint:wrapcheck // intentional
+}
+
+// Prune удаляет записи старше before, возвращает число удалённых.
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	tx, errBeginTxx := s.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return 0, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if errLock := repo.LockRPCAudit(ctx, tx); errLock != nil {
+		return 0, errLock //nolint:wrapcheck // intentional
+	}
+
+	count, errPrune := repo.PruneRPCAudit(ctx, tx, before)
+	if errPrune != nil {
+		return 0, errPrune //nolint:wrapcheck // intentional
+	}
+
+	return count, tx.Commit() //nolint:wrapcheck // intentional
+}
+
+// Verify проходит цепочку от начала и возвращает id первой записи, у которой не сходится
+// ссылка на предыдущую или собственный хэш. 0 - цепочка цела. checked - сколько записей проверено.
+func (s *Store) Verify(ctx context.Context) (brokenID int64, checked int, err error) {
+	tx, errBeginTxx := s.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return 0, 0, errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	anchor, errGetAnchor := repo.GetRPCAuditAnchor(ctx, tx)
+	if errGetAnchor != nil {
+		return 0, 0, errGetAnchor //nolint:wrapcheck // intentional
+	}
+
+	prevHash, lastID := anchor.LastPrunedHash, anchor.LastPrunedID
+
+	for {
+		audits, errGetAfter := repo.GetRPCAuditAfter(ctx, tx, lastID, verifyBatchSize)
+		if errGetAfter != nil {
+			return 0, checked, errGetAfter //nolint:wrapcheck // intentional
+		}
+
+		if len(audits) == 0 {
+			return 0, checked, nil
+		}
+
+		for i := range audits {
+			audit := &audits[i]
+			if audit.PrevHash != prevHash || Hash(audit) != audit.Hash {
+				return audit.ID, checked, nil
+			}
+
+			prevHash, lastID = audit.Hash, audit.ID
+			checked++
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package rpcaudit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gitlab.com/pjrpc/pjrpc/v2"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcbatch"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// Middleware пишет в журнал каждый вызов: метод, вызывающего, сырые params, ответ или ошибку и время выполнения.
+// Ответ уходит провайдеру после того, как Writer допишет запись.
+func Middleware(writer *Writer, redactor *Redactor) pjrpc.Middleware {
+	return func(next pjrpc.Handler) pjrpc.Handler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			started := time.Now()
+			res, err := next(ctx, params)
+			latency := time.Since(started)
+
+			audit := &repo.RPCAudit{
+				CreatedAt:     started,
+				CallerID:      callerID(params),
+				Params:        string(redactor.Redact(params)),
+				LatencyMicros: latency.Microseconds(),
+			}
+
+			if data, ok := pjrpc.ContextGetData(ctx); ok {
+				audit.Method = data.JRPCRequest.Method
+				audit.RequestID = data.JRPCRequest.GetID()
+				audit.RemoteAddr = data.HTTTRequest.Header.Get("X-Real-IP")
+
+				if audit.RemoteAddr == "" {
+					audit.RemoteAddr = data.HTTTRequest.RemoteAddr
+				}
+			}
+
+			if err != nil {
+				audit.Error = err.Error()
+			} else if response, errMarshal := json.Marshal(res); errMarshal == nil {
+				audit.Response = string(redactor.Redact(response))
+			}
+
+			// в атомарном пакете успешный ответ заменяется ошибкой, если пакет откатился,
+			// поэтому запись ждёт итога пакета и пишется с тем ответом, который получил провайдер
+			if !rpcbatch.AfterBatch(ctx, func() { writer.Write(audit) }, func(errAborted error) {
+				if audit.Error == "" {
+					audit.Response = ""
+					audit.Error = errAborted.Error()
+				}
+
+				writer.Write(audit)
+			}) {
+				writer.Write(audit)
+			}
+
+			return res, err
+		}
+	}
+}
+
+func callerID(params json.RawMessage) int {
+	var caller struct {
+		CallerID int `json:"callerId"`
+	}
+
+	_ = json.Unmarshal(params, &caller)
+
+	return caller.CallerID
+}
+
+// RunRetention раз в сутки удаляет записи старше retention до отмены ctx.
+func RunRetention(ctx context.Context, store *Store, retention time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(24 * time.Hour) //nolint:gomnd // раз в сутки
+	defer ticker.Stop()
+
+	for {
+		if count, errPrune := store.Prune(ctx, time.Now().Add(-retention)); errPrune != nil {
+			logger.Error("rpc audit retention failed", zap.Error(errPrune))
+		} else if count > 0 {
+			logger.Info("rpc audit pruned", zap.Int64("records", count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
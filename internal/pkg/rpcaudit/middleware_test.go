@@ -0,0 +1,152 @@
+package rpcaudit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/pjrpc/pjrpc/v2"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcbatch"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var (
+	errNoQueries = errors.New("fake driver runs no queries")
+	errBet       = errors.New("bet failed")
+)
+
+// fakeDriver база без запросов: пакету нужны только Begin, Commit и Rollback.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errNoQueries }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+var registerOnce sync.Once
+
+// serveAudited выполняет пакет через pjrpc с журналом и возвращает ответ и записи журнала.
+func serveAudited(t *testing.T, body string, atomic bool) (pjrpc.BatchResponses, []*repo.RPCAudit) {
+	t.Helper()
+
+	registerOnce.Do(func() { sql.Register("rpcaudit-fake", fakeDriver{}) })
+
+	db, errOpen := sqlx.Open("rpcaudit-fake", "")
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	appender := &fakeAppender{}
+	writer := NewWriter(appender, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		writer.Run(ctx)
+	}()
+
+	srv := pjrpc.NewServerHTTP()
+	batchHandler := rpcbatch.NewHandler(srv, db, 0, zap.NewNop())
+
+	srv.RegisterMethod("ok", func(context.Context, json.RawMessage) (interface{}, error) {
+		return map[string]int{"balance": 100}, nil
+	})
+	srv.RegisterMethod("fail", func(context.Context, json.RawMessage) (interface{}, error) {
+		return nil, errBet
+	})
+	srv.With(Middleware(writer, nil), batchHandler.Middleware)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(pjrpc.ContentTypeHeaderName, pjrpc.ContentTypeHeaderValue)
+	if atomic {
+		req.Header.Set(rpcbatch.AtomicHeader, "true")
+	}
+
+	recorder := httptest.NewRecorder()
+	batchHandler.ServeHTTP(recorder, req)
+
+	var responses pjrpc.BatchResponses
+	if errUnmarshal := json.Unmarshal(recorder.Body.Bytes(), &responses); errUnmarshal != nil {
+		t.Fatalf("response %s: %v", recorder.Body.String(), errUnmarshal)
+	}
+
+	// ответ уже отдан, значит записи в журнале
+	cancel()
+	<-done
+
+	var audits []*repo.RPCAudit
+	for _, batch := range appender.batches {
+		audits = append(audits, batch...)
+	}
+
+	return responses, audits
+}
+
+// TestMiddlewareAuditsAbortedBatch журнал совпадает с тем, что получил провайдер: успешный вызов
+// откаченного пакета записан с ошибкой пакета, а не с ответом, которого провайдер не видел.
+func TestMiddlewareAuditsAbortedBatch(t *testing.T) {
+	responses, audits := serveAudited(
+		t,
+		`[{"jsonrpc":"2.0","id":1,"method":"ok","params":{}},{"jsonrpc":"2.0","id":2,"method":"fail","params":{}}]`,
+		true,
+	)
+
+	if len(responses) != 2 || len(audits) != 2 {
+		t.Fatalf("responses = %d, audits = %d, want 2 and 2", len(responses), len(audits))
+	}
+
+	if responses[0].Error == nil || audits[0].Response != "" || audits[0].Error != responses[0].Error.Error() {
+		t.Fatalf("aborted call audited as %q/%q, response error %v", audits[0].Response, audits[0].Error, responses[0].Error)
+	}
+
+	if !strings.Contains(audits[0].Error, "batch aborted") {
+		t.Fatalf("aborted call audited as %q", audits[0].Error)
+	}
+
+	if audits[1].Error != errBet.Error() {
+		t.Fatalf("failed call audited as %q", audits[1].Error)
+	}
+}
+
+func TestMiddlewareAuditsCommittedBatch(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		responses, audits := serveAudited(
+			t,
+			`[{"jsonrpc":"2.0","id":1,"method":"ok","params":{}},{"jsonrpc":"2.0","id":2,"method":"ok","params":{}}]`,
+			atomic,
+		)
+
+		if len(responses) != 2 || len(audits) != 2 {
+			t.Fatalf("atomic %v: responses = %d, audits = %d, want 2 and 2", atomic, len(responses), len(audits))
+		}
+
+		for i, audit := range audits {
+			if audit.Error != "" || audit.Response != `{"balance":100}` {
+				t.Fatalf("atomic %v: audit %d = %q/%q", atomic, i, audit.Response, audit.Error)
+			}
+		}
+	}
+}
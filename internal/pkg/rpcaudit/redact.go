@@ -0,0 +1,73 @@
+package rpcaudit
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const redacted = "***"
+
+// Redactor заменяет значения перечисленных полей JSON на "***" на любой глубине вложенности.
+// Имена полей сравниваются без учёта регистра.
+type Redactor struct {
+	fields map[string]struct{}
+}
+
+func NewRedactor(fields ...string) *Redactor {
+	r := &Redactor{fields: make(map[string]struct{}, len(fields))}
+
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			r.fields[strings.ToLower(field)] = struct{}{}
+		}
+	}
+
+	return r
+}
+
+// Redact возвращает raw без изменений, если редактировать нечего или это не JSON.
+func (r *Redactor) Redact(raw []byte) []byte {
+	if r == nil || len(r.fields) == 0 || len(raw) == 0 {
+		return raw
+	}
+
+	var value interface{}
+	if errUnmarshal := json.Unmarshal(raw, &value); errUnmarshal != nil {
+		return raw
+	}
+
+	if !r.redact(value) {
+		return raw
+	}
+
+	result, errMarshal := json.Marshal(value)
+	if errMarshal != nil {
+		return raw
+	}
+
+	return result
+}
+
+func (r *Redactor) redact(value interface{}) bool {
+	changed := false
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if _, ok := r.fields[strings.ToLower(key)]; ok {
+				typed[key] = redacted
+				changed = true
+
+				continue
+			}
+
+			changed = r.redact(nested) || changed
+		}
+	case []interface{}:
+		for _, nested := range typed {
+			changed = r.redact(nested) || changed
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,173 @@
+package rpcaudit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const (
+	defaultQueueSize      = 4096
+	defaultWriteBatchSize = 100
+
+	defaultRetryDelay    = 100 * time.Millisecond
+	defaultMaxRetryDelay = 5 * time.Second
+)
+
+// Appender дописывает пачку записей в цепочку, *Store.
+type Appender interface {
+	AppendBatch(ctx context.Context, audits []*repo.RPCAudit) error
+}
+
+// pending запись в очереди, done закрывается, когда она дописана в журнал.
+type pending struct {
+	audit *repo.RPCAudit
+	done  chan struct{}
+}
+
+// Writer единственный писатель журнала в процессе. Вызов ставит запись в очередь и ждёт, пока писатель
+// её допишет, а писатель дописывает накопившееся пачками: блокировка цепочки берётся раз на пачку,
+// а не на каждый вызов. Ответ уходит провайдеру только после записи в журнал, падение процесса
+// не теряет записей об уже отвеченных вызовах.
+type Writer struct {
+	appender      Appender
+	queue         chan pending
+	batchSize     int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+	logger        *zap.Logger
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+func NewWriter(appender Appender, logger *zap.Logger) *Writer {
+	return &Writer{
+		appender:      appender,
+		queue:         make(chan pending, defaultQueueSize),
+		batchSize:     defaultWriteBatchSize,
+		retryDelay:    defaultRetryDelay,
+		maxRetryDelay: defaultMaxRetryDelay,
+		logger:        logger,
+	}
+}
+
+// Write возвращается, когда запись дописана в журнал. Если писатель не успевает и очередь полна,
+// запись дописывается сразу в вызывающей горутине. После остановки Run записи тоже дописываются
+// сразу: серверы ещё дожидаются начатых вызовов.
+func (w *Writer) Write(audit *repo.RPCAudit) {
+	item := pending{audit: audit, done: make(chan struct{})}
+
+	w.mu.RLock()
+
+	queued := false
+
+	if !w.stopped {
+		select {
+		case w.queue <- item:
+			queued = true
+		default:
+		}
+	}
+
+	w.mu.RUnlock()
+
+	if !queued {
+		w.append([]pending{item})
+
+		return
+	}
+
+	<-item.done
+}
+
+// Run пишет записи до отмены ctx, затем дописывает всё, что осталось в очереди.
+func (w *Writer) Run(ctx context.Context) {
+	batch := make([]pending, 0, w.batchSize)
+
+	for {
+		select {
+		case <-ctx.Done():
+			// дальше Write в очередь не пишет, так что после drain в ней ничего не останется
+			w.mu.Lock()
+			w.stopped = true
+			w.mu.Unlock()
+
+			w.drain(batch)
+
+			return
+		case item := <-w.queue:
+			batch = append(batch[:0], item)
+			batch = w.collect(batch)
+
+			w.append(batch)
+		}
+	}
+}
+
+// collect добирает в пачку то, что уже лежит в очереди, не дожидаясь новых записей.
+func (w *Writer) collect(batch []pending) []pending {
+	for len(batch) < w.batchSize {
+		select {
+		case item := <-w.queue:
+			batch = append(batch, item)
+		default:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+func (w *Writer) drain(batch []pending) {
+	for {
+		batch = w.collect(batch[:0])
+		if len(batch) == 0 {
+			return
+		}
+
+		w.append(batch)
+	}
+}
+
+// append повторяет запись пачки, пока она не пройдёт: запись не выбрасывается, а вызовы,
+// которые её ждут, стоят, пока журнал недоступен.
+func (w *Writer) append(batch []pending) {
+	audits := make([]*repo.RPCAudit, len(batch))
+	for i, item := range batch {
+		audits[i] = item.audit
+	}
+
+	delay := w.retryDelay
+
+	// контекст свой: при остановке ctx уже отменён, а очередь нужно дописать
+	for attempt := 1; ; attempt++ {
+		errAppend := w.appender.AppendBatch(context.Background(), audits)
+		if errAppend == nil {
+			break
+		}
+
+		w.logger.Error(
+			"could not write rpc audit, retrying",
+			zap.Int("records", len(audits)),
+			zap.String("method", audits[0].Method),
+			zap.String("request_id", audits[0].RequestID),
+			zap.Int("attempt", attempt),
+			zap.Error(errAppend),
+		)
+
+		time.Sleep(delay)
+
+		if delay *= 2; delay > w.maxRetryDelay {
+			delay = w.maxRetryDelay
+		}
+	}
+
+	for _, item := range batch {
+		close(item.done)
+	}
+}
@@ -0,0 +1,239 @@
+package rpcaudit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var errAppend = errors.New("append failed")
+
+// fakeAppender первые failures вызовов отвечает ошибкой, в batches попадают только записанные пачки.
+type fakeAppender struct {
+	mu       sync.Mutex
+	batches  [][]*repo.RPCAudit
+	calls    int
+	failures int
+}
+
+func (a *fakeAppender) AppendBatch(_ context.Context, audits []*repo.RPCAudit) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.calls++
+
+	if a.calls <= a.failures {
+		return errAppend
+	}
+
+	a.batches = append(a.batches, append([]*repo.RPCAudit(nil), audits...))
+
+	return nil
+}
+
+func (a *fakeAppender) requestIDs() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var ids []string
+
+	for _, batch := range a.batches {
+		for _, audit := range batch {
+			ids = append(ids, audit.RequestID)
+		}
+	}
+
+	return ids
+}
+
+func newAudit(i int) *repo.RPCAudit {
+	return &repo.RPCAudit{Method: "withdrawAndDeposit", RequestID: strconv.Itoa(i)}
+}
+
+func assertOrder(t *testing.T, ids []string, count int) {
+	t.Helper()
+
+	if len(ids) != count {
+		t.Fatalf("got %d records, want %d", len(ids), count)
+	}
+
+	for i, id := range ids {
+		if id != strconv.Itoa(i) {
+			t.Fatalf("record %d is %s", i, id)
+		}
+	}
+}
+
+// enqueue ставит запись в очередь, как Write, но не ждёт её записи: писатель ещё не запущен.
+func enqueue(writer *Writer, audit *repo.RPCAudit) pending {
+	item := pending{audit: audit, done: make(chan struct{})}
+	writer.queue <- item
+
+	return item
+}
+
+func assertDone(t *testing.T, items []pending) {
+	t.Helper()
+
+	for i, item := range items {
+		select {
+		case <-item.done:
+		default:
+			t.Fatalf("record %d is not released after its batch was written", i)
+		}
+	}
+}
+
+func TestWriterDrainsOnCancel(t *testing.T) {
+	appender := &fakeAppender{}
+	writer := NewWriter(appender, zap.NewNop())
+	writer.batchSize = 3
+
+	var items []pending
+	for i := 0; i < 10; i++ {
+		items = append(items, enqueue(writer, newAudit(i)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer.Run(ctx)
+
+	assertOrder(t, appender.requestIDs(), 10)
+	assertDone(t, items)
+
+	for _, batch := range appender.batches {
+		if len(batch) > 3 {
+			t.Fatalf("batch of %d records, want at most 3", len(batch))
+		}
+	}
+}
+
+func TestWriterBatchesQueued(t *testing.T) {
+	appender := &fakeAppender{}
+	writer := NewWriter(appender, zap.NewNop())
+
+	for i := 0; i < 5; i++ {
+		enqueue(writer, newAudit(i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		writer.Run(ctx)
+	}()
+
+	cancel()
+	<-done
+
+	assertOrder(t, appender.requestIDs(), 5)
+
+	if len(appender.batches) != 1 {
+		t.Fatalf("got %d batches, want 1", len(appender.batches))
+	}
+}
+
+func TestWriterFullQueueAppendsInline(t *testing.T) {
+	appender := &fakeAppender{}
+	writer := NewWriter(appender, zap.NewNop())
+	writer.queue = make(chan pending, 2)
+
+	for i := 0; i < 2; i++ {
+		enqueue(writer, newAudit(i))
+	}
+
+	// третья запись не влезла в очередь и дописана сразу
+	writer.Write(newAudit(2))
+
+	if ids := appender.requestIDs(); len(ids) != 1 || ids[0] != "2" {
+		t.Fatalf("inline records %v, want [2]", ids)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer.Run(ctx)
+
+	if got := len(appender.requestIDs()); got != 3 {
+		t.Fatalf("got %d records, want 3", got)
+	}
+}
+
+// TestWriterRetriesFailedBatch пачка, которую не удалось записать, не выбрасывается.
+func TestWriterRetriesFailedBatch(t *testing.T) {
+	appender := &fakeAppender{failures: 3}
+	writer := NewWriter(appender, zap.NewNop())
+	writer.batchSize = 1
+	writer.retryDelay = time.Millisecond
+
+	items := []pending{enqueue(writer, newAudit(0)), enqueue(writer, newAudit(1))}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer.Run(ctx)
+
+	assertOrder(t, appender.requestIDs(), 2)
+	assertDone(t, items)
+
+	if appender.calls != 5 {
+		t.Fatalf("got %d append calls, want 3 failed and 2 written", appender.calls)
+	}
+}
+
+// TestWriteWaitsForAppend Write не возвращается, пока запись не дописана, даже если журнал недоступен.
+func TestWriteWaitsForAppend(t *testing.T) {
+	appender := &fakeAppender{failures: 2}
+	writer := NewWriter(appender, zap.NewNop())
+	writer.retryDelay = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		writer.Run(ctx)
+	}()
+
+	writer.Write(newAudit(0))
+
+	appender.mu.Lock()
+	calls := appender.calls
+	appender.mu.Unlock()
+
+	if calls != 3 {
+		t.Fatalf("write returned after %d append calls, want 3", calls)
+	}
+
+	assertOrder(t, appender.requestIDs(), 1)
+
+	cancel()
+	<-done
+}
+
+func TestWriterAppendsInlineAfterStop(t *testing.T) {
+	appender := &fakeAppender{}
+	writer := NewWriter(appender, zap.NewNop())
+
+	enqueue(writer, newAudit(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer.Run(ctx)
+
+	writer.Write(newAudit(1))
+
+	assertOrder(t, appender.requestIDs(), 2)
+}
@@ -0,0 +1,256 @@
+package rpcbatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/pjrpc/pjrpc/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// AtomicHeader заголовок атомарного пакета: все вызовы фиксируются вместе или не фиксируется ни один.
+	AtomicHeader = "X-Batch-Atomic"
+
+	DefaultMaxBatchSize = 20
+
+	// codeBatchAborted вызов не выполнен или откачен, потому что упал другой вызов атомарного пакета.
+	codeBatchAborted = -32001
+)
+
+// Handler обёртка над pjrpc сервером: ограничивает размер пакета и выполняет атомарные пакеты
+// в одной транзакции. Вызовы пакета pjrpc выполняет последовательно в порядке пакета.
+type Handler struct {
+	next         http.Handler
+	db           *sqlx.DB
+	logger       *zap.Logger
+	locks        *PlayerLocks
+	maxBatchSize int
+}
+
+func NewHandler(next http.Handler, db *sqlx.DB, maxBatchSize int, logger *zap.Logger) *Handler {
+	if maxBatchSize <= 0 {
+		maxBatchSize = DefaultMaxBatchSize
+	}
+
+	return &Handler{
+		next:         next,
+		db:           db,
+		logger:       logger,
+		locks:        NewPlayerLocks(),
+		maxBatchSize: maxBatchSize,
+	}
+}
+
+// Middleware держит блокировку игрока на время вызова, а в атомарном пакете
+// не даёт выполнять вызовы после первой ошибки.
+func (h *Handler) Middleware(next pjrpc.Handler) pjrpc.Handler {
+	return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		state := stateFromContext(ctx)
+		if state != nil && state.failed {
+			return nil, errBatchAborted()
+		}
+
+		if player := playerName(params); player != "" {
+			if _, locked := state.players()[player]; !locked {
+				defer h.locks.Lock(player)()
+			}
+		}
+
+		res, err := next(ctx, params)
+		if err != nil && state != nil {
+			state.failed = true
+		}
+
+		return res, err
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, errReadAll := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+
+	if errReadAll != nil {
+		writeError(w, false, pjrpc.JRPCErrParseError("failed to read body"))
+
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// одиночный вызов и всё, что не разбирается как пакет, обрабатывает pjrpc
+	var calls []json.RawMessage
+	if len(body) == 0 || body[0] != '[' || json.Unmarshal(body, &calls) != nil {
+		h.next.ServeHTTP(w, r)
+
+		return
+	}
+
+	switch {
+	case len(calls) == 0:
+		writeError(w, false, pjrpc.JRPCErrInvalidRequest("empty batch"))
+
+		return
+	case len(calls) > h.maxBatchSize:
+		writeError(w, true, pjrpc.JRPCErrInvalidRequest("batch too large"))
+
+		return
+	}
+
+	if !strings.EqualFold(r.Header.Get(AtomicHeader), "true") {
+		h.next.ServeHTTP(w, r)
+
+		return
+	}
+
+	h.serveAtomic(w, r, calls)
+}
+
+func (h *Handler) serveAtomic(w http.ResponseWriter, r *http.Request, calls []json.RawMessage) {
+	state := &batchState{lockedPlayers: make(map[string]struct{})}
+
+	for _, call := range calls {
+		var request pjrpc.Request
+		if json.Unmarshal(call, &request) != nil {
+			continue
+		}
+
+		if player := playerName(request.Params); player != "" {
+			state.lockedPlayers[player] = struct{}{}
+		}
+	}
+
+	players := make([]string, 0, len(state.lockedPlayers))
+	for player := range state.lockedPlayers {
+		players = append(players, player)
+	}
+
+	defer h.locks.LockAll(players)()
+
+	tx, errBeginTxx := h.db.BeginTxx(r.Context(), nil)
+	if errBeginTxx != nil {
+		h.logger.Error("could not begin batch transaction", zap.Error(errBeginTxx))
+		writeError(w, true, pjrpc.JRPCErrInternalError())
+
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	state.tx = tx
+
+	recorder := &bufferedWriter{header: make(http.Header), status: http.StatusOK}
+	h.next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), contextKey{}, state)))
+
+	body := recorder.body.Bytes()
+
+	var responses pjrpc.BatchResponses
+	if errUnmarshal := json.Unmarshal(body, &responses); errUnmarshal != nil {
+		// ответ pjrpc не разобрать, значит и фиксировать нечего
+		h.logger.Error("could not parse batch response", zap.Error(errUnmarshal))
+
+		_ = tx.Rollback()
+
+		runHooks(state.afterRollback)
+
+		writeError(w, true, pjrpc.JRPCErrInternalError())
+
+		return
+	}
+
+	// ошибки разбора вызова и паники в обработчике middleware не видит, поэтому проверяются ответы
+	failed := state.failed
+
+	for _, response := range responses {
+		failed = failed || response.Error != nil
+	}
+
+	if !failed {
+		if errCommit := tx.Commit(); errCommit != nil {
+			h.logger.Error("could not commit batch transaction", zap.Error(errCommit))
+
+			failed = true
+		} else {
+			runHooks(state.afterCommit)
+		}
+	}
+
+	if failed {
+		_ = tx.Rollback()
+
+		runHooks(state.afterRollback)
+
+		body = abortResponses(responses)
+	}
+
+	for key, values := range recorder.header {
+		w.Header()[key] = values
+	}
+
+	w.WriteHeader(recorder.status)
+	_, _ = w.Write(body)
+}
+
+// abortResponses заменяет успешные ответы ошибкой: их изменения откачены вместе с пакетом.
+func abortResponses(responses pjrpc.BatchResponses) []byte {
+	for _, response := range responses {
+		if response.Error == nil {
+			response.SetError(errBatchAborted())
+		}
+	}
+
+	body, _ := json.Marshal(responses) //nolint:errchkjson // обычная структура
+
+	return body
+}
+
+func errBatchAborted() *pjrpc.ErrorResponse {
+	return pjrpc.JRPCErrServerError(codeBatchAborted, "batch aborted")
+}
+
+func playerName(params json.RawMessage) string {
+	var player struct {
+		PlayerName string `json:"playerName"`
+	}
+
+	_ = json.Unmarshal(params, &player)
+
+	return player.PlayerName
+}
+
+func writeError(w http.ResponseWriter, isBatch bool, errResponse *pjrpc.ErrorResponse) {
+	response := &pjrpc.Response{JSONRPC: pjrpc.JSONRPCVersion, Error: errResponse}
+
+	var payload interface{} = response
+	if isBatch {
+		payload = pjrpc.BatchResponses{response}
+	}
+
+	body, _ := json.Marshal(payload) //nolint:errchkjson // обычная структура
+
+	w.Header().Set(pjrpc.ContentTypeHeaderName, pjrpc.ContentTypeHeaderValue)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+type bufferedWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (b *bufferedWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	return b.body.Write(p) //nolint:wrapcheck // intentional
+}
+
+func (b *bufferedWriter) WriteHeader(status int) {
+	b.status = status
+}
@@ -0,0 +1,175 @@
+package rpcbatch_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcbatch"
+)
+
+var errNoQueries = errors.New("fake driver runs no queries")
+
+// fakeDriver база без запросов: тестам нужны только Begin, Commit и Rollback.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errNoQueries }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+var registerOnce sync.Once
+
+func openDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	registerOnce.Do(func() { sql.Register("rpcbatch-fake", fakeDriver{}) })
+
+	db, errOpen := sqlx.Open("rpcbatch-fake", "")
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+type hooks struct {
+	committed, rolledBack int
+}
+
+// callsHandler вместо pjrpc: каждый вызов пакета открывает транзакцию и ставит хуки,
+// а ответ содержит ошибку, если failing.
+func callsHandler(t *testing.T, db *sqlx.DB, calls int, failing bool, got *hooks) http.Handler {
+	t.Helper()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < calls; i++ {
+			tx, errBeginTx := rpcbatch.BeginTx(r.Context(), db)
+			if errBeginTx != nil {
+				t.Errorf("begin: %v", errBeginTx)
+
+				return
+			}
+
+			tx.AfterCommit(func() { got.committed++ })
+			tx.AfterRollback(func() { got.rolledBack++ })
+
+			// внутри пакета Commit вызова ничего не фиксирует
+			if errCommit := tx.Commit(); errCommit != nil {
+				t.Errorf("commit: %v", errCommit)
+			}
+		}
+
+		response := `[{"jsonrpc":"2.0","id":1,"result":{}},{"jsonrpc":"2.0","id":2,"result":{}}]`
+		if failing {
+			response = `[{"jsonrpc":"2.0","id":1,"result":{}},{"jsonrpc":"2.0","id":2,"error":{"code":-1,"message":"x"}}]`
+		}
+
+		_, _ = w.Write([]byte(response))
+	})
+}
+
+func serveBatch(handler http.Handler) *httptest.ResponseRecorder {
+	body := `[{"jsonrpc":"2.0","id":1,"method":"a","params":{}},{"jsonrpc":"2.0","id":2,"method":"b","params":{}}]`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(rpcbatch.AtomicHeader, "true")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func TestAtomicBatchCommitRunsAfterCommit(t *testing.T) {
+	db := openDB(t)
+
+	var got hooks
+
+	serveBatch(rpcbatch.NewHandler(callsHandler(t, db, 2, false, &got), db, 0, zap.NewNop()))
+
+	if got.committed != 2 || got.rolledBack != 0 {
+		t.Fatalf("hooks = %+v, want 2 after commit and no after rollback", got)
+	}
+}
+
+func TestAtomicBatchRollbackRunsAfterRollback(t *testing.T) {
+	db := openDB(t)
+
+	var got hooks
+
+	recorder := serveBatch(rpcbatch.NewHandler(callsHandler(t, db, 2, true, &got), db, 0, zap.NewNop()))
+
+	// оба вызова прошли, но пакет откатился: их хуки отката выполняются, хуки фиксации нет
+	if got.committed != 0 || got.rolledBack != 2 {
+		t.Fatalf("hooks = %+v, want no after commit and 2 after rollback", got)
+	}
+
+	if !strings.Contains(recorder.Body.String(), "batch aborted") {
+		t.Fatalf("response = %s, want the successful call aborted", recorder.Body.String())
+	}
+}
+
+func TestTxRollbackRunsOnce(t *testing.T) {
+	db := openDB(t)
+
+	tx, errBeginTx := rpcbatch.BeginTx(context.Background(), db)
+	if errBeginTx != nil {
+		t.Fatalf("begin: %v", errBeginTx)
+	}
+
+	var got hooks
+
+	tx.AfterCommit(func() { got.committed++ })
+	tx.AfterRollback(func() { got.rolledBack++ })
+
+	_ = tx.Rollback()
+	_ = tx.Rollback()
+
+	if got.committed != 0 || got.rolledBack != 1 {
+		t.Fatalf("hooks = %+v, want one after rollback", got)
+	}
+}
+
+func TestTxRollbackAfterCommit(t *testing.T) {
+	db := openDB(t)
+
+	tx, errBeginTx := rpcbatch.BeginTx(context.Background(), db)
+	if errBeginTx != nil {
+		t.Fatalf("begin: %v", errBeginTx)
+	}
+
+	var got hooks
+
+	tx.AfterCommit(func() { got.committed++ })
+	tx.AfterRollback(func() { got.rolledBack++ })
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		t.Fatalf("commit: %v", errCommit)
+	}
+
+	// отложенный Rollback после Commit
+	_ = tx.Rollback()
+
+	if got.committed != 1 || got.rolledBack != 0 {
+		t.Fatalf("hooks = %+v, want one after commit", got)
+	}
+}
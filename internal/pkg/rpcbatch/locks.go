@@ -0,0 +1,67 @@
+package rpcbatch
+
+import (
+	"sort"
+	"sync"
+)
+
+// PlayerLocks блокировки игроков в пределах процесса: вызовы одного игрока выполняются по очереди.
+type PlayerLocks struct {
+	mu    sync.Mutex
+	locks map[string]*playerLock
+}
+
+type playerLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func NewPlayerLocks() *PlayerLocks {
+	return &PlayerLocks{locks: make(map[string]*playerLock)}
+}
+
+// Lock блокирует игрока и возвращает функцию разблокировки.
+func (l *PlayerLocks) Lock(player string) func() {
+	l.mu.Lock()
+
+	lock, ok := l.locks[player]
+	if !ok {
+		lock = &playerLock{}
+		l.locks[player] = lock
+	}
+
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if lock.refs--; lock.refs == 0 {
+			delete(l.locks, player)
+		}
+	}
+}
+
+// LockAll блокирует игроков в порядке сортировки, чтобы два пакета с общими игроками не ждали друг друга.
+func (l *PlayerLocks) LockAll(players []string) func() {
+	sorted := make([]string, len(players))
+	copy(sorted, players)
+	sort.Strings(sorted)
+
+	unlocks := make([]func(), 0, len(sorted))
+
+	for _, player := range sorted {
+		unlocks = append(unlocks, l.Lock(player))
+	}
+
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package rpcbatch
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type contextKey struct{}
+
+// batchState общее состояние атомарного пакета, вызовы пакета выполняются pjrpc последовательно.
+type batchState struct {
+	tx *sqlx.Tx
+	// failed вызов пакета вернул ошибку, остальные вызовы не выполняются
+	failed bool
+	// lockedPlayers игроки, заблокированные на весь пакет
+	lockedPlayers map[string]struct{}
+	// afterCommit выполняются после фиксации транзакции пакета, при откате отбрасываются
+	afterCommit []func()
+	// afterRollback выполняются, если пакет откатился
+	afterRollback []func()
+}
+
+func stateFromContext(ctx context.Context) *batchState {
+	state, _ := ctx.Value(contextKey{}).(*batchState)
+
+	return state
+}
+
+func (s *batchState) players() map[string]struct{} {
+	if s == nil {
+		return nil
+	}
+
+	return s.lockedPlayers
+}
+
+// Tx транзакция вызова. Внутри атомарного пакета это общая транзакция пакета, и Commit с Rollback
+// вызова ничего не делают: её фиксирует или откатывает Handler после последнего вызова.
+type Tx struct {
+	*sqlx.Tx
+	state         *batchState
+	afterCommit   []func()
+	afterRollback []func()
+	// done транзакция зафиксирована или откачена, повторный Rollback ничего не делает
+	done bool
+}
+
+// BeginTx открывает транзакцию вызова или возвращает транзакцию атомарного пакета.
+func BeginTx(ctx context.Context, db *sqlx.DB) (*Tx, error) {
+	if state := stateFromContext(ctx); state != nil {
+		return &Tx{Tx: state.tx, state: state}, nil
+	}
+
+	tx, errBeginTxx := db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return nil, errBeginTxx //nolint:wrapcheck // intentional
+	}
+
+	return &Tx{Tx: tx}, nil
+}
+
+// AfterCommit fn выполнится после фиксации транзакции, для атомарного пакета - после фиксации всего пакета.
+// При откате fn не выполняется. Так кэш не увидит изменений, которых нет в базе.
+func (t *Tx) AfterCommit(fn func()) {
+	if t.state != nil {
+		t.state.afterCommit = append(t.state.afterCommit, fn)
+
+		return
+	}
+
+	t.afterCommit = append(t.afterCommit, fn)
+}
+
+// AfterRollback fn выполнится, если транзакция откатится, для атомарного пакета - если откатится весь пакет,
+// даже когда сам вызов прошёл. Неудачный Commit тоже откат.
+func (t *Tx) AfterRollback(fn func()) {
+	if t.state != nil {
+		t.state.afterRollback = append(t.state.afterRollback, fn)
+
+		return
+	}
+
+	t.afterRollback = append(t.afterRollback, fn)
+}
+
+func (t *Tx) Commit() error {
+	if t.state != nil {
+		return nil
+	}
+
+	if errCommit := t.Tx.Commit(); errCommit != nil {
+		return errCommit //nolint:wrapcheck // intentional
+	}
+
+	t.done = true
+
+	runHooks(t.afterCommit)
+
+	return nil
+}
+
+func (t *Tx) Rollback() error {
+	if t.state != nil || t.done {
+		return nil
+	}
+
+	t.done = true
+
+	errRollback := t.Tx.Rollback()
+
+	runHooks(t.afterRollback)
+
+	return errRollback //nolint:wrapcheck // intentional
+}
+
+func runHooks(fns []func()) {
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// InBatch вызов выполняется внутри атомарного пакета и видит его ещё не зафиксированные изменения.
+func InBatch(ctx context.Context) bool {
+	return stateFromContext(ctx) != nil
+}
+
+// AfterBatch ответ вызова атомарного пакета окончательный только после последнего вызова: committed
+// выполнится после фиксации пакета, aborted - после отката с ошибкой, которую провайдер получит
+// вместо успешного ответа вызова. Вне атомарного пакета ничего не ставит и возвращает false.
+func AfterBatch(ctx context.Context, committed func(), aborted func(errAborted error)) bool {
+	state := stateFromContext(ctx)
+	if state == nil {
+		return false
+	}
+
+	state.afterCommit = append(state.afterCommit, committed)
+	state.afterRollback = append(state.afterRollback, func() { aborted(errBatchAborted()) })
+
+	return true
+}
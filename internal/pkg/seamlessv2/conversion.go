@@ -0,0 +1,136 @@
+package seamlessv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/exchangerate"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+// rateScale знаков после запятой у billing.payments.exchange_rate.
+const rateScale = 12
+
+// conversion пересчёт сумм между валютой игры и валютой кошелька по курсу, зафиксированному
+// на момент запроса. Тот же курс записывается в платёж, по нему суммы можно пересчитать заново.
+type conversion struct {
+	gameCurrency   repo.Currency
+	walletCurrency repo.Currency
+	// rate сколько единиц валюты кошелька стоит единица валюты игры
+	rate      *big.Rat
+	rateValue string
+	rateID    *int64
+}
+
+// lockConversion nil, если пересчёт группе не включён или игра работает в валюте кошелька.
+// Если прямого курса нет, используется обратный, округлённый до точности колонки.
+func lockConversion(
+	ctx context.Context,
+	tx StoreTx,
+	config WalletConfig,
+	gameCurrencyCode string,
+	walletCurrencyID int,
+	now time.Time,
+) (*conversion, error) {
+	if !config.CurrencyConversion || gameCurrencyCode == "" {
+		return nil, nil //nolint:nilnil // пересчёт не нужен
+	}
+
+	walletCurrency, errGetWalletCurrency := tx.Currencies().ByID(ctx, walletCurrencyID)
+	if errGetWalletCurrency != nil {
+		return nil, errGetWalletCurrency //nolint:wrapcheck // intentional
+	}
+
+	if walletCurrency.Code == gameCurrencyCode {
+		return nil, nil //nolint:nilnil // пересчёт не нужен
+	}
+
+	gameCurrency, errGetGameCurrency := tx.Currencies().ByCode(ctx, gameCurrencyCode)
+	if errGetGameCurrency != nil {
+		return nil, errGetGameCurrency //nolint:wrapcheck // intentional
+	}
+
+	result := &conversion{gameCurrency: *gameCurrency, walletCurrency: walletCurrency}
+
+	direct, errDirect := tx.ExchangeRates().Rate(ctx, gameCurrency.ID, walletCurrency.ID, now)
+	if errDirect != nil {
+		return nil, errDirect //nolint:wrapcheck // intentional
+	}
+
+	if direct != nil {
+		rate, errParseRate := exchangerate.ParseRate(direct.Rate)
+		if errParseRate != nil {
+			return nil, errParseRate //nolint:wrapcheck // intentional
+		}
+
+		result.rate, result.rateValue, result.rateID = rate, direct.Rate, &direct.ID
+
+		return result, nil
+	}
+
+	inverse, errInverse := tx.ExchangeRates().Rate(ctx, walletCurrency.ID, gameCurrency.ID, now)
+	if errInverse != nil {
+		return nil, errInverse //nolint:wrapcheck // intentional
+	}
+
+	if inverse == nil {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrNoExchangeRate, gameCurrency.Code, walletCurrency.Code)
+	}
+
+	rate, errParseRate := exchangerate.ParseRate(inverse.Rate)
+	if errParseRate != nil {
+		return nil, errParseRate //nolint:wrapcheck // intentional
+	}
+
+	result.rateValue = exchangerate.Invert(rate).FloatString(rateScale)
+	result.rate, _ = new(big.Rat).SetString(result.rateValue)
+	result.rateID = &inverse.ID
+
+	return result, nil
+}
+
+func (c *conversion) toWallet(amount int) int {
+	return exchangerate.Convert(
+		amount,
+		c.rate,
+		c.gameCurrency.NumberOfDigitsAfterTheDecimalSeparator,
+		c.walletCurrency.NumberOfDigitsAfterTheDecimalSeparator,
+	)
+}
+
+// winToWallet выигрыш пересчитывается с округлением вниз, как и roundWin: доли единицы остаются казино.
+func (c *conversion) winToWallet(amount int) int {
+	return exchangerate.ConvertDown(
+		amount,
+		c.rate,
+		c.gameCurrency.NumberOfDigitsAfterTheDecimalSeparator,
+		c.walletCurrency.NumberOfDigitsAfterTheDecimalSeparator,
+	)
+}
+
+func (c *conversion) toGame(amount int) int {
+	return exchangerate.Convert(
+		amount,
+		exchangerate.Invert(c.rate),
+		c.walletCurrency.NumberOfDigitsAfterTheDecimalSeparator,
+		c.gameCurrency.NumberOfDigitsAfterTheDecimalSeparator,
+	)
+}
+
+func (c *conversion) record(gameWithdraw, gameDeposit int) repo.PaymentConversion {
+	gameCurrencyID := c.gameCurrency.ID
+	rateValue := c.rateValue
+
+	return repo.PaymentConversion{
+		GameCurrencyID: &gameCurrencyID,
+		GameWithdraw:   gameWithdraw,
+		GameDeposit:    gameDeposit,
+		ExchangeRate:   &rateValue,
+		ExchangeRateID: c.rateID,
+	}
+}
@@ -16,9 +16,10 @@ import (
 
 // List of the server JSON-RPC methods.
 const (
-	JSONRPCMethodGetBalance          = "getBalance"
-	JSONRPCMethodRollbackTransaction = "withdrawAndDeposit"
-	JSONRPCMethodWithdrawAndDeposit  = "rollbackTransaction"
+	JSONRPCMethodGetBalance            = "getBalance"
+	JSONRPCMethodRollbackTransaction   = "rollbackTransaction"
+	JSONRPCMethodWithdrawAndDeposit    = "withdrawAndDeposit"
+	JSONRPCMethodGetTransactionHistory = "getTransactionHistory"
 )
 
 // SeamlessV2ServiceServer is an API server for SeamlessV2Service service.
@@ -26,6 +27,7 @@ type SeamlessV2ServiceServer interface {
 	GetBalance(ctx context.Context, in *types.GetBalanceRequest) (*types.GetBalanceResponse, error)
 	RollbackTransaction(ctx context.Context, in *types.RollbackTransactionRequest) (*types.RollbackTransactionResponse, error)
 	WithdrawAndDeposit(ctx context.Context, in *types.WithdrawAndDepositRequest) (*types.WithdrawAndDepositResponse, error)
+	GetTransactionHistory(ctx context.Context, in *types.GetTransactionHistoryRequest) (*types.GetTransactionHistoryResponse, error)
 }
 
 type regSeamlessV2Service struct {
@@ -39,6 +41,7 @@ func RegisterSeamlessV2ServiceServer(srv pjrpc.Registrator, svc SeamlessV2Servic
 	srv.RegisterMethod(JSONRPCMethodGetBalance, r.regGetBalance)
 	srv.RegisterMethod(JSONRPCMethodRollbackTransaction, r.regRollbackTransaction)
 	srv.RegisterMethod(JSONRPCMethodWithdrawAndDeposit, r.regWithdrawAndDeposit)
+	srv.RegisterMethod(JSONRPCMethodGetTransactionHistory, r.regGetTransactionHistory)
 
 	srv.With(middlewares...)
 }
@@ -90,3 +93,19 @@ func (r *regSeamlessV2Service) regWithdrawAndDeposit(ctx context.Context, params
 
 	return res, nil
 }
+
+func (r *regSeamlessV2Service) regGetTransactionHistory(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	in := new(types.GetTransactionHistoryRequest)
+	if params != nil {
+		if err := pjson.Unmarshal(params, in); err != nil {
+			return nil, pjrpc.JRPCErrParseError("failed to parse params")
+		}
+	}
+
+	res, err := r.svc.GetTransactionHistory(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("failed GetTransactionHistory: %w", err)
+	}
+
+	return res, nil
+}
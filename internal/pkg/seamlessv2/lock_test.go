@@ -0,0 +1,83 @@
+package seamlessv2_test
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/memstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+// recordingStore пишет в calls, в каком порядке транзакции блокируют игрока и читают баланс.
+type recordingStore struct {
+	*memstore.Store
+	calls *[]string
+}
+
+//nolint:ireturn // intentional
+func (s recordingStore) Begin(ctx context.Context) (seamlessv2.StoreTx, error) {
+	tx, errBegin := s.Store.Begin(ctx)
+	if errBegin != nil {
+		return nil, errBegin //nolint:wrapcheck // intentional
+	}
+
+	return recordingTx{StoreTx: tx, calls: s.calls}, nil
+}
+
+type recordingTx struct {
+	seamlessv2.StoreTx
+	calls *[]string
+}
+
+//nolint:ireturn // intentional
+func (t recordingTx) Users() seamlessv2.UserRepository {
+	return recordingUsers{UserRepository: t.StoreTx.Users(), calls: t.calls}
+}
+
+//nolint:ireturn // intentional
+func (t recordingTx) Wallets() seamlessv2.WalletRepository {
+	return recordingWallets{WalletRepository: t.StoreTx.Wallets(), calls: t.calls}
+}
+
+type recordingUsers struct {
+	seamlessv2.UserRepository
+	calls *[]string
+}
+
+func (u recordingUsers) LockByName(ctx context.Context, name string) (*repo.User, error) {
+	*u.calls = append(*u.calls, "lock")
+
+	return u.UserRepository.LockByName(ctx, name) //nolint:wrapcheck // intentional
+}
+
+type recordingWallets struct {
+	seamlessv2.WalletRepository
+	calls *[]string
+}
+
+func (w recordingWallets) Balance(ctx context.Context, userID int) (int, error) {
+	*w.calls = append(*w.calls, "balance")
+
+	return w.WalletRepository.Balance(ctx, userID) //nolint:wrapcheck // intentional
+}
+
+func TestWithdrawAndDepositLocksPlayer(t *testing.T) {
+	var calls []string
+
+	store := recordingStore{Store: memstore.New(eur, usd), calls: &calls}
+	svc := seamlessv2.NewRPCService(store, nil, seamlessv2.DefaultStartingBalance, zap.NewNop())
+	openWallet(t, svc)
+
+	calls = nil
+
+	if _, errWithdrawAndDeposit := withdrawAndDeposit(svc, "tx-1", 100, 0); errWithdrawAndDeposit != nil {
+		t.Fatalf("withdraw and deposit: %v", errWithdrawAndDeposit)
+	}
+
+	if len(calls) < 2 || calls[0] != "lock" {
+		t.Fatalf("calls = %v, want the player locked before the balance is read", calls)
+	}
+}
@@ -0,0 +1,547 @@
+// Package memstore хранилище кошельков в памяти для тестов seamlessv2 без Postgres.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var (
+	ErrNotFound                = errors.New("not found")
+	ErrDuplicateUser           = errors.New("user already exists")
+	ErrDuplicateTransactionRef = errors.New("not unique transactionRef")
+)
+
+var _ seamlessv2.Store = (*Store)(nil)
+
+// Store транзакции выполняются строго по одной: Begin ждёт, пока предыдущая транзакция
+// завершится, и работает с копией данных, которая заменяет общие данные только на Commit.
+type Store struct {
+	mu   sync.Mutex
+	data *data
+}
+
+type data struct {
+	users      []repo.User
+	payments   []repo.Payment
+	bonuses    map[string]bool
+	currencies []repo.Currency
+	bankGroups map[int]bankGroup
+	rates      []repo.ExchangeRate
+}
+
+type bankGroup struct {
+	group       repo.BankGroup
+	currencyIDs []int
+}
+
+func New(currencies ...repo.Currency) *Store {
+	return &Store{data: &data{
+		bonuses:    make(map[string]bool),
+		currencies: append([]repo.Currency(nil), currencies...),
+		bankGroups: make(map[int]bankGroup),
+	}}
+}
+
+func (d *data) clone() *data {
+	bonuses := make(map[string]bool, len(d.bonuses))
+	for bonus, used := range d.bonuses {
+		bonuses[bonus] = used
+	}
+
+	return &data{
+		users:      append([]repo.User(nil), d.users...),
+		payments:   append([]repo.Payment(nil), d.payments...),
+		bonuses:    bonuses,
+		currencies: append([]repo.Currency(nil), d.currencies...),
+		// транзакции группы не меняют, SetOperatorBankGroup заменяет map целиком
+		bankGroups: d.bankGroups,
+		rates:      d.rates,
+	}
+}
+
+//nolint:ireturn // intentional
+func (s *Store) Begin(_ context.Context) (seamlessv2.StoreTx, error) {
+	s.mu.Lock()
+
+	return &tx{store: s, data: s.data.clone()}, nil
+}
+
+// AddUser добавляет игрока как есть, например заблокированного. Пустой ID назначается автоматически.
+func (s *Store) AddUser(user repo.User) repo.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.ID == 0 {
+		user.ID = len(s.data.users) + 1
+	}
+
+	if user.Status == "" {
+		user.Status = repo.UserStatusActive
+	}
+
+	s.data.users = append(s.data.users, user)
+
+	return user
+}
+
+// SetUserStatus меняет статус уже заведённого игрока, как admin API. false - игрока нет.
+func (s *Store) SetUserStatus(name string, status repo.UserStatus, excludedUntil *time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.users {
+		if s.data.users[i].Name == name {
+			s.data.users[i].Status = status
+			s.data.users[i].ExcludedUntil = excludedUntil
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) AddBonus(bonusID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.bonuses[bonusID] = false
+}
+
+// SetOperatorBankGroup назначает оператору банковскую группу с разрешёнными валютами.
+func (s *Store) SetOperatorBankGroup(operatorID int, group repo.BankGroup, currencyIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bankGroups := make(map[int]bankGroup, len(s.data.bankGroups)+1)
+	for id, entry := range s.data.bankGroups {
+		bankGroups[id] = entry
+	}
+
+	bankGroups[operatorID] = bankGroup{group: group, currencyIDs: append([]int(nil), currencyIDs...)}
+	s.data.bankGroups = bankGroups
+}
+
+// AddExchangeRate курсы транзакциями не меняются, добавляются только здесь.
+func (s *Store) AddExchangeRate(rate repo.ExchangeRate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rate.ID = int64(len(s.data.rates) + 1)
+	s.data.rates = append(append([]repo.ExchangeRate(nil), s.data.rates...), rate)
+}
+
+// BonusUsed второй результат false, если бонуса нет.
+func (s *Store) BonusUsed(bonusID string) (used, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, ok = s.data.bonuses[bonusID]
+
+	return used, ok
+}
+
+// Payments копия всех зафиксированных платежей в порядке id.
+func (s *Store) Payments() []repo.Payment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]repo.Payment(nil), s.data.payments...)
+}
+
+type tx struct {
+	store *Store
+	data  *data
+	done  bool
+	// afterCommit выполняются после Commit, уже без блокировки хранилища
+	afterCommit []func()
+	// afterRollback выполняются после отката, тоже без блокировки
+	afterRollback []func()
+}
+
+func (t *tx) Commit() error {
+	if t.done {
+		return nil
+	}
+
+	t.store.data = t.data
+	t.done = true
+	t.store.mu.Unlock()
+
+	for _, fn := range t.afterCommit {
+		fn()
+	}
+
+	return nil
+}
+
+func (t *tx) AfterCommit(fn func()) {
+	t.afterCommit = append(t.afterCommit, fn)
+}
+
+func (t *tx) AfterRollback(fn func()) {
+	t.afterRollback = append(t.afterRollback, fn)
+}
+
+func (t *tx) Rollback() error {
+	if t.done {
+		return nil
+	}
+
+	t.done = true
+	t.store.mu.Unlock()
+
+	for _, fn := range t.afterRollback {
+		fn()
+	}
+
+	return nil
+}
+
+//nolint:ireturn // intentional
+func (t *tx) Users() seamlessv2.UserRepository { return users{t.data} }
+
+//nolint:ireturn // intentional
+func (t *tx) Wallets() seamlessv2.WalletRepository { return wallets{t.data} }
+
+//nolint:ireturn // intentional
+func (t *tx) Payments() seamlessv2.PaymentRepository { return payments{t.data} }
+
+//nolint:ireturn // intentional
+func (t *tx) Bonuses() seamlessv2.BonusRepository { return bonuses{t.data} }
+
+//nolint:ireturn // intentional
+func (t *tx) Currencies() seamlessv2.CurrencyRepository { return currencies{t.data} }
+
+//nolint:ireturn // intentional
+func (t *tx) BankGroups() seamlessv2.BankGroupRepository { return bankGroups{t.data} }
+
+//nolint:ireturn // intentional
+func (t *tx) ExchangeRates() seamlessv2.ExchangeRateRepository { return exchangeRates{t.data} }
+
+type users struct {
+	data *data
+}
+
+func (u users) FindByName(_ context.Context, name string) (*repo.User, error) {
+	for i := range u.data.users {
+		if u.data.users[i].Name == name {
+			user := u.data.users[i]
+
+			return &user, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// LockByName транзакции хранилища и так идут по одной.
+func (u users) LockByName(ctx context.Context, name string) (*repo.User, error) {
+	return u.FindByName(ctx, name)
+}
+
+func (u users) Create(ctx context.Context, name string, operatorID int) (*repo.User, error) {
+	if _, errFindByName := u.FindByName(ctx, name); errFindByName == nil {
+		return nil, ErrDuplicateUser
+	}
+
+	now := time.Now().UTC()
+	user := repo.User{
+		ID:         len(u.data.users) + 1,
+		CreatedAt:  now,
+		Name:       name,
+		Status:     repo.UserStatusActive,
+		UpdatedAt:  now,
+		OperatorID: &operatorID,
+	}
+
+	u.data.users = append(u.data.users, user)
+
+	return &user, nil
+}
+
+type wallets struct {
+	data *data
+}
+
+func (w wallets) Balance(_ context.Context, userID int) (int, error) {
+	var balance int
+
+	for _, payment := range w.data.payments {
+		if payment.UserID == userID && payment.RollBackAt == nil {
+			balance += payment.Deposit - payment.Withdraw
+		}
+	}
+
+	return balance, nil
+}
+
+func (w wallets) CurrencyID(_ context.Context, userID int) (int, error) {
+	for _, payment := range w.data.payments {
+		if payment.UserID == userID {
+			return payment.CurrencyID, nil
+		}
+	}
+
+	return -1, nil
+}
+
+type payments struct {
+	data *data
+}
+
+func (p payments) Create(
+	_ context.Context,
+	userID, currencyID, withdraw, deposit int,
+	transactionRef string,
+	paymentContext repo.PaymentContext,
+) (*repo.Payment, error) {
+	if len(paymentContext.Extras) == 0 {
+		paymentContext.Extras = []byte("{}")
+	}
+
+	now := time.Now().UTC()
+	payment := repo.Payment{
+		ID:             len(p.data.payments) + 1,
+		CreatedAt:      &now,
+		UserID:         userID,
+		CurrencyID:     currencyID,
+		Withdraw:       withdraw,
+		Deposit:        deposit,
+		TransactionRef: transactionRef,
+		PaymentContext: paymentContext,
+	}
+
+	p.data.payments = append(p.data.payments, payment)
+
+	return &payment, nil
+}
+
+func (p payments) CheckUniqueRef(_ context.Context, transactionRef string) error {
+	for _, payment := range p.data.payments {
+		if payment.TransactionRef == transactionRef {
+			return ErrDuplicateTransactionRef
+		}
+	}
+
+	return nil
+}
+
+func (p payments) Rollback(_ context.Context, transactionRef string) ([]repo.Payment, error) {
+	var (
+		rolledBack []repo.Payment
+		found      bool
+	)
+
+	now := time.Now().UTC()
+
+	for i := range p.data.payments {
+		if p.data.payments[i].TransactionRef != transactionRef {
+			continue
+		}
+
+		found = true
+
+		if p.data.payments[i].RollBackAt == nil {
+			p.data.payments[i].RollBackAt = &now
+			rolledBack = append(rolledBack, p.data.payments[i])
+		}
+	}
+
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return rolledBack, nil
+}
+
+// filtered платежи игрока под фильтром, новые сверху.
+func (p payments) filtered(filter repo.PaymentHistoryFilter) []repo.Payment {
+	var result []repo.Payment
+
+	for _, payment := range p.data.payments {
+		if payment.UserID == filter.UserID && matches(filter, &payment) {
+			result = append(result, payment)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+
+	return result
+}
+
+func matches(filter repo.PaymentHistoryFilter, payment *repo.Payment) bool {
+	switch {
+	case filter.From != nil && payment.CreatedAt.Before(*filter.From),
+		filter.To != nil && !payment.CreatedAt.Before(*filter.To),
+		filter.GameID != "" && payment.GameID != filter.GameID,
+		filter.GameRoundRef != "" && payment.GameRoundRef != filter.GameRoundRef,
+		filter.Reason != "" && payment.Reason != filter.Reason,
+		filter.RolledBack != nil && (payment.RollBackAt != nil) != *filter.RolledBack:
+		return false
+	default:
+		return true
+	}
+}
+
+// History как repo.GetPaymentHistory: сначала страница, затем баланс до её первой записи
+// и нарастающий итог по всем платежам игрока в границах страницы.
+func (p payments) History(
+	_ context.Context,
+	filter repo.PaymentHistoryFilter,
+	limit int,
+) ([]repo.PaymentHistoryRow, error) {
+	var rows []repo.PaymentHistoryRow
+
+	for _, payment := range p.filtered(filter) {
+		if len(rows) == limit {
+			break
+		}
+
+		if filter.BeforeID == 0 || payment.ID < filter.BeforeID {
+			rows = append(rows, repo.PaymentHistoryRow{Payment: payment})
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, nil
+	}
+
+	firstID, lastID := rows[len(rows)-1].ID, rows[0].ID
+	balances := make(map[int]int, len(rows))
+
+	var balance int
+
+	for _, payment := range p.data.payments {
+		if payment.UserID != filter.UserID || payment.ID > lastID {
+			continue
+		}
+
+		if payment.RollBackAt == nil {
+			balance += payment.Deposit - payment.Withdraw
+		}
+
+		if payment.ID >= firstID {
+			balances[payment.ID] = balance
+		}
+	}
+
+	for i := range rows {
+		rows[i].Balance = balances[rows[i].ID]
+	}
+
+	return rows, nil
+}
+
+func (p payments) HistoryTotals(
+	_ context.Context,
+	filter repo.PaymentHistoryFilter,
+) (repo.PaymentHistoryTotals, error) {
+	var totals repo.PaymentHistoryTotals
+
+	for _, payment := range p.filtered(filter) {
+		totals.Count++
+
+		if payment.RollBackAt == nil {
+			totals.Withdraw += payment.Withdraw
+			totals.Deposit += payment.Deposit
+		}
+	}
+
+	return totals, nil
+}
+
+type bonuses struct {
+	data *data
+}
+
+func (b bonuses) Write(_ context.Context, bonusID string) error {
+	if _, ok := b.data.bonuses[bonusID]; !ok {
+		b.data.bonuses[bonusID] = false
+	}
+
+	return nil
+}
+
+func (b bonuses) Use(_ context.Context, bonusID string) error {
+	if _, ok := b.data.bonuses[bonusID]; !ok {
+		return ErrNotFound
+	}
+
+	b.data.bonuses[bonusID] = true
+
+	return nil
+}
+
+type currencies struct {
+	data *data
+}
+
+func (c currencies) ByID(_ context.Context, id int) (repo.Currency, error) {
+	for _, currency := range c.data.currencies {
+		if currency.ID == id {
+			return currency, nil
+		}
+	}
+
+	return repo.Currency{}, ErrNotFound
+}
+
+func (c currencies) ByCode(_ context.Context, code string) (*repo.Currency, error) {
+	for i := range c.data.currencies {
+		if c.data.currencies[i].Code == code {
+			currency := c.data.currencies[i]
+
+			return &currency, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+type bankGroups struct {
+	data *data
+}
+
+func (b bankGroups) ByOperator(_ context.Context, operatorID int) (*repo.BankGroup, []int, error) {
+	entry, ok := b.data.bankGroups[operatorID]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	group := entry.group
+
+	return &group, append([]int(nil), entry.currencyIDs...), nil
+}
+
+type exchangeRates struct {
+	data *data
+}
+
+func (e exchangeRates) Rate(
+	_ context.Context,
+	fromCurrencyID, toCurrencyID int,
+	at time.Time,
+) (*repo.ExchangeRate, error) {
+	var found *repo.ExchangeRate
+
+	for i := range e.data.rates {
+		rate := e.data.rates[i]
+		if rate.FromCurrencyID != fromCurrencyID || rate.ToCurrencyID != toCurrencyID || rate.EffectiveAt.After(at) {
+			continue
+		}
+
+		if found == nil || rate.EffectiveAt.After(found.EffectiveAt) {
+			found = &rate
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,33 @@
+package seamlessv2
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var (
+	ErrPlayerClosed       = errors.New("player account is closed")
+	ErrPlayerBetsDisabled = errors.New("player is not allowed to place bets")
+)
+
+// checkBalanceAllowed закрытому аккаунту баланс не отдаётся, остальные статусы
+// могут смотреть баланс, чтобы провайдер мог доиграть открытые раунды.
+func checkBalanceAllowed(user *repo.User, now time.Time) error {
+	if user.EffectiveStatus(now) == repo.UserStatusClosed {
+		return ErrPlayerClosed
+	}
+
+	return nil
+}
+
+// checkBetAllowed ставки принимаются только от активных игроков.
+// Выигрыши и откаты разрешены при любом статусе, иначе не закрыть уже начатые раунды.
+func checkBetAllowed(user *repo.User, now time.Time) error {
+	if user.EffectiveStatus(now) != repo.UserStatusActive {
+		return ErrPlayerBetsDisabled
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package seamlessv2
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcbatch"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var _ Store = (*PostgresStore)(nil)
+
+// PostgresStore Store поверх функций пакета repo. Внутри атомарного пакета все вызовы
+// получают общую транзакцию пакета, см. rpcbatch.BeginTx.
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+//nolint:ireturn // intentional
+func (s *PostgresStore) Begin(ctx context.Context) (StoreTx, error) {
+	tx, errBeginTx := rpcbatch.BeginTx(ctx, s.db)
+	if errBeginTx != nil {
+		return nil, errBeginTx //nolint:wrapcheck // intentional
+	}
+
+	return &postgresTx{Tx: tx}, nil
+}
+
+type postgresTx struct {
+	*rpcbatch.Tx
+}
+
+//nolint:ireturn // intentional
+func (t *postgresTx) Users() UserRepository { return postgresUsers{t.Tx.Tx} }
+
+//nolint:ireturn // intentional
+func (t *postgresTx) Wallets() WalletRepository { return postgresWallets{t.Tx.Tx} }
+
+//nolint:ireturn // intentional
+func (t *postgresTx) Payments() PaymentRepository { return postgresPayments{t.Tx.Tx} }
+
+//nolint:ireturn // intentional
+func (t *postgresTx) Bonuses() BonusRepository { return postgresBonuses{t.Tx.Tx} }
+
+//nolint:ireturn // intentional
+func (t *postgresTx) Currencies() CurrencyRepository { return postgresCurrencies{t.Tx.Tx} }
+
+//nolint:ireturn // intentional
+func (t *postgresTx) BankGroups() BankGroupRepository { return postgresBankGroups{t.Tx.Tx} }
+
+//nolint:ireturn // intentional
+func (t *postgresTx) ExchangeRates() ExchangeRateRepository { return postgresExchangeRates{t.Tx.Tx} }
+
+type postgresUsers struct {
+	tx *sqlx.Tx
+}
+
+func (u postgresUsers) FindByName(ctx context.Context, name string) (*repo.User, error) {
+	return repo.FindUserByName(ctx, u.tx, name) //nolint:wrapcheck // intentional
+}
+
+func (u postgresUsers) LockByName(ctx context.Context, name string) (*repo.User, error) {
+	return repo.LockUserByName(ctx, u.tx, name) //nolint:wrapcheck // intentional
+}
+
+func (u postgresUsers) Create(ctx context.Context, name string, operatorID int) (*repo.User, error) {
+	return repo.NewUser(ctx, u.tx, name, operatorID) //nolint:wrapcheck // intentional
+}
+
+type postgresWallets struct {
+	tx *sqlx.Tx
+}
+
+func (w postgresWallets) Balance(ctx context.Context, userID int) (int, error) {
+	return repo.GetDepositByUserID(ctx, w.tx, userID) //nolint:wrapcheck // intentional
+}
+
+func (w postgresWallets) CurrencyID(ctx context.Context, userID int) (int, error) {
+	return repo.GetCurrencyID(ctx, w.tx, userID) //nolint:wrapcheck // intentional
+}
+
+type postgresPayments struct {
+	tx *sqlx.Tx
+}
+
+func (p postgresPayments) Create(
+	ctx context.Context,
+	userID, currencyID, withdraw, deposit int,
+	transactionRef string,
+	paymentContext repo.PaymentContext,
+) (*repo.Payment, error) {
+	return repo.NewPayment( //nolint:wrapcheck // intentional
+		repo.WithOperatorCall(ctx),
+		p.tx,
+		userID,
+		currencyID,
+		withdraw,
+		deposit,
+		transactionRef,
+		paymentContext,
+	)
+}
+
+func (p postgresPayments) CheckUniqueRef(ctx context.Context, transactionRef string) error {
+	return repo.CheckUniqueTransactionRef(ctx, p.tx, transactionRef) //nolint:wrapcheck // intentional
+}
+
+func (p postgresPayments) Rollback(ctx context.Context, transactionRef string) ([]repo.Payment, error) {
+	return repo.RollbackPayment(repo.WithOperatorCall(ctx), p.tx, transactionRef) //nolint:wrapcheck // intentional
+}
+
+func (p postgresPayments) History(
+	ctx context.Context,
+	filter repo.PaymentHistoryFilter,
+	limit int,
+) ([]repo.PaymentHistoryRow, error) {
+	return repo.GetPaymentHistory(ctx, p.tx, filter, limit) //nolint:wrapcheck // intentional
+}
+
+func (p postgresPayments) HistoryTotals(
+	ctx context.Context,
+	filter repo.PaymentHistoryFilter,
+) (repo.PaymentHistoryTotals, error) {
+	return repo.GetPaymentHistoryTotals(ctx, p.tx, filter) //nolint:wrapcheck // intentional
+}
+
+type postgresBonuses struct {
+	tx *sqlx.Tx
+}
+
+func (b postgresBonuses) Write(ctx context.Context, bonusID string) error {
+	return repo.WriteBonus(ctx, b.tx, bonusID) //nolint:wrapcheck // intentional
+}
+
+func (b postgresBonuses) Use(ctx context.Context, bonusID string) error {
+	return repo.UseBonus(ctx, b.tx, bonusID) //nolint:wrapcheck // intentional
+}
+
+type postgresCurrencies struct {
+	tx *sqlx.Tx
+}
+
+func (c postgresCurrencies) ByID(ctx context.Context, id int) (repo.Currency, error) {
+	return repo.GetCurrencyByID(ctx, c.tx, id) //nolint:wrapcheck // intentional
+}
+
+func (c postgresCurrencies) ByCode(ctx context.Context, code string) (*repo.Currency, error) {
+	return repo.GetCurrencyByCode(ctx, c.tx, code) //nolint:wrapcheck // intentional
+}
+
+type postgresBankGroups struct {
+	tx *sqlx.Tx
+}
+
+func (b postgresBankGroups) ByOperator(ctx context.Context, operatorID int) (*repo.BankGroup, []int, error) {
+	bankGroup, errGetBankGroupByOperatorID := repo.GetBankGroupByOperatorID(ctx, b.tx, operatorID)
+	if errGetBankGroupByOperatorID != nil || bankGroup == nil {
+		return nil, nil, errGetBankGroupByOperatorID //nolint:wrapcheck // intentional
+	}
+
+	currencyIDs, errGetBankGroupCurrencyIDs := repo.GetBankGroupCurrencyIDs(ctx, b.tx, bankGroup.ID)
+	if errGetBankGroupCurrencyIDs != nil {
+		return nil, nil, errGetBankGroupCurrencyIDs //nolint:wrapcheck // intentional
+	}
+
+	return bankGroup, currencyIDs, nil
+}
+
+type postgresExchangeRates struct {
+	tx *sqlx.Tx
+}
+
+func (e postgresExchangeRates) Rate(
+	ctx context.Context,
+	fromCurrencyID, toCurrencyID int,
+	at time.Time,
+) (*repo.ExchangeRate, error) {
+	return repo.GetExchangeRate(ctx, e.tx, fromCurrencyID, toCurrencyID, at) //nolint:wrapcheck // intentional
+}
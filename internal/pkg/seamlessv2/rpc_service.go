@@ -2,48 +2,97 @@ package seamlessv2
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
-	"github.com/jmoiron/sqlx"
-	"github.com/opentracing/opentracing-go"
 	"go.uber.org/zap"
 
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/idempotency"
 	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
 	"github.com/rinatusmanov/jsonrpc20/internal/repo"
 )
 
 type RPCService struct {
-	db     *sqlx.DB
-	logger *zap.Logger
+	store Store
+	// keys захват transactionRef на время выполнения, nil - дубли ловит только проверка в базе
+	keys *idempotency.Keys
+	// startingBalance стартовый баланс игроков операторов без банковской группы
+	startingBalance int
+	logger          *zap.Logger
 }
 
+// InitialDepositRef transactionRef платежа со стартовым балансом нового кошелька.
+const InitialDepositRef = "init"
+
 var (
-	ErrNoFreeCurrency       = errors.New("no free currency")
-	ErrConflictOfCurrencies = errors.New("conflict of currencies")
+	ErrNoFreeCurrency          = errors.New("no free currency")
+	ErrConflictOfCurrencies    = errors.New("conflict of currencies")
+	ErrCurrencyDisabled        = errors.New("currency is disabled")
+	ErrDuplicateTransactionRef = errors.New("not unique transactionRef")
+	ErrTransactionInFlight     = errors.New("transaction with the same transactionRef is in progress")
 )
 
+// checkCurrencyEnabled не даёт работать с кошельком в отключённой валюте (например SLL после перехода на SLE).
+func checkCurrencyEnabled(currency repo.Currency) error {
+	if currency.Disabled {
+		return fmt.Errorf("%w: %s", ErrCurrencyDisabled, currency.Code)
+	}
+
+	return nil
+}
+
+// checkWalletCurrency без пересчёта провайдер должен работать в валюте кошелька, иначе суммы
+// в чужой валюте молча записались бы как есть. Пустая валюта в запросе - валюта кошелька.
+func checkWalletCurrency(wallet repo.Currency, code string) error {
+	if code != "" && code != wallet.Code {
+		return fmt.Errorf("%w: %s, wallet in %s", ErrConflictOfCurrencies, code, wallet.Code)
+	}
+
+	return nil
+}
+
 // GetBalance проигнорировал очень много полей так как вообще не понимаю их сути.
 func (r *RPCService) GetBalance(
 	ctx context.Context,
 	in *types.GetBalanceRequest,
 ) (*types.GetBalanceResponse, error) {
-	tx, _ := r.db.BeginTxx(ctx, nil)
-	defer tx.Commit() //nolint:errcheck // intentional
+	tx, errBeginTx := r.store.Begin(ctx)
+	if errBeginTx != nil {
+		return nil, errBeginTx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
 
-	span := opentracing.SpanFromContext(ctx)
-	span.SetTag("method", "GetBalance")
+	config, errWalletConfig := r.walletConfig(ctx, tx, in.CallerID)
+	if errWalletConfig != nil {
+		return nil, errWalletConfig
+	}
+
+	if errCheckFreeRounds := config.checkFreeRounds(in.BonusID, 0); errCheckFreeRounds != nil {
+		return nil, errCheckFreeRounds
+	}
 
-	user, errFindUserByName := repo.FindUserByName(ctx, tx, in.PlayerName)
+	user, errFindUserByName := tx.Users().FindByName(ctx, in.PlayerName)
 	if errFindUserByName != nil {
-		return r.newUser(ctx, in, tx)
+		response, errNewUser := r.newUser(ctx, in, tx, config)
+		if errNewUser != nil {
+			return nil, errNewUser
+		}
+
+		return response, tx.Commit() //nolint:wrapcheck // intentional
+	}
+
+	if errCheckBalanceAllowed := checkBalanceAllowed(user, time.Now()); errCheckBalanceAllowed != nil {
+		return nil, errCheckBalanceAllowed
 	}
 
-	currencyID, errGetCurrencyID := repo.GetCurrencyID(ctx, tx, user.ID)
+	currencyID, errGetCurrencyID := tx.Wallets().CurrencyID(ctx, user.ID)
 	if errGetCurrencyID != nil {
 		return nil, errGetCurrencyID //nolint:wrapcheck // intentional
 	}
 
-	currency, errGetCurrencyByID := repo.GetCurrencyByID(ctx, tx, currencyID)
+	currency, errGetCurrencyByID := tx.Currencies().ByID(ctx, currencyID)
 	if errGetCurrencyByID != nil {
 		return nil, errGetCurrencyByID //nolint:wrapcheck // intentional
 	}
@@ -52,41 +101,86 @@ func (r *RPCService) GetBalance(
 		return nil, ErrConflictOfCurrencies
 	}
 
+	if errCheckCurrencyEnabled := checkCurrencyEnabled(currency); errCheckCurrencyEnabled != nil {
+		return nil, errCheckCurrencyEnabled
+	}
+
+	if errCheckCurrency := config.checkCurrency(currency.ID, currency.Code); errCheckCurrency != nil {
+		return nil, errCheckCurrency
+	}
+
 	if in.BonusID != "" {
-		if errWriteBonus := repo.WriteBonus(ctx, tx, in.BonusID); errWriteBonus != nil {
+		if errWriteBonus := tx.Bonuses().Write(ctx, in.BonusID); errWriteBonus != nil {
 			return nil, errWriteBonus //nolint:wrapcheck // intentional
 		}
 	}
 
-	balance, errGetDepositByUserID := repo.GetDepositByUserID(ctx, tx, user.ID)
+	balance, errGetDepositByUserID := tx.Wallets().Balance(ctx, user.ID)
 	if errGetDepositByUserID != nil {
 		return nil, errGetDepositByUserID //nolint:wrapcheck // intentional
 	}
 
+	conv, errLockConversion := lockConversion(ctx, tx, config, in.Currency, currencyID, time.Now())
+	if errLockConversion != nil {
+		return nil, errLockConversion
+	}
+
+	if conv != nil {
+		balance = conv.toGame(balance)
+	} else if errCheckWalletCurrency := checkWalletCurrency(currency, in.Currency); errCheckWalletCurrency != nil {
+		return nil, errCheckWalletCurrency
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
 	return &types.GetBalanceResponse{Balance: balance, FreeRoundsLeft: 0}, nil
 }
 
 func (r *RPCService) newUser(
 	ctx context.Context,
 	in *types.GetBalanceRequest,
-	tx *sqlx.Tx,
+	tx StoreTx,
+	config WalletConfig,
 ) (*types.GetBalanceResponse, error) {
 	var (
 		errNewUser error
 		user       *repo.User
 	)
 
-	if user, errNewUser = repo.NewUser(ctx, tx, in.PlayerName); errNewUser != nil {
+	if user, errNewUser = tx.Users().Create(ctx, in.PlayerName, in.CallerID); errNewUser != nil {
 		return nil, errNewUser //nolint:wrapcheck // intentional
 	}
 
-	currency, errGetCurrencyByCode := repo.GetCurrencyByCode(ctx, tx, in.Currency)
+	currency, errGetCurrencyByCode := tx.Currencies().ByCode(ctx, in.Currency)
 	if errGetCurrencyByCode != nil {
 		return nil, errGetCurrencyByCode //nolint:wrapcheck // intentional
 	}
 
-	const balance = 10000
-	if _, errNewPayment := repo.NewPayment(ctx, tx, user.ID, currency.ID, 0, balance, "init"); errNewPayment != nil {
+	if errCheckCurrencyEnabled := checkCurrencyEnabled(*currency); errCheckCurrencyEnabled != nil {
+		return nil, errCheckCurrencyEnabled
+	}
+
+	if errCheckCurrency := config.checkCurrency(currency.ID, currency.Code); errCheckCurrency != nil {
+		return nil, errCheckCurrency
+	}
+
+	balance := config.StartingBalance
+	if _, errNewPayment := tx.Payments().Create(
+		ctx,
+		user.ID,
+		currency.ID,
+		0,
+		balance,
+		InitialDepositRef,
+		repo.PaymentContext{
+			GameID:    in.GameID,
+			SessionID: in.SessionID,
+			CallerID:  in.CallerID,
+			BonusID:   in.BonusID,
+		},
+	); errNewPayment != nil {
 		return nil, errNewPayment //nolint:wrapcheck // intentional
 	}
 
@@ -100,67 +194,240 @@ func (r *RPCService) RollbackTransaction(
 	ctx context.Context,
 	in *types.RollbackTransactionRequest,
 ) (*types.RollbackTransactionResponse, error) {
-	tx, _ := r.db.BeginTxx(ctx, nil)
-	defer tx.Commit() //nolint:errcheck // intentional
+	ctx = WithBalanceUpdate(ctx)
 
-	return &types.RollbackTransactionResponse{}, repo.RollbackPayment( //nolint:wrapcheck // intentional
-		ctx,
-		tx,
-		in.TransactionRef,
-	)
+	tx, errBeginTx := r.store.Begin(ctx)
+	if errBeginTx != nil {
+		return nil, errBeginTx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	// статус игрока не проверяется: откат разрешён даже заблокированным игрокам.
+	if _, errRollbackPayment := tx.Payments().Rollback(ctx, in.TransactionRef); errRollbackPayment != nil {
+		return nil, errRollbackPayment //nolint:wrapcheck // intentional
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	return &types.RollbackTransactionResponse{}, nil
 }
 
 func (r *RPCService) WithdrawAndDeposit(
 	ctx context.Context,
 	in *types.WithdrawAndDepositRequest,
 ) (*types.WithdrawAndDepositResponse, error) {
-	tx, _ := r.db.BeginTxx(ctx, nil)
-	defer tx.Commit() //nolint:errcheck // intentional
+	// transactionRef захватывается до транзакции: параллельный дубль ждёт или получает ошибку,
+	// а не проходит проверку в базе одновременно с оригиналом
+	claim, errClaim := r.keys.Claim(ctx, "withdraw-and-deposit:"+in.TransactionRef, withdrawAndDepositFingerprint(in))
+
+	var completed *idempotency.CompletedError
+
+	switch {
+	case errors.As(errClaim, &completed):
+		// повтор уже выполненного запроса получает ответ оригинала
+		var response types.WithdrawAndDepositResponse
+		if completed.Response == nil || json.Unmarshal(completed.Response, &response) != nil {
+			return nil, ErrDuplicateTransactionRef
+		}
+
+		return &response, nil
+	case errors.Is(errClaim, idempotency.ErrMismatch):
+		// тот же transactionRef с другой ставкой - не повтор, ответ оригинала ему не отдаётся
+		return nil, ErrDuplicateTransactionRef
+	case errors.Is(errClaim, idempotency.ErrInFlight):
+		return nil, ErrTransactionInFlight
+	case errClaim != nil:
+		return nil, errClaim //nolint:wrapcheck // intentional
+	}
+
+	response, errWithdrawAndDeposit := r.withdrawAndDeposit(WithBalanceUpdate(ctx), in, claim)
+	if errWithdrawAndDeposit != nil {
+		claim.Release()
+	}
+
+	return response, errWithdrawAndDeposit
+}
+
+// withdrawAndDepositFingerprint параметры, по которым повтор отличается от другого запроса с тем же transactionRef.
+func withdrawAndDepositFingerprint(in *types.WithdrawAndDepositRequest) string {
+	return fmt.Sprintf("%q:%d:%d:%q", in.PlayerName, in.Withdraw, in.Deposit, in.Currency)
+}
+
+// withdrawAndDeposit итог в захвате сохраняется только после фиксации, внутри атомарного пакета -
+// после фиксации всего пакета. Если пакет откатится, захват снимается, и провайдер может повторить вызов.
+func (r *RPCService) withdrawAndDeposit(
+	ctx context.Context,
+	in *types.WithdrawAndDepositRequest,
+	claim *idempotency.Claim,
+) (*types.WithdrawAndDepositResponse, error) {
+	tx, errBeginTx := r.store.Begin(ctx)
+	if errBeginTx != nil {
+		return nil, errBeginTx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	tx.AfterRollback(claim.Release)
+
+	config, errWalletConfig := r.walletConfig(ctx, tx, in.CallerID)
+	if errWalletConfig != nil {
+		return nil, errWalletConfig
+	}
+
+	if errCheckFreeRounds := config.checkFreeRounds(in.BonusID, in.ChargeFreeRounds); errCheckFreeRounds != nil {
+		return nil, errCheckFreeRounds
+	}
 
 	if in.BonusID != "" {
-		if errUseBonus := repo.UseBonus(ctx, tx, in.BonusID); errUseBonus != nil {
+		if errUseBonus := tx.Bonuses().Use(ctx, in.BonusID); errUseBonus != nil {
 			return nil, errUseBonus //nolint:wrapcheck // intentional
 		}
 	}
 
-	user, errFindUserByName := repo.FindUserByName(ctx, tx, in.PlayerName)
-	if errFindUserByName != nil {
-		return nil, errFindUserByName //nolint:wrapcheck // intentional
+	// блокировка до чтения баланса: ставка и списание из админки не пройдут проверку баланса вместе
+	user, errLockUserByName := tx.Users().LockByName(ctx, in.PlayerName)
+	if errLockUserByName != nil {
+		return nil, errLockUserByName //nolint:wrapcheck // intentional
+	}
+
+	now := time.Now()
+
+	if in.Withdraw > 0 {
+		if errCheckBetAllowed := checkBetAllowed(user, now); errCheckBetAllowed != nil {
+			return nil, errCheckBetAllowed
+		}
+	}
+
+	currencyID, errGetCurrencyID := tx.Wallets().CurrencyID(ctx, user.ID)
+	if errGetCurrencyID != nil {
+		return nil, errGetCurrencyID //nolint:wrapcheck // intentional
+	}
+
+	if errCheckCurrency := config.checkCurrency(currencyID, in.Currency); errCheckCurrency != nil {
+		return nil, errCheckCurrency
+	}
+
+	conv, errLockConversion := lockConversion(ctx, tx, config, in.Currency, currencyID, now)
+	if errLockConversion != nil {
+		return nil, errLockConversion
 	}
 
-	deposit, errGetDepositByUserID := repo.GetDepositByUserID(ctx, tx, user.ID)
+	withdraw, deposit := in.Withdraw, in.Deposit
+	if conv != nil {
+		withdraw, deposit = config.round(conv.toWallet(in.Withdraw)), conv.winToWallet(in.Deposit)
+	} else {
+		walletCurrency, errGetCurrencyByID := tx.Currencies().ByID(ctx, currencyID)
+		if errGetCurrencyByID != nil {
+			return nil, errGetCurrencyByID //nolint:wrapcheck // intentional
+		}
+
+		if errCheckWalletCurrency := checkWalletCurrency(walletCurrency, in.Currency); errCheckWalletCurrency != nil {
+			return nil, errCheckWalletCurrency
+		}
+	}
+
+	if errCheckBet := config.checkBet(withdraw); errCheckBet != nil {
+		return nil, errCheckBet
+	}
+
+	var originalDeposit int
+	if rounded := config.roundWin(deposit); rounded != deposit {
+		originalDeposit, deposit = deposit, rounded
+	}
+
+	balance, errGetDepositByUserID := tx.Wallets().Balance(ctx, user.ID)
 	if errGetDepositByUserID != nil {
 		return nil, errGetDepositByUserID //nolint:wrapcheck // intentional
 	}
 
-	newBalance := deposit + in.Deposit - in.Withdraw
+	newBalance := balance + deposit - withdraw
 	if newBalance < 0 {
 		return nil, ErrNoFreeCurrency
 	}
 
-	if errCheckUniqueTransactionRef := repo.CheckUniqueTransactionRef(
+	if errCheckUniqueTransactionRef := tx.Payments().CheckUniqueRef(
 		ctx,
-		tx,
 		in.TransactionRef,
 	); errCheckUniqueTransactionRef != nil {
 		return nil, errCheckUniqueTransactionRef //nolint:wrapcheck // intentional
 	}
 
-	_, errNewPayment := repo.NewPayment(ctx, tx, user.ID, 1, deposit, in.Withdraw, in.TransactionRef)
+	paymentContext, errWithdrawAndDepositContext := withdrawAndDepositContext(in, originalDeposit)
+	if errWithdrawAndDepositContext != nil {
+		return nil, errWithdrawAndDepositContext
+	}
+
+	if conv != nil {
+		paymentContext.PaymentConversion = conv.record(in.Withdraw, in.Deposit)
+		// провайдер ведёт баланс в валюте игры
+		newBalance = conv.toGame(newBalance)
+	}
+
+	_, errNewPayment := tx.Payments().Create(
+		ctx,
+		user.ID,
+		currencyID,
+		withdraw,
+		deposit,
+		in.TransactionRef,
+		paymentContext,
+	)
 	if errNewPayment != nil {
 		return nil, errNewPayment //nolint:wrapcheck // intentional
 	}
 
-	return &types.WithdrawAndDepositResponse{
+	response := &types.WithdrawAndDepositResponse{
 		NewBalance:     newBalance,
 		TransactionID:  in.TransactionRef,
 		FreeRoundsLeft: 0,
+	}
+
+	tx.AfterCommit(func() { claim.Complete(response) })
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		return nil, errCommit //nolint:wrapcheck // intentional
+	}
+
+	return response, nil
+}
+
+// withdrawAndDepositContext всё, что провайдер прислал о ставке, сохраняется вместе с платежом.
+// originalDeposit выигрыш в валюте кошелька до округления по правилам банковской группы, 0 если не округлялся.
+func withdrawAndDepositContext(in *types.WithdrawAndDepositRequest, originalDeposit int) (repo.PaymentContext, error) {
+	extras, errMarshal := json.Marshal(struct {
+		SessionAlternativeID string `json:"sessionAlternativeId,omitempty"`
+		ChargeFreeRounds     int    `json:"chargeFreerounds,omitempty"`
+		Currency             string `json:"currency,omitempty"`
+		OriginalDeposit      int    `json:"originalDeposit,omitempty"`
+	}{
+		SessionAlternativeID: in.SessionAlternativeID,
+		ChargeFreeRounds:     in.ChargeFreeRounds,
+		Currency:             in.Currency,
+		OriginalDeposit:      originalDeposit,
+	})
+	if errMarshal != nil {
+		return repo.PaymentContext{}, errMarshal //nolint:wrapcheck // intentional
+	}
+
+	return repo.PaymentContext{
+		GameID:       in.GameID,
+		GameRoundRef: in.GameRoundRef,
+		Reason:       string(in.Reason),
+		SessionID:    in.SessionID,
+		CallerID:     in.CallerID,
+		BonusID:      in.BonusID,
+		BetType:      in.SpinDetails.BetType,
+		WinType:      in.SpinDetails.WinType,
+		Extras:       extras,
 	}, nil
 }
 
-func NewRPCService(db *sqlx.DB, logger *zap.Logger) *RPCService {
+func NewRPCService(store Store, keys *idempotency.Keys, startingBalance int, logger *zap.Logger) *RPCService {
 	return &RPCService{
-		db:     db,
-		logger: logger,
+		store:           store,
+		keys:            keys,
+		startingBalance: startingBalance,
+		logger:          logger,
 	}
 }
@@ -0,0 +1,399 @@
+package seamlessv2_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/idempotency"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/memstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const (
+	operatorID = 1
+	player     = "player1"
+)
+
+var (
+	eur = repo.Currency{ID: 1, Code: "EUR", NumberOfDigitsAfterTheDecimalSeparator: 2, Name: "Euro"}
+	usd = repo.Currency{ID: 2, Code: "USD", NumberOfDigitsAfterTheDecimalSeparator: 2, Name: "US Dollar"}
+)
+
+func newService(t *testing.T) (*memstore.Store, *seamlessv2.RPCService) {
+	t.Helper()
+
+	store := memstore.New(eur, usd)
+
+	return store, seamlessv2.NewRPCService(store, nil, seamlessv2.DefaultStartingBalance, zap.NewNop())
+}
+
+// openWallet заводит кошелёк игрока в EUR со стартовым балансом.
+func openWallet(t *testing.T, svc *seamlessv2.RPCService) {
+	t.Helper()
+
+	if _, errGetBalance := getBalance(svc, "EUR"); errGetBalance != nil {
+		t.Fatalf("open wallet: %v", errGetBalance)
+	}
+}
+
+func getBalance(svc *seamlessv2.RPCService, currency string) (*types.GetBalanceResponse, error) {
+	return svc.GetBalance(context.Background(), &types.GetBalanceRequest{
+		CallerID:   operatorID,
+		PlayerName: player,
+		Currency:   currency,
+		GameID:     "riot",
+	})
+}
+
+func withdrawAndDeposit(
+	svc *seamlessv2.RPCService,
+	ref string,
+	withdraw, deposit int,
+) (*types.WithdrawAndDepositResponse, error) {
+	return svc.WithdrawAndDeposit(context.Background(), &types.WithdrawAndDepositRequest{
+		CallerID:       operatorID,
+		PlayerName:     player,
+		Withdraw:       withdraw,
+		Deposit:        deposit,
+		Currency:       "EUR",
+		TransactionRef: ref,
+		GameRoundRef:   "round-1",
+		GameID:         "riot",
+	})
+}
+
+func rollback(svc *seamlessv2.RPCService, ref string) error {
+	_, errRollback := svc.RollbackTransaction(context.Background(), &types.RollbackTransactionRequest{
+		CallerID:       operatorID,
+		PlayerName:     player,
+		TransactionRef: ref,
+	})
+
+	return errRollback
+}
+
+func balanceOf(t *testing.T, svc *seamlessv2.RPCService) int {
+	t.Helper()
+
+	response, errGetBalance := getBalance(svc, "EUR")
+	if errGetBalance != nil {
+		t.Fatalf("get balance: %v", errGetBalance)
+	}
+
+	return response.Balance
+}
+
+func TestGetBalanceNewUser(t *testing.T) {
+	store, svc := newService(t)
+
+	response, errGetBalance := getBalance(svc, "EUR")
+	if errGetBalance != nil {
+		t.Fatalf("get balance: %v", errGetBalance)
+	}
+
+	if response.Balance != seamlessv2.DefaultStartingBalance {
+		t.Fatalf("balance = %d, want %d", response.Balance, seamlessv2.DefaultStartingBalance)
+	}
+
+	payments := store.Payments()
+	if len(payments) != 1 {
+		t.Fatalf("payments = %d, want 1", len(payments))
+	}
+
+	if payment := payments[0]; payment.TransactionRef != seamlessv2.InitialDepositRef ||
+		payment.Deposit != seamlessv2.DefaultStartingBalance || payment.CurrencyID != eur.ID {
+		t.Fatalf("initial deposit = %+v", payment)
+	}
+
+	// повторный запрос не создаёт второй стартовый депозит
+	if balance := balanceOf(t, svc); balance != seamlessv2.DefaultStartingBalance {
+		t.Fatalf("balance after second call = %d, want %d", balance, seamlessv2.DefaultStartingBalance)
+	}
+
+	if len(store.Payments()) != 1 {
+		t.Fatalf("payments after second call = %d, want 1", len(store.Payments()))
+	}
+}
+
+func TestPlayerStatus(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		status        repo.UserStatus
+		excludedUntil *time.Time
+		balanceErr    error
+		betErr        error
+	}{
+		{name: "active", status: repo.UserStatusActive},
+		{name: "suspended", status: repo.UserStatusSuspended, betErr: seamlessv2.ErrPlayerBetsDisabled},
+		{
+			name:          "self excluded",
+			status:        repo.UserStatusSelfExcluded,
+			excludedUntil: &future,
+			betErr:        seamlessv2.ErrPlayerBetsDisabled,
+		},
+		{name: "self exclusion expired", status: repo.UserStatusSelfExcluded, excludedUntil: &past},
+		{
+			name:       "closed",
+			status:     repo.UserStatusClosed,
+			balanceErr: seamlessv2.ErrPlayerClosed,
+			betErr:     seamlessv2.ErrPlayerBetsDisabled,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			store, svc := newService(t)
+			openWallet(t, svc)
+
+			if !store.SetUserStatus(player, test.status, test.excludedUntil) {
+				t.Fatal("player not found")
+			}
+
+			if _, errGetBalance := getBalance(svc, "EUR"); !errors.Is(errGetBalance, test.balanceErr) {
+				t.Fatalf("get balance error = %v, want %v", errGetBalance, test.balanceErr)
+			}
+
+			if _, errBet := withdrawAndDeposit(svc, "bet", 100, 0); !errors.Is(errBet, test.betErr) {
+				t.Fatalf("bet error = %v, want %v", errBet, test.betErr)
+			}
+
+			// выигрыш и откат разрешены при любом статусе, чтобы закрыть начатые раунды
+			if _, errWin := withdrawAndDeposit(svc, "win", 0, 50); errWin != nil {
+				t.Fatalf("win: %v", errWin)
+			}
+
+			if errRollback := rollback(svc, "win"); errRollback != nil {
+				t.Fatalf("rollback: %v", errRollback)
+			}
+		})
+	}
+}
+
+func TestWithdrawAndDeposit(t *testing.T) {
+	_, svc := newService(t)
+	openWallet(t, svc)
+
+	response, errWithdrawAndDeposit := withdrawAndDeposit(svc, "tx-1", 400, 1200)
+	if errWithdrawAndDeposit != nil {
+		t.Fatalf("withdraw and deposit: %v", errWithdrawAndDeposit)
+	}
+
+	want := seamlessv2.DefaultStartingBalance - 400 + 1200
+	if response.NewBalance != want || response.TransactionID != "tx-1" {
+		t.Fatalf("response = %+v, want balance %d", response, want)
+	}
+
+	if balance := balanceOf(t, svc); balance != want {
+		t.Fatalf("balance = %d, want %d", balance, want)
+	}
+}
+
+func TestWithdrawAndDepositInsufficientFunds(t *testing.T) {
+	store, svc := newService(t)
+	openWallet(t, svc)
+
+	_, errWithdrawAndDeposit := withdrawAndDeposit(svc, "tx-1", seamlessv2.DefaultStartingBalance+1, 0)
+	if !errors.Is(errWithdrawAndDeposit, seamlessv2.ErrNoFreeCurrency) {
+		t.Fatalf("error = %v, want %v", errWithdrawAndDeposit, seamlessv2.ErrNoFreeCurrency)
+	}
+
+	if len(store.Payments()) != 1 {
+		t.Fatalf("payments = %d, want only the initial deposit", len(store.Payments()))
+	}
+
+	// выигрыш в том же запросе покрывает ставку
+	if _, errCovered := withdrawAndDeposit(svc, "tx-2", seamlessv2.DefaultStartingBalance+1, 1); errCovered != nil {
+		t.Fatalf("covered bet: %v", errCovered)
+	}
+}
+
+func TestWithdrawAndDepositDuplicateRef(t *testing.T) {
+	store, svc := newService(t)
+	openWallet(t, svc)
+
+	if _, errFirst := withdrawAndDeposit(svc, "tx-1", 100, 0); errFirst != nil {
+		t.Fatalf("first call: %v", errFirst)
+	}
+
+	if _, errDuplicate := withdrawAndDeposit(svc, "tx-1", 100, 0); !errors.Is(
+		errDuplicate,
+		memstore.ErrDuplicateTransactionRef,
+	) {
+		t.Fatalf("duplicate error = %v, want %v", errDuplicate, memstore.ErrDuplicateTransactionRef)
+	}
+
+	if balance := balanceOf(t, svc); balance != seamlessv2.DefaultStartingBalance-100 {
+		t.Fatalf("balance = %d, want %d", balance, seamlessv2.DefaultStartingBalance-100)
+	}
+
+	if len(store.Payments()) != 2 {
+		t.Fatalf("payments = %d, want 2", len(store.Payments()))
+	}
+}
+
+// TestWithdrawAndDepositReplay с захватом ключей повтор выполненного запроса получает ответ оригинала,
+// а не ошибку дубля.
+func TestWithdrawAndDepositReplay(t *testing.T) {
+	store := memstore.New(eur, usd)
+	keys := idempotency.New(cashstore.NewLRU(0, 0), idempotency.Options{}, zap.NewNop())
+	svc := seamlessv2.NewRPCService(store, keys, seamlessv2.DefaultStartingBalance, zap.NewNop())
+	openWallet(t, svc)
+
+	first, errFirst := withdrawAndDeposit(svc, "tx-1", 100, 30)
+	if errFirst != nil {
+		t.Fatalf("first call: %v", errFirst)
+	}
+
+	// между повторами баланс меняется, но повтор видит баланс на момент оригинала
+	if _, errOther := withdrawAndDeposit(svc, "tx-2", 50, 0); errOther != nil {
+		t.Fatalf("other call: %v", errOther)
+	}
+
+	replayed, errReplay := withdrawAndDeposit(svc, "tx-1", 100, 30)
+	if errReplay != nil {
+		t.Fatalf("replay: %v", errReplay)
+	}
+
+	if *replayed != *first {
+		t.Fatalf("replayed response = %+v, want %+v", *replayed, *first)
+	}
+
+	if balance := balanceOf(t, svc); balance != seamlessv2.DefaultStartingBalance-100+30-50 {
+		t.Fatalf("balance = %d, want %d", balance, seamlessv2.DefaultStartingBalance-100+30-50)
+	}
+
+	if len(store.Payments()) != 3 {
+		t.Fatalf("payments = %d, want 3", len(store.Payments()))
+	}
+
+	// неудачный запрос не сохраняется: повтор выполняется заново и снова получает ошибку
+	for i := 0; i < 2; i++ {
+		if _, errTooLarge := withdrawAndDeposit(svc, "tx-3", seamlessv2.DefaultStartingBalance, 0); !errors.Is(
+			errTooLarge,
+			seamlessv2.ErrNoFreeCurrency,
+		) {
+			t.Fatalf("attempt %d error = %v, want %v", i, errTooLarge, seamlessv2.ErrNoFreeCurrency)
+		}
+	}
+}
+
+func TestWithdrawAndDepositReusedRef(t *testing.T) {
+	store := memstore.New(eur, usd)
+	keys := idempotency.New(cashstore.NewLRU(0, 0), idempotency.Options{}, zap.NewNop())
+	svc := seamlessv2.NewRPCService(store, keys, seamlessv2.DefaultStartingBalance, zap.NewNop())
+	openWallet(t, svc)
+
+	if _, errFirst := withdrawAndDeposit(svc, "tx-1", 100, 30); errFirst != nil {
+		t.Fatalf("first call: %v", errFirst)
+	}
+
+	// тот же transactionRef с другой суммой не получает ответ оригинала
+	if _, errReused := withdrawAndDeposit(svc, "tx-1", 100, 3000); !errors.Is(
+		errReused,
+		seamlessv2.ErrDuplicateTransactionRef,
+	) {
+		t.Fatalf("reused ref error = %v, want %v", errReused, seamlessv2.ErrDuplicateTransactionRef)
+	}
+
+	if balance := balanceOf(t, svc); balance != seamlessv2.DefaultStartingBalance-100+30 {
+		t.Fatalf("balance = %d, want %d", balance, seamlessv2.DefaultStartingBalance-100+30)
+	}
+}
+
+func TestRollbackUnknownRef(t *testing.T) {
+	_, svc := newService(t)
+	openWallet(t, svc)
+
+	if errRollback := rollback(svc, "missing"); !errors.Is(errRollback, memstore.ErrNotFound) {
+		t.Fatalf("error = %v, want %v", errRollback, memstore.ErrNotFound)
+	}
+}
+
+func TestRollbackRepeated(t *testing.T) {
+	store, svc := newService(t)
+	openWallet(t, svc)
+
+	if _, errBet := withdrawAndDeposit(svc, "tx-1", 300, 0); errBet != nil {
+		t.Fatalf("bet: %v", errBet)
+	}
+
+	var rolledBackAt *time.Time
+
+	for i := 0; i < 2; i++ {
+		if errRollback := rollback(svc, "tx-1"); errRollback != nil {
+			t.Fatalf("rollback %d: %v", i+1, errRollback)
+		}
+
+		if balance := balanceOf(t, svc); balance != seamlessv2.DefaultStartingBalance {
+			t.Fatalf("balance after rollback %d = %d, want %d", i+1, balance, seamlessv2.DefaultStartingBalance)
+		}
+
+		payment := store.Payments()[1]
+		if payment.RollBackAt == nil {
+			t.Fatalf("payment is not rolled back after rollback %d", i+1)
+		}
+
+		// повтор не переписывает время первого отката
+		if rolledBackAt != nil && !payment.RollBackAt.Equal(*rolledBackAt) {
+			t.Fatalf("rollback_at changed on repeat: %v -> %v", *rolledBackAt, *payment.RollBackAt)
+		}
+
+		rolledBackAt = payment.RollBackAt
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	t.Run("wallet currency", func(t *testing.T) {
+		store, svc := newService(t)
+		openWallet(t, svc)
+
+		if _, errGetBalance := getBalance(svc, "USD"); !errors.Is(errGetBalance, seamlessv2.ErrConflictOfCurrencies) {
+			t.Fatalf("get balance error = %v, want %v", errGetBalance, seamlessv2.ErrConflictOfCurrencies)
+		}
+
+		_, errWithdrawAndDeposit := svc.WithdrawAndDeposit(context.Background(), &types.WithdrawAndDepositRequest{
+			CallerID:       operatorID,
+			PlayerName:     player,
+			Withdraw:       100,
+			Currency:       "USD",
+			TransactionRef: "tx-1",
+		})
+		if !errors.Is(errWithdrawAndDeposit, seamlessv2.ErrConflictOfCurrencies) {
+			t.Fatalf("withdraw error = %v, want %v", errWithdrawAndDeposit, seamlessv2.ErrConflictOfCurrencies)
+		}
+
+		if len(store.Payments()) != 1 {
+			t.Fatalf("payments = %d, want only the initial deposit", len(store.Payments()))
+		}
+	})
+
+	t.Run("bank group", func(t *testing.T) {
+		store, svc := newService(t)
+		store.SetOperatorBankGroup(operatorID, repo.BankGroup{
+			ID:           "usd-only",
+			RoundingStep: 1,
+			RoundingMode: repo.RoundingNone,
+			FreeRounds:   repo.FreeRoundsAllow,
+		}, usd.ID)
+
+		if _, errGetBalance := getBalance(svc, "EUR"); !errors.Is(errGetBalance, seamlessv2.ErrCurrencyNotAllowed) {
+			t.Fatalf("error = %v, want %v", errGetBalance, seamlessv2.ErrCurrencyNotAllowed)
+		}
+
+		if len(store.Payments()) != 0 {
+			t.Fatalf("payments = %d, want none", len(store.Payments()))
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package seamlessv2
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+type balanceUpdateKey struct{}
+
+// WithBalanceUpdate помечает вызов, который меняет баланс: хранилище с кэшем должно читать баланс
+// из базы, а не из кэша, иначе по устаревшему балансу можно уйти в минус.
+func WithBalanceUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, balanceUpdateKey{}, true)
+}
+
+func IsBalanceUpdate(ctx context.Context) bool {
+	update, _ := ctx.Value(balanceUpdateKey{}).(bool)
+
+	return update
+}
+
+// Store хранилище кошельков, с которым работает RPCService. В проде это NewPostgresStore,
+// для тестов без базы - memstore.
+type Store interface {
+	// Begin открывает транзакцию вызова, изменения видны другим вызовам только после Commit.
+	Begin(ctx context.Context) (StoreTx, error)
+}
+
+// StoreTx транзакция хранилища. Rollback после Commit ничего не делает.
+type StoreTx interface {
+	Users() UserRepository
+	Wallets() WalletRepository
+	Payments() PaymentRepository
+	Bonuses() BonusRepository
+	Currencies() CurrencyRepository
+	BankGroups() BankGroupRepository
+	ExchangeRates() ExchangeRateRepository
+	// AfterCommit fn выполнится только после фиксации изменений транзакции.
+	AfterCommit(fn func())
+	// AfterRollback fn выполнится, если изменения транзакции так и не зафиксированы, в том числе когда
+	// вызов внутри атомарного пакета прошёл, а откатился весь пакет.
+	AfterRollback(fn func())
+	Commit() error
+	Rollback() error
+}
+
+type UserRepository interface {
+	FindByName(ctx context.Context, name string) (*repo.User, error)
+	// LockByName как FindByName, но игрок заблокирован до конца транзакции: изменения баланса
+	// одного игрока из любых инстансов и из админки идут по очереди. Кэшем не обслуживается.
+	LockByName(ctx context.Context, name string) (*repo.User, error)
+	Create(ctx context.Context, name string, operatorID int) (*repo.User, error)
+}
+
+type WalletRepository interface {
+	// Balance сумма всех не откаченных платежей игрока.
+	Balance(ctx context.Context, userID int) (int, error)
+	// CurrencyID валюта кошелька по первому платежу игрока, -1 если платежей нет.
+	CurrencyID(ctx context.Context, userID int) (int, error)
+}
+
+type PaymentRepository interface {
+	Create(
+		ctx context.Context,
+		userID, currencyID, withdraw, deposit int,
+		transactionRef string,
+		paymentContext repo.PaymentContext,
+	) (*repo.Payment, error)
+	// CheckUniqueRef ошибка, если платёж с таким transactionRef уже есть.
+	CheckUniqueRef(ctx context.Context, transactionRef string) error
+	// Rollback отмечает платёж откаченным и возвращает откаченные платежи, ошибка если платежа нет.
+	// Уже откаченный платёж не меняется, результат пустой.
+	Rollback(ctx context.Context, transactionRef string) ([]repo.Payment, error)
+	History(ctx context.Context, filter repo.PaymentHistoryFilter, limit int) ([]repo.PaymentHistoryRow, error)
+	HistoryTotals(ctx context.Context, filter repo.PaymentHistoryFilter) (repo.PaymentHistoryTotals, error)
+}
+
+type BonusRepository interface {
+	Write(ctx context.Context, bonusID string) error
+	// Use отмечает бонус использованным, ошибка если бонуса нет.
+	Use(ctx context.Context, bonusID string) error
+}
+
+type CurrencyRepository interface {
+	ByID(ctx context.Context, id int) (repo.Currency, error)
+	ByCode(ctx context.Context, code string) (*repo.Currency, error)
+}
+
+type BankGroupRepository interface {
+	// ByOperator банковская группа оператора и её разрешённые валюты, nil если группа не назначена.
+	ByOperator(ctx context.Context, operatorID int) (*repo.BankGroup, []int, error)
+}
+
+type ExchangeRateRepository interface {
+	// Rate курс from -> to, действующий на момент at, nil если курса нет.
+	Rate(ctx context.Context, fromCurrencyID, toCurrencyID int, at time.Time) (*repo.ExchangeRate, error)
+}
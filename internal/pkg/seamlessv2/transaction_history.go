@@ -0,0 +1,101 @@
+package seamlessv2
+
+import (
+	"context"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// GetTransactionHistory история платежей игрока, новые сверху, с keyset пагинацией по id:
+// nextCursor из ответа передаётся в cursor следующего запроса, 0 означает что страниц больше нет.
+func (r *RPCService) GetTransactionHistory(
+	ctx context.Context,
+	in *types.GetTransactionHistoryRequest,
+) (*types.GetTransactionHistoryResponse, error) {
+	// в атомарном пакете история видит платежи предыдущих вызовов пакета
+	tx, errBeginTx := r.store.Begin(ctx)
+	if errBeginTx != nil {
+		return nil, errBeginTx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // только чтение
+
+	user, errFindUserByName := tx.Users().FindByName(ctx, in.PlayerName)
+	if errFindUserByName != nil {
+		return nil, errFindUserByName //nolint:wrapcheck // intentional
+	}
+
+	limit := in.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	filter := repo.PaymentHistoryFilter{
+		UserID:       user.ID,
+		From:         in.From,
+		To:           in.To,
+		GameID:       in.GameID,
+		GameRoundRef: in.GameRoundRef,
+		Reason:       in.Reason,
+		RolledBack:   in.RolledBack,
+		BeforeID:     in.Cursor,
+	}
+
+	// берём на одну запись больше, чтобы понять есть ли следующая страница
+	rows, errGetPaymentHistory := tx.Payments().History(ctx, filter, limit+1)
+	if errGetPaymentHistory != nil {
+		return nil, errGetPaymentHistory //nolint:wrapcheck // intentional
+	}
+
+	totals, errGetPaymentHistoryTotals := tx.Payments().HistoryTotals(ctx, filter)
+	if errGetPaymentHistoryTotals != nil {
+		return nil, errGetPaymentHistoryTotals //nolint:wrapcheck // intentional
+	}
+
+	var nextCursor int
+	if len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = rows[limit-1].ID
+	}
+
+	transactions := make([]types.Transaction, 0, len(rows))
+
+	for _, row := range rows {
+		transaction := types.Transaction{
+			ID:             row.ID,
+			RollbackAt:     row.RollBackAt,
+			TransactionRef: row.TransactionRef,
+			GameID:         row.GameID,
+			GameRoundRef:   row.GameRoundRef,
+			Reason:         row.Reason,
+			Withdraw:       row.Withdraw,
+			Deposit:        row.Deposit,
+			Balance:        row.Balance,
+		}
+
+		if row.CreatedAt != nil {
+			transaction.CreatedAt = *row.CreatedAt
+		}
+
+		transactions = append(transactions, transaction)
+	}
+
+	return &types.GetTransactionHistoryResponse{
+		Transactions: transactions,
+		NextCursor:   nextCursor,
+		Totals: types.TransactionTotals{
+			Count:    totals.Count,
+			Withdraw: totals.Withdraw,
+			Deposit:  totals.Deposit,
+		},
+	}, nil
+}
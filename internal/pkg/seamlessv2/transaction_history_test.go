@@ -0,0 +1,161 @@
+package seamlessv2_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+)
+
+type historyRow struct {
+	ref     string
+	balance int
+}
+
+// readHistory проходит все страницы по курсору и проверяет, что ни одна запись не потерялась и не повторилась.
+func readHistory(
+	t *testing.T,
+	svc *seamlessv2.RPCService,
+	in types.GetTransactionHistoryRequest,
+) ([][]historyRow, types.TransactionTotals) {
+	t.Helper()
+
+	var (
+		pages  [][]historyRow
+		totals types.TransactionTotals
+	)
+
+	for {
+		response, errHistory := svc.GetTransactionHistory(context.Background(), &in)
+		if errHistory != nil {
+			t.Fatalf("history: %v", errHistory)
+		}
+
+		page := make([]historyRow, 0, len(response.Transactions))
+		for _, transaction := range response.Transactions {
+			page = append(page, historyRow{ref: transaction.TransactionRef, balance: transaction.Balance})
+		}
+
+		pages = append(pages, page)
+		totals = response.Totals
+
+		if response.NextCursor == 0 {
+			return pages, totals
+		}
+
+		in.Cursor = response.NextCursor
+	}
+}
+
+func assertPages(t *testing.T, got, want [][]historyRow) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Fatalf("page %d = %v, want %v", i, got[i], want[i])
+		}
+
+		for j := range want[i] {
+			if got[i][j] != want[i][j] {
+				t.Fatalf("page %d = %v, want %v", i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestGetTransactionHistoryPages(t *testing.T) {
+	_, svc := newService(t)
+	openWallet(t, svc)
+
+	start := seamlessv2.DefaultStartingBalance
+
+	for _, step := range []struct {
+		ref               string
+		withdraw, deposit int
+	}{
+		{"tx-1", 100, 0},
+		{"tx-2", 50, 200},
+		{"tx-3", 30, 0},
+		{"tx-4", 0, 10},
+	} {
+		if _, errWithdrawAndDeposit := withdrawAndDeposit(svc, step.ref, step.withdraw, step.deposit); errWithdrawAndDeposit != nil {
+			t.Fatalf("%s: %v", step.ref, errWithdrawAndDeposit)
+		}
+	}
+
+	if errRollback := rollback(svc, "tx-2"); errRollback != nil {
+		t.Fatalf("rollback: %v", errRollback)
+	}
+
+	initial := historyRow{seamlessv2.InitialDepositRef, start}
+	tx1 := historyRow{"tx-1", start - 100}
+	// откаченный платёж на баланс не влияет, ни на свой, ни на следующие
+	tx2 := historyRow{"tx-2", start - 100}
+	tx3 := historyRow{"tx-3", start - 130}
+	tx4 := historyRow{"tx-4", start - 120}
+
+	t.Run("all", func(t *testing.T) {
+		pages, totals := readHistory(t, svc, types.GetTransactionHistoryRequest{
+			CallerID:   operatorID,
+			PlayerName: player,
+			Limit:      2,
+		})
+
+		assertPages(t, pages, [][]historyRow{{tx4, tx3}, {tx2, tx1}, {initial}})
+
+		want := types.TransactionTotals{Count: 5, Withdraw: 130, Deposit: start + 10}
+		if totals != want {
+			t.Fatalf("totals = %+v, want %+v", totals, want)
+		}
+	})
+
+	t.Run("page ends on the last record", func(t *testing.T) {
+		pages, _ := readHistory(t, svc, types.GetTransactionHistoryRequest{
+			CallerID:   operatorID,
+			PlayerName: player,
+			Limit:      5,
+		})
+
+		assertPages(t, pages, [][]historyRow{{tx4, tx3, tx2, tx1, initial}})
+	})
+
+	t.Run("rolled back filtered out", func(t *testing.T) {
+		rolledBack := false
+
+		// страница перескакивает через откаченный платёж, баланс всё равно по всей истории
+		pages, totals := readHistory(t, svc, types.GetTransactionHistoryRequest{
+			CallerID:   operatorID,
+			PlayerName: player,
+			RolledBack: &rolledBack,
+			Limit:      2,
+		})
+
+		assertPages(t, pages, [][]historyRow{{tx4, tx3}, {tx1, initial}})
+
+		if totals.Count != 4 {
+			t.Fatalf("count = %d, want 4", totals.Count)
+		}
+	})
+
+	t.Run("only rolled back", func(t *testing.T) {
+		rolledBack := true
+
+		pages, totals := readHistory(t, svc, types.GetTransactionHistoryRequest{
+			CallerID:   operatorID,
+			PlayerName: player,
+			RolledBack: &rolledBack,
+			Limit:      2,
+		})
+
+		assertPages(t, pages, [][]historyRow{{tx2}})
+
+		if want := (types.TransactionTotals{Count: 1}); totals != want {
+			t.Fatalf("totals = %+v, want %+v", totals, want)
+		}
+	})
+}
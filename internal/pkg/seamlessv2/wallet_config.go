@@ -0,0 +1,143 @@
+package seamlessv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var (
+	ErrCurrencyNotAllowed  = errors.New("currency is not allowed for the operator")
+	ErrBetOutOfLimits      = errors.New("bet is out of limits")
+	ErrBetNotRounded       = errors.New("bet is not a multiple of the rounding step")
+	ErrFreeRoundsDisabled  = errors.New("free rounds are disabled for the operator")
+	ErrFreeRoundsOverLimit = errors.New("too many free rounds charged")
+)
+
+// DefaultStartingBalance стартовый баланс для операторов без банковской группы, если он не задан в конфигурации.
+const DefaultStartingBalance = 10000
+
+// WalletConfig настройки кошельков оператора, берутся из его банковской группы.
+// Оператор без группы работает как раньше: любая валюта, без лимитов и округления.
+type WalletConfig struct {
+	BankGroupID string
+	// CurrencyIDs пустой - разрешены все валюты.
+	CurrencyIDs         []int
+	StartingBalance     int
+	BetMin              int
+	BetMax              int
+	RoundingStep        int
+	RoundingMode        repo.RoundingMode
+	FreeRounds          repo.FreeRoundsPolicy
+	FreeRoundsMaxCharge int
+	CurrencyConversion  bool
+}
+
+func defaultWalletConfig(startingBalance int) WalletConfig {
+	return WalletConfig{
+		StartingBalance: startingBalance,
+		RoundingStep:    1,
+		RoundingMode:    repo.RoundingNone,
+		FreeRounds:      repo.FreeRoundsAllow,
+	}
+}
+
+// walletConfig настройки определяются по оператору, от которого пришёл запрос, а не по тому,
+// через которого игрок зарегистрировался.
+func (r *RPCService) walletConfig(ctx context.Context, tx StoreTx, operatorID int) (WalletConfig, error) {
+	bankGroup, currencyIDs, errByOperator := tx.BankGroups().ByOperator(ctx, operatorID)
+	if errByOperator != nil {
+		return WalletConfig{}, errByOperator //nolint:wrapcheck // intentional
+	}
+
+	if bankGroup == nil {
+		return defaultWalletConfig(r.startingBalance), nil
+	}
+
+	return WalletConfig{
+		BankGroupID:         bankGroup.ID,
+		CurrencyIDs:         currencyIDs,
+		StartingBalance:     bankGroup.StartingBalance,
+		BetMin:              bankGroup.BetMin,
+		BetMax:              bankGroup.BetMax,
+		RoundingStep:        bankGroup.RoundingStep,
+		RoundingMode:        bankGroup.RoundingMode,
+		FreeRounds:          bankGroup.FreeRounds,
+		FreeRoundsMaxCharge: bankGroup.FreeRoundsMaxCharge,
+		CurrencyConversion:  bankGroup.CurrencyConversion,
+	}, nil
+}
+
+func (c WalletConfig) checkCurrency(currencyID int, code string) error {
+	if len(c.CurrencyIDs) == 0 {
+		return nil
+	}
+
+	for _, allowedID := range c.CurrencyIDs {
+		if allowedID == currencyID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrCurrencyNotAllowed, code)
+}
+
+// checkBet лимиты и шаг округления проверяются только для ставок, выигрыш принимается всегда.
+func (c WalletConfig) checkBet(withdraw int) error {
+	if withdraw <= 0 {
+		return nil
+	}
+
+	if withdraw < c.BetMin || (c.BetMax > 0 && withdraw > c.BetMax) {
+		return fmt.Errorf("%w: %d not in [%d, %d]", ErrBetOutOfLimits, withdraw, c.BetMin, c.BetMax)
+	}
+
+	if c.RoundingMode != repo.RoundingNone && c.RoundingStep > 1 && withdraw%c.RoundingStep != 0 {
+		return fmt.Errorf("%w: %d, step %d", ErrBetNotRounded, withdraw, c.RoundingStep)
+	}
+
+	return nil
+}
+
+// round округляет ставку до шага группы по её режиму. Ставка округляется только после пересчёта
+// из валюты игры: сумму в валюте кошелька игрок не выбирал, а ставку в своей валюте провайдер уже списал.
+func (c WalletConfig) round(amount int) int {
+	if c.RoundingStep <= 1 || amount <= 0 {
+		return amount
+	}
+
+	switch c.RoundingMode {
+	case repo.RoundingDown:
+		return amount - amount%c.RoundingStep
+	case repo.RoundingHalfUp:
+		return (amount + c.RoundingStep/2) / c.RoundingStep * c.RoundingStep //nolint:gomnd // half of the step
+	case repo.RoundingNone:
+	}
+
+	return amount
+}
+
+// roundWin округляет выигрыш до шага группы всегда вниз, в пользу казино: с half_up выигрыш 15
+// при шаге 10 зачислил бы игроку 20. Режим none выигрыш не округляет.
+func (c WalletConfig) roundWin(amount int) int {
+	if c.RoundingStep <= 1 || amount <= 0 || c.RoundingMode == repo.RoundingNone {
+		return amount
+	}
+
+	return amount - amount%c.RoundingStep
+}
+
+// checkFreeRounds при запрете бесплатных вращений отклоняются и бонусы, ими их начисляют.
+func (c WalletConfig) checkFreeRounds(bonusID string, chargeFreeRounds int) error {
+	if c.FreeRounds == repo.FreeRoundsDeny && (bonusID != "" || chargeFreeRounds > 0) {
+		return ErrFreeRoundsDisabled
+	}
+
+	if c.FreeRoundsMaxCharge > 0 && chargeFreeRounds > c.FreeRoundsMaxCharge {
+		return fmt.Errorf("%w: %d, max %d", ErrFreeRoundsOverLimit, chargeFreeRounds, c.FreeRoundsMaxCharge)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package seamlessv2
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+func TestRound(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		mode   repo.RoundingMode
+		step   int
+		amount int
+		bet    int
+		win    int
+	}{
+		{name: "none", mode: repo.RoundingNone, step: 10, amount: 15, bet: 15, win: 15},
+		{name: "step one", mode: repo.RoundingHalfUp, step: 1, amount: 15, bet: 15, win: 15},
+		{name: "down", mode: repo.RoundingDown, step: 10, amount: 19, bet: 10, win: 10},
+		{name: "half up below half", mode: repo.RoundingHalfUp, step: 10, amount: 14, bet: 10, win: 10},
+		{name: "half up at half", mode: repo.RoundingHalfUp, step: 10, amount: 15, bet: 20, win: 10},
+		{name: "half up above half", mode: repo.RoundingHalfUp, step: 10, amount: 19, bet: 20, win: 10},
+		{name: "multiple of step", mode: repo.RoundingHalfUp, step: 10, amount: 30, bet: 30, win: 30},
+		{name: "below step", mode: repo.RoundingDown, step: 10, amount: 7, bet: 0, win: 0},
+		{name: "zero", mode: repo.RoundingHalfUp, step: 10, amount: 0, bet: 0, win: 0},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			config := WalletConfig{RoundingStep: tc.step, RoundingMode: tc.mode}
+
+			if got := config.round(tc.amount); got != tc.bet {
+				t.Errorf("round(%d) = %d, want %d", tc.amount, got, tc.bet)
+			}
+
+			if got := config.roundWin(tc.amount); got != tc.win {
+				t.Errorf("roundWin(%d) = %d, want %d", tc.amount, got, tc.win)
+			}
+		})
+	}
+}
+
+func TestConversion(t *testing.T) {
+	eur := repo.Currency{ID: 1, Code: "EUR", NumberOfDigitsAfterTheDecimalSeparator: 2}
+	jpy := repo.Currency{ID: 2, Code: "JPY", NumberOfDigitsAfterTheDecimalSeparator: 0}
+	kwd := repo.Currency{ID: 3, Code: "KWD", NumberOfDigitsAfterTheDecimalSeparator: 3}
+
+	for _, tc := range []struct {
+		name   string
+		game   repo.Currency
+		wallet repo.Currency
+		rate   string
+		amount int
+		bet    int
+		win    int
+		back   int
+	}{
+		// 1.99 EUR * 163.25 = 324.8675 JPY
+		{name: "fewer digits", game: eur, wallet: jpy, rate: "163.25", amount: 199, bet: 325, win: 324, back: 199},
+		// 1.005 KWD * 3 = 3.015 EUR: ставка округляется от нуля, выигрыш вниз
+		{name: "half", game: kwd, wallet: eur, rate: "3", amount: 1005, bet: 302, win: 301, back: 1007},
+		// 250 JPY * 0.006125574 = 1.5313... EUR
+		{name: "more digits", game: jpy, wallet: eur, rate: "0.006125574", amount: 250, bet: 153, win: 153, back: 250},
+		{name: "exact", game: eur, wallet: kwd, rate: "0.3", amount: 1000, bet: 3000, win: 3000, back: 1000},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			rate, ok := new(big.Rat).SetString(tc.rate)
+			if !ok {
+				t.Fatalf("bad rate %s", tc.rate)
+			}
+
+			conv := &conversion{gameCurrency: tc.game, walletCurrency: tc.wallet, rate: rate, rateValue: tc.rate}
+
+			if got := conv.toWallet(tc.amount); got != tc.bet {
+				t.Errorf("toWallet(%d) = %d, want %d", tc.amount, got, tc.bet)
+			}
+
+			if got := conv.winToWallet(tc.amount); got != tc.win {
+				t.Errorf("winToWallet(%d) = %d, want %d", tc.amount, got, tc.win)
+			}
+
+			if got := conv.toGame(tc.bet); got != tc.back {
+				t.Errorf("toGame(%d) = %d, want %d", tc.bet, got, tc.back)
+			}
+		})
+	}
+}
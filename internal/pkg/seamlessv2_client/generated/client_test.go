@@ -0,0 +1,107 @@
+package generated_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"gitlab.com/pjrpc/pjrpc/v2"
+	"gitlab.com/pjrpc/pjrpc/v2/client"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	seamlessGenerated "github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/generated"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/memstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2_client/generated"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const player = "player1"
+
+// newClient клиент к сервису на хранилище в памяти поверх httptest: ответы проходят весь путь
+// от генерированного сервера до разбора в генерированном клиенте.
+func newClient(t *testing.T) generated.SeamlessV2ServiceClient {
+	t.Helper()
+
+	store := memstore.New(repo.Currency{ID: 1, Code: "EUR", NumberOfDigitsAfterTheDecimalSeparator: 2, Name: "Euro"})
+
+	srv := pjrpc.NewServerHTTP()
+	seamlessGenerated.RegisterSeamlessV2ServiceServer(
+		srv,
+		seamlessv2.NewRPCService(store, nil, seamlessv2.DefaultStartingBalance, zap.NewNop()),
+	)
+
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+
+	cl, errNew := client.New(server.URL + "/rpc/")
+	if errNew != nil {
+		t.Fatalf("new client: %v", errNew)
+	}
+
+	return generated.NewSeamlessV2ServiceClient(cl)
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	cl := newClient(t)
+
+	balance, errGetBalance := cl.GetBalance(ctx, &types.GetBalanceRequest{
+		CallerID:   1,
+		PlayerName: player,
+		Currency:   "EUR",
+		GameID:     "riot",
+	})
+	if errGetBalance != nil {
+		t.Fatalf("get balance: %v", errGetBalance)
+	}
+
+	if balance.Balance != seamlessv2.DefaultStartingBalance {
+		t.Fatalf("balance = %d, want %d", balance.Balance, seamlessv2.DefaultStartingBalance)
+	}
+
+	payment, errWithdrawAndDeposit := cl.WithdrawAndDeposit(ctx, &types.WithdrawAndDepositRequest{
+		CallerID:       1,
+		PlayerName:     player,
+		Withdraw:       100,
+		Deposit:        40,
+		Currency:       "EUR",
+		TransactionRef: "tx-1",
+		GameRoundRef:   "round-1",
+		GameID:         "riot",
+	})
+	if errWithdrawAndDeposit != nil {
+		t.Fatalf("withdraw and deposit: %v", errWithdrawAndDeposit)
+	}
+
+	if want := seamlessv2.DefaultStartingBalance - 60; payment.NewBalance != want {
+		t.Fatalf("new balance = %d, want %d", payment.NewBalance, want)
+	}
+
+	history, errGetTransactionHistory := cl.GetTransactionHistory(ctx, &types.GetTransactionHistoryRequest{
+		CallerID:   1,
+		PlayerName: player,
+		Limit:      1,
+	})
+	if errGetTransactionHistory != nil {
+		t.Fatalf("get transaction history: %v", errGetTransactionHistory)
+	}
+
+	if len(history.Transactions) != 1 || history.Transactions[0].TransactionRef != "tx-1" ||
+		history.Transactions[0].Balance != payment.NewBalance {
+		t.Fatalf("transactions = %+v", history.Transactions)
+	}
+
+	if history.NextCursor != history.Transactions[0].ID || history.Totals.Count != 2 {
+		t.Fatalf("next cursor = %d, totals = %+v", history.NextCursor, history.Totals)
+	}
+
+	if _, errRollback := cl.RollbackTransaction(ctx, &types.RollbackTransactionRequest{
+		CallerID:       1,
+		PlayerName:     player,
+		TransactionRef: "tx-1",
+	}); errRollback != nil {
+		t.Fatalf("rollback transaction: %v", errRollback)
+	}
+}
@@ -15,9 +15,10 @@ import (
 
 // List of the client JSON-RPC methods.
 const (
-	JSONRPCMethodGetBalance_Client          = "getBalance"
-	JSONRPCMethodRollbackTransaction_Client = "withdrawAndDeposit"
-	JSONRPCMethodWithdrawAndDeposit_Client  = "rollbackTransaction"
+	JSONRPCMethodGetBalance_Client            = "getBalance"
+	JSONRPCMethodRollbackTransaction_Client   = "rollbackTransaction"
+	JSONRPCMethodWithdrawAndDeposit_Client    = "withdrawAndDeposit"
+	JSONRPCMethodGetTransactionHistory_Client = "getTransactionHistory"
 )
 
 // SeamlessV2ServiceClient is an API client for SeamlessV2Service service.
@@ -25,6 +26,7 @@ type SeamlessV2ServiceClient interface {
 	GetBalance(ctx context.Context, in *types.GetBalanceRequest, mods ...client.Mod) (*types.GetBalanceResponse, error)
 	RollbackTransaction(ctx context.Context, in *types.RollbackTransactionRequest, mods ...client.Mod) (*types.RollbackTransactionResponse, error)
 	WithdrawAndDeposit(ctx context.Context, in *types.WithdrawAndDepositRequest, mods ...client.Mod) (*types.WithdrawAndDepositResponse, error)
+	GetTransactionHistory(ctx context.Context, in *types.GetTransactionHistoryRequest, mods ...client.Mod) (*types.GetTransactionHistoryResponse, error)
 }
 
 type implSeamlessV2ServiceClient struct {
@@ -42,6 +44,8 @@ func (c *implSeamlessV2ServiceClient) GetBalance(ctx context.Context, in *types.
 		return result, fmt.Errorf("failed to create uuid generator: %w", err)
 	}
 
+	result = new(types.GetBalanceResponse)
+
 	err = c.cl.Invoke(ctx, gen.String(), JSONRPCMethodGetBalance_Client, in, result, mods...)
 	if err != nil {
 		return result, fmt.Errorf("failed to Invoke method %q: %w", JSONRPCMethodGetBalance_Client, err)
@@ -56,6 +60,8 @@ func (c *implSeamlessV2ServiceClient) RollbackTransaction(ctx context.Context, i
 		return result, fmt.Errorf("failed to create uuid generator: %w", err)
 	}
 
+	result = new(types.RollbackTransactionResponse)
+
 	err = c.cl.Invoke(ctx, gen.String(), JSONRPCMethodRollbackTransaction_Client, in, result, mods...)
 	if err != nil {
 		return result, fmt.Errorf("failed to Invoke method %q: %w", JSONRPCMethodRollbackTransaction_Client, err)
@@ -70,6 +76,8 @@ func (c *implSeamlessV2ServiceClient) WithdrawAndDeposit(ctx context.Context, in
 		return result, fmt.Errorf("failed to create uuid generator: %w", err)
 	}
 
+	result = new(types.WithdrawAndDepositResponse)
+
 	err = c.cl.Invoke(ctx, gen.String(), JSONRPCMethodWithdrawAndDeposit_Client, in, result, mods...)
 	if err != nil {
 		return result, fmt.Errorf("failed to Invoke method %q: %w", JSONRPCMethodWithdrawAndDeposit_Client, err)
@@ -77,3 +85,19 @@ func (c *implSeamlessV2ServiceClient) WithdrawAndDeposit(ctx context.Context, in
 
 	return result, nil
 }
+
+func (c *implSeamlessV2ServiceClient) GetTransactionHistory(ctx context.Context, in *types.GetTransactionHistoryRequest, mods ...client.Mod) (result *types.GetTransactionHistoryResponse, err error) {
+	gen, err := uuid.NewUUID()
+	if err != nil {
+		return result, fmt.Errorf("failed to create uuid generator: %w", err)
+	}
+
+	result = new(types.GetTransactionHistoryResponse)
+
+	err = c.cl.Invoke(ctx, gen.String(), JSONRPCMethodGetTransactionHistory_Client, in, result, mods...)
+	if err != nil {
+		return result, fmt.Errorf("failed to Invoke method %q: %w", JSONRPCMethodGetTransactionHistory_Client, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package togenerate
+
+import "github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+
+// AdminService интерфейс административного API кошелька для сотрудников поддержки
+//
+//nolint:lll
+//go:generate genpjrpc -search.name=AdminService -print.place.path_swagger_file=../../../swagger/admin.json -print.content.swagger_data_path=./swagger_data.json -print.place.path_client=../admin_client/generated -print.place.path_server=../admin/generated //nolint:lll
+type AdminService interface {
+	setErrorData(types.ErrorData)
+
+	//genpjrpc:params method_name=setPlayerStatus
+	SetPlayerStatus(request types.SetPlayerStatusRequest) types.SetPlayerStatusResponse
+	//genpjrpc:params method_name=creditPlayer
+	CreditPlayer(request types.AdjustBalanceRequest) types.AdjustBalanceResponse
+	//genpjrpc:params method_name=debitPlayer
+	DebitPlayer(request types.AdjustBalanceRequest) types.AdjustBalanceResponse
+	//genpjrpc:params method_name=forceRollback
+	ForceRollback(request types.ForceRollbackRequest) types.ForceRollbackResponse
+	//genpjrpc:params method_name=getPlayer
+	GetPlayer(request types.GetPlayerRequest) types.GetPlayerResponse
+	//genpjrpc:params method_name=getBalanceHistory
+	GetBalanceHistory(request types.GetBalanceHistoryRequest) types.GetBalanceHistoryResponse
+	//genpjrpc:params method_name=grantBonus
+	GrantBonus(request types.GrantBonusRequest) types.GrantBonusResponse
+	//genpjrpc:params method_name=createCurrency
+	CreateCurrency(request types.CreateCurrencyRequest) types.Currency
+	//genpjrpc:params method_name=listCurrencies
+	ListCurrencies(request types.ListCurrenciesRequest) types.ListCurrenciesResponse
+	//genpjrpc:params method_name=setCurrencyEnabled
+	SetCurrencyEnabled(request types.SetCurrencyEnabledRequest) types.Currency
+	//genpjrpc:params method_name=createBankGroup
+	CreateBankGroup(request types.CreateBankGroupRequest) types.BankGroup
+	//genpjrpc:params method_name=updateBankGroup
+	UpdateBankGroup(request types.UpdateBankGroupRequest) types.BankGroup
+	//genpjrpc:params method_name=listBankGroups
+	ListBankGroups(request types.ListBankGroupsRequest) types.ListBankGroupsResponse
+	//genpjrpc:params method_name=deleteBankGroup
+	DeleteBankGroup(request types.DeleteBankGroupRequest) types.DeleteBankGroupResponse
+	//genpjrpc:params method_name=setOperatorBankGroup
+	SetOperatorBankGroup(request types.SetOperatorBankGroupRequest) types.SetOperatorBankGroupResponse
+	//genpjrpc:params method_name=registerOperatorWebhook
+	RegisterOperatorWebhook(request types.RegisterOperatorWebhookRequest) types.RegisterOperatorWebhookResponse
+	//genpjrpc:params method_name=deactivateOperatorWebhook
+	DeactivateOperatorWebhook(request types.DeactivateOperatorWebhookRequest) types.DeactivateOperatorWebhookResponse
+	//genpjrpc:params method_name=replayWebhookDeadLetter
+	ReplayWebhookDeadLetter(request types.ReplayWebhookDeadLetterRequest) types.ReplayWebhookDeadLetterResponse
+	//genpjrpc:params method_name=getRevenueReport
+	GetRevenueReport(request types.GetRevenueReportRequest) types.GetRevenueReportResponse
+}
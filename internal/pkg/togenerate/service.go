@@ -12,8 +12,10 @@ type SeamlessV2Service interface {
 
 	//genpjrpc:params method_name=getBalance
 	GetBalance(request types.GetBalanceRequest) types.GetBalanceResponse
-	//genpjrpc:params method_name=withdrawAndDeposit
-	RollbackTransaction(request types.RollbackTransactionRequest) types.RollbackTransactionResponse
 	//genpjrpc:params method_name=rollbackTransaction
+	RollbackTransaction(request types.RollbackTransactionRequest) types.RollbackTransactionResponse
+	//genpjrpc:params method_name=withdrawAndDeposit
 	WithdrawAndDeposit(request types.WithdrawAndDepositRequest) types.WithdrawAndDepositResponse
+	//genpjrpc:params method_name=getTransactionHistory
+	GetTransactionHistory(request types.GetTransactionHistoryRequest) types.GetTransactionHistoryResponse
 }
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"gitlab.com/pjrpc/pjrpc/v2"
+)
+
+// codeServerError код, который pjrpc отдаёт для ошибок без своего кода, см. pjrpc.JRPCErrServerError.
+const codeServerError = -32000
+
+// Middleware span "<service>.<method>" на каждый вызов JSON-RPC. Родительский контекст берётся из заголовков
+// HTTP запроса, если вызывающий его передал. params и ответ в теги не попадают: в них имена игроков и суммы,
+// тегами пишутся только метод, id запроса, callerId и признак пакета.
+func Middleware(service string) pjrpc.Middleware {
+	return func(next pjrpc.Handler) pjrpc.Handler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			tracer := opentracing.GlobalTracer()
+
+			method := "unknown"
+			options := []opentracing.StartSpanOption{ext.SpanKindRPCServer}
+			tags := opentracing.Tags{string(ext.Component): "jsonrpc"}
+
+			if data, ok := pjrpc.ContextGetData(ctx); ok {
+				method = data.JRPCRequest.Method
+				tags["rpc.request_id"] = data.JRPCRequest.GetID()
+				tags["rpc.batch"] = data.IsBatch
+
+				if parent, errExtract := tracer.Extract(
+					opentracing.HTTPHeaders,
+					opentracing.HTTPHeadersCarrier(data.HTTTRequest.Header),
+				); errExtract == nil {
+					options = append(options, opentracing.ChildOf(parent))
+				}
+			}
+
+			if callerID := callerID(params); callerID != 0 {
+				tags["rpc.caller_id"] = callerID
+			}
+
+			span := tracer.StartSpan(service+"."+method, append(options, tags)...)
+			defer span.Finish()
+
+			res, err := next(opentracing.ContextWithSpan(ctx, span), params)
+			if err != nil {
+				setError(span, err)
+				span.SetTag("rpc.error_code", errorCode(err))
+			}
+
+			return res, err
+		}
+	}
+}
+
+// setError сообщения ошибок сервиса не содержат данных игроков, поэтому пишутся в лог span как есть.
+func setError(span opentracing.Span, err error) {
+	ext.Error.Set(span, true)
+	span.LogFields(log.String("event", "error"), log.String("message", err.Error()))
+}
+
+func errorCode(err error) int {
+	var errResponse *pjrpc.ErrorResponse
+	if errors.As(err, &errResponse) {
+		return errResponse.Code
+	}
+
+	return codeServerError
+}
+
+func callerID(params json.RawMessage) int {
+	var caller struct {
+		CallerID int `json:"callerId"`
+	}
+
+	_ = json.Unmarshal(params, &caller)
+
+	return caller.CallerID
+}
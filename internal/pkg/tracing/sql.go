@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+var _ driver.Connector = (*connector)(nil)
+
+// NewConnector соединения drv, у которых каждый запрос с контекстом получает дочерний span с текстом SQL
+// и числом строк. Аргументы запросов в span не пишутся. Запросы без span в контексте (фоновые задачи)
+// не трассируются, чтобы не плодить корневые трассы на каждый опрос очередей.
+//
+//	db := sqlx.NewDb(sql.OpenDB(tracing.NewConnector(&pq.Driver{}, dsn)), "postgres")
+func NewConnector(drv driver.Driver, dsn string) driver.Connector {
+	return &connector{driver: drv, dsn: dsn}
+}
+
+type connector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	inner, errOpen := c.driver.Open(c.dsn)
+	if errOpen != nil {
+		return nil, errOpen //nolint:wrapcheck // intentional
+	}
+
+	return &conn{Conn: inner}, nil
+}
+
+//nolint:ireturn // intentional
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// conn интерфейсы, которых нет у соединения драйвера, отвечают driver.ErrSkip, и database/sql
+// переходит к Prepare, как без обёртки.
+type conn struct {
+	driver.Conn
+}
+
+//nolint:ireturn // intentional
+func (c *conn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, options) //nolint:wrapcheck // intentional
+	}
+
+	return c.Conn.Begin() //nolint:staticcheck,wrapcheck // у драйвера нет BeginTx
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx) //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+//nolint:ireturn // intentional
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span := startSpan(ctx, "sql.query", query)
+	if span == nil {
+		return queryer.QueryContext(ctx, query, args) //nolint:wrapcheck // intentional
+	}
+
+	inner, errQuery := queryer.QueryContext(ctx, query, args)
+	if errQuery != nil {
+		finishSpan(span, errQuery)
+
+		return nil, errQuery //nolint:wrapcheck // intentional
+	}
+
+	return &rows{Rows: inner, span: span}, nil
+}
+
+//nolint:ireturn // intentional
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span := startSpan(ctx, "sql.exec", query)
+	if span == nil {
+		return execer.ExecContext(ctx, query, args) //nolint:wrapcheck // intentional
+	}
+
+	result, errExec := execer.ExecContext(ctx, query, args)
+	if errExec == nil {
+		if affected, errRowsAffected := result.RowsAffected(); errRowsAffected == nil {
+			span.SetTag("db.rows_affected", affected)
+		}
+	}
+
+	finishSpan(span, errExec)
+
+	return result, errExec //nolint:wrapcheck // intentional
+}
+
+func startSpan(ctx context.Context, operation, query string) opentracing.Span {
+	parent := opentracing.SpanFromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+
+	span := parent.Tracer().StartSpan(operation, opentracing.ChildOf(parent.Context()), ext.SpanKindRPCClient)
+	ext.Component.Set(span, "database/sql")
+	ext.DBType.Set(span, "sql")
+	ext.DBStatement.Set(span, query)
+
+	return span
+}
+
+func finishSpan(span opentracing.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		setError(span, err)
+	}
+
+	span.Finish()
+}
+
+// rows span закрывается вместе со строками, поэтому в него попадает и время чтения результата.
+type rows struct {
+	driver.Rows
+	span  opentracing.Span
+	count int
+	err   error
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	errNext := r.Rows.Next(dest)
+
+	switch {
+	case errNext == nil:
+		r.count++
+	case !errors.Is(errNext, io.EOF):
+		r.err = errNext
+	}
+
+	return errNext //nolint:wrapcheck // intentional
+}
+
+func (r *rows) Close() error {
+	errClose := r.Rows.Close()
+
+	if r.span != nil {
+		r.span.SetTag("db.rows", r.count)
+
+		if r.err == nil {
+			r.err = errClose
+		}
+
+		finishSpan(r.span, r.err)
+		r.span = nil
+	}
+
+	return errClose //nolint:wrapcheck // intentional
+}
@@ -0,0 +1,22 @@
+package types
+
+type AdjustBalanceRequest struct {
+	PlayerName string           `json:"playerName"`
+	Amount     int              `json:"amount"`
+	ReasonCode AdjustmentReason `json:"reasonCode"`
+	Comment    string           `json:"comment"`
+}
+
+type AdjustmentReason string
+
+const (
+	AdjustmentGoodwill     AdjustmentReason = "GOODWILL"
+	AdjustmentCorrection   AdjustmentReason = "CORRECTION"
+	AdjustmentCompensation AdjustmentReason = "COMPENSATION"
+	AdjustmentChargeback   AdjustmentReason = "CHARGEBACK"
+)
+
+type AdjustBalanceResponse struct {
+	NewBalance     int    `json:"newBalance"`
+	TransactionRef string `json:"transactionRef"`
+}
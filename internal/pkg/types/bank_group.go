@@ -0,0 +1,60 @@
+package types
+
+type BankGroup struct {
+	ID string `json:"id"`
+	// CurrencyCode валюта по умолчанию, CurrencyCodes все разрешённые валюты группы.
+	CurrencyCode        string   `json:"currencyCode"`
+	CurrencyCodes       []string `json:"currencyCodes"`
+	StartingBalance     int      `json:"startingBalance"`
+	BetMin              int      `json:"betMin"`
+	BetMax              int      `json:"betMax"`
+	RoundingStep        int      `json:"roundingStep"`
+	RoundingMode        string   `json:"roundingMode"`
+	FreeRounds          string   `json:"freeRounds"`
+	FreeRoundsMaxCharge int      `json:"freeRoundsMaxCharge"`
+	CurrencyConversion  bool     `json:"currencyConversion"`
+}
+
+type CreateBankGroupRequest struct {
+	ID           string `json:"id"`
+	CurrencyCode string `json:"currencyCode"`
+}
+
+// UpdateBankGroupRequest заменяет настройки группы целиком. BetMax и FreeRoundsMaxCharge 0 - без ограничения,
+// RoundingMode none|down|half_up, FreeRounds allow|deny. CurrencyConversion пересчитывает ставки в валюте игры
+// в валюту кошелька по курсу.
+type UpdateBankGroupRequest struct {
+	ID                  string   `json:"id"`
+	CurrencyCodes       []string `json:"currencyCodes"`
+	StartingBalance     int      `json:"startingBalance"`
+	BetMin              int      `json:"betMin"`
+	BetMax              int      `json:"betMax"`
+	RoundingStep        int      `json:"roundingStep"`
+	RoundingMode        string   `json:"roundingMode"`
+	FreeRounds          string   `json:"freeRounds"`
+	FreeRoundsMaxCharge int      `json:"freeRoundsMaxCharge"`
+	CurrencyConversion  bool     `json:"currencyConversion"`
+}
+
+type ListBankGroupsRequest struct {
+	// CurrencyCode пустой - группы всех валют.
+	CurrencyCode string `json:"currencyCode,omitempty"`
+}
+
+type ListBankGroupsResponse struct {
+	BankGroups []BankGroup `json:"bankGroups"`
+}
+
+type DeleteBankGroupRequest struct {
+	ID string `json:"id"`
+}
+
+type DeleteBankGroupResponse struct{}
+
+// SetOperatorBankGroupRequest пустой BankGroupID отвязывает оператора, он работает с настройками по умолчанию.
+type SetOperatorBankGroupRequest struct {
+	OperatorID  int    `json:"operatorId"`
+	BankGroupID string `json:"bankGroupId"`
+}
+
+type SetOperatorBankGroupResponse struct{}
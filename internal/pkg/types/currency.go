@@ -0,0 +1,29 @@
+package types
+
+type CreateCurrencyRequest struct {
+	ID                                     int    `json:"id"`
+	Code                                   string `json:"code"`
+	NumberOfDigitsAfterTheDecimalSeparator int    `json:"numberOfDigitsAfterTheDecimalSeparator"`
+	Name                                   string `json:"name"`
+	// Replaces код валюты, которую новая заменяет (SLE вместо SLL), она будет отключена.
+	Replaces string `json:"replaces,omitempty"`
+}
+
+type Currency struct {
+	ID                                     int    `json:"id"`
+	Code                                   string `json:"code"`
+	NumberOfDigitsAfterTheDecimalSeparator int    `json:"numberOfDigitsAfterTheDecimalSeparator"`
+	Name                                   string `json:"name"`
+	Enabled                                bool   `json:"enabled"`
+}
+
+type SetCurrencyEnabledRequest struct {
+	Code    string `json:"code"`
+	Enabled bool   `json:"enabled"`
+}
+
+type ListCurrenciesRequest struct{}
+
+type ListCurrenciesResponse struct {
+	Currencies []Currency `json:"currencies"`
+}
@@ -0,0 +1,11 @@
+package types
+
+type ForceRollbackRequest struct {
+	TransactionRef string `json:"transactionRef"`
+	Comment        string `json:"comment"`
+}
+
+type ForceRollbackResponse struct {
+	PlayerName string `json:"playerName"`
+	NewBalance int    `json:"newBalance"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+type GetBalanceHistoryRequest struct {
+	PlayerName string `json:"playerName"`
+	Limit      int    `json:"limit"`
+}
+
+type GetBalanceHistoryResponse struct {
+	Items []BalanceHistoryItem `json:"items"`
+}
+
+type BalanceHistoryItem struct {
+	ID             int        `json:"id"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	RollbackAt     *time.Time `json:"rollbackAt,omitempty"`
+	Withdraw       int        `json:"withdraw"`
+	Deposit        int        `json:"deposit"`
+	Balance        int        `json:"balance"`
+	TransactionRef string     `json:"transactionRef"`
+}
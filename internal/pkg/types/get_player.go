@@ -0,0 +1,18 @@
+package types
+
+import "time"
+
+type GetPlayerRequest struct {
+	PlayerName string `json:"playerName"`
+}
+
+type GetPlayerResponse struct {
+	ID            int          `json:"id"`
+	PlayerName    string       `json:"playerName"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	Status        PlayerStatus `json:"status"`
+	ExcludedUntil *time.Time   `json:"excludedUntil,omitempty"`
+	StatusReason  string       `json:"statusReason"`
+	Currency      string       `json:"currency"`
+	Balance       int          `json:"balance"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+type GetTransactionHistoryRequest struct {
+	CallerID     int        `json:"callerId"`
+	PlayerName   string     `json:"playerName"`
+	From         *time.Time `json:"from,omitempty"`
+	To           *time.Time `json:"to,omitempty"`
+	GameID       string     `json:"gameId,omitempty"`
+	GameRoundRef string     `json:"gameRoundRef,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	RolledBack   *bool      `json:"rolledBack,omitempty"`
+	Cursor       int        `json:"cursor,omitempty"`
+	Limit        int        `json:"limit,omitempty"`
+}
+
+type GetTransactionHistoryResponse struct {
+	Transactions []Transaction     `json:"transactions"`
+	NextCursor   int               `json:"nextCursor"`
+	Totals       TransactionTotals `json:"totals"`
+}
+
+type Transaction struct {
+	ID             int        `json:"id"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	RollbackAt     *time.Time `json:"rollbackAt,omitempty"`
+	TransactionRef string     `json:"transactionRef"`
+	GameID         string     `json:"gameId"`
+	GameRoundRef   string     `json:"gameRoundRef"`
+	Reason         string     `json:"reason"`
+	Withdraw       int        `json:"withdraw"`
+	Deposit        int        `json:"deposit"`
+	Balance        int        `json:"balance"`
+}
+
+type TransactionTotals struct {
+	Count    int `json:"count"`
+	Withdraw int `json:"withdraw"`
+	Deposit  int `json:"deposit"`
+}
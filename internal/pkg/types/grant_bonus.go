@@ -0,0 +1,9 @@
+package types
+
+type GrantBonusRequest struct {
+	BonusID    string `json:"bonusId"`
+	PlayerName string `json:"playerName"`
+	Comment    string `json:"comment"`
+}
+
+type GrantBonusResponse struct{}
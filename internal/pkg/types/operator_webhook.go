@@ -0,0 +1,25 @@
+package types
+
+type RegisterOperatorWebhookRequest struct {
+	OperatorID int    `json:"operatorId"`
+	URL        string `json:"url"`
+	Secret     string `json:"secret"`
+}
+
+type RegisterOperatorWebhookResponse struct {
+	ID int `json:"id"`
+}
+
+type DeactivateOperatorWebhookRequest struct {
+	ID int `json:"id"`
+}
+
+type DeactivateOperatorWebhookResponse struct{}
+
+type ReplayWebhookDeadLetterRequest struct {
+	ID int64 `json:"id"`
+}
+
+type ReplayWebhookDeadLetterResponse struct {
+	DeliveryID int64 `json:"deliveryId"`
+}
@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+type GetRevenueReportRequest struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	OperatorID *int      `json:"operatorId,omitempty"`
+	GameID     string    `json:"gameId,omitempty"`
+	Currency   string    `json:"currency,omitempty"`
+}
+
+type GetRevenueReportResponse struct {
+	Rows   []RevenueRow   `json:"rows"`
+	Totals []RevenueTotal `json:"totals"`
+}
+
+type RevenueRow struct {
+	Day         string `json:"day"`
+	OperatorID  int    `json:"operatorId"`
+	GameID      string `json:"gameId"`
+	Currency    string `json:"currency"`
+	Bets        int    `json:"bets"`
+	Stake       int64  `json:"stake"`
+	Win         int64  `json:"win"`
+	GGR         int64  `json:"ggr"`
+	Adjustments int64  `json:"adjustments"`
+	NGR         int64  `json:"ngr"`
+}
+
+// RevenueTotal итог за период по одной валюте, суммы в разных валютах не складываются.
+type RevenueTotal struct {
+	Currency    string `json:"currency"`
+	Bets        int    `json:"bets"`
+	Stake       int64  `json:"stake"`
+	Win         int64  `json:"win"`
+	GGR         int64  `json:"ggr"`
+	Adjustments int64  `json:"adjustments"`
+	NGR         int64  `json:"ngr"`
+}
@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+type SetPlayerStatusRequest struct {
+	PlayerName    string       `json:"playerName"`
+	Status        PlayerStatus `json:"status"`
+	ExcludedUntil *time.Time   `json:"excludedUntil,omitempty"`
+	Reason        string       `json:"reason"`
+}
+
+type PlayerStatus string
+
+const (
+	PlayerActive       PlayerStatus = "active"
+	PlayerSuspended    PlayerStatus = "suspended"
+	PlayerSelfExcluded PlayerStatus = "self_excluded"
+	PlayerClosed       PlayerStatus = "closed"
+)
+
+type SetPlayerStatusResponse struct {
+	PlayerName    string       `json:"playerName"`
+	Status        PlayerStatus `json:"status"`
+	ExcludedUntil *time.Time   `json:"excludedUntil,omitempty"`
+}
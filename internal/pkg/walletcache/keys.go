@@ -0,0 +1,106 @@
+package walletcache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+)
+
+const keyPrefix = "wallet:"
+
+// userVersionKey версия записи игрока. Игрок кэшируется под текущей версией, а InvalidateUser заводит
+// новую: заполнение транзакции, прочитавшей игрока до изменения статуса, ляжет под старую версию
+// и больше не прочитается, даже если запишется уже после сброса.
+func userVersionKey(name string) string {
+	return keyPrefix + "user:version:" + name
+}
+
+// userVersionTTL версия живёт дольше записей игрока. Истёкшая версия заводится заново, старые записи
+// просто перестают читаться.
+const userVersionTTL = 24 * time.Hour
+
+func userKey(version, name string) string {
+	return keyPrefix + "user:" + version + ":" + name
+}
+
+func currencyIDKey(id int) string {
+	return keyPrefix + "currency:id:" + strconv.Itoa(id)
+}
+
+func currencyCodeKey(code string) string {
+	return keyPrefix + "currency:code:" + code
+}
+
+func walletCurrencyKey(userID int) string {
+	return keyPrefix + "wallet-currency:" + strconv.Itoa(userID)
+}
+
+func balanceKey(userID int) string {
+	return keyPrefix + "balance:" + strconv.Itoa(userID)
+}
+
+// bankGroupVersionKey версия настроек банковских групп. Группа оператора кэшируется под текущей версией,
+// поэтому любое изменение групп сбрасывает весь кэш групп одной записью.
+const bankGroupVersionKey = keyPrefix + "bank-group:version"
+
+func bankGroupKey(version string, operatorID int) string {
+	return keyPrefix + "bank-group:" + version + ":" + strconv.Itoa(operatorID)
+}
+
+// Функции Invalidate вызываются после фиксации изменений в базе в обход Store, например из админки.
+// cache nil - кэш выключен.
+
+func InvalidateUser(ctx context.Context, cache cashstore.Cache, name string) error {
+	if cache == nil {
+		return nil
+	}
+
+	return cache.Set(ctx, userVersionKey(name), newVersion(), userVersionTTL) //nolint:wrapcheck // intentional
+}
+
+func InvalidateCurrency(ctx context.Context, cache cashstore.Cache, id int, code string) error {
+	if cache == nil {
+		return nil
+	}
+
+	return cache.Delete(ctx, currencyIDKey(id), currencyCodeKey(code)) //nolint:wrapcheck // intentional
+}
+
+func InvalidateBalance(ctx context.Context, cache cashstore.Cache, userID int) error {
+	if cache == nil {
+		return nil
+	}
+
+	return cache.Delete(ctx, balanceKey(userID)) //nolint:wrapcheck // intentional
+}
+
+func InvalidateBankGroups(ctx context.Context, cache cashstore.Cache) error {
+	if cache == nil {
+		return nil
+	}
+
+	return cache.Set(ctx, bankGroupVersionKey, newVersion(), 0) //nolint:wrapcheck // intentional
+}
+
+func newVersion() []byte {
+	return []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+// currentVersion текущая версия по ключу versionKey. Если ключ вытеснен, заводится новая версия: старые
+// записи под прежней версией больше не читаются и доживают свой ttl.
+func currentVersion(ctx context.Context, cache cashstore.Cache, versionKey string, ttl time.Duration) (string, error) {
+	version, ok, errGet := cache.Get(ctx, versionKey)
+	if errGet != nil || ok {
+		return string(version), errGet //nolint:wrapcheck // intentional
+	}
+
+	if _, errSetNX := cache.SetNX(ctx, versionKey, newVersion(), ttl); errSetNX != nil {
+		return "", errSetNX //nolint:wrapcheck // intentional
+	}
+
+	version, _, errGet = cache.Get(ctx, versionKey)
+
+	return string(version), errGet //nolint:wrapcheck // intentional
+}
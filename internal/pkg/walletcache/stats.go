@@ -0,0 +1,101 @@
+package walletcache
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// Виды закэшированных данных, по ним ведётся статистика.
+const (
+	KindUser           = "user"
+	KindCurrency       = "currency"
+	KindWalletCurrency = "wallet_currency"
+	KindBalance        = "balance"
+	KindBankGroup      = "bank_group"
+	// KindTotal итог по всем видам в Snapshot.
+	KindTotal = "total"
+)
+
+var kinds = []string{KindUser, KindCurrency, KindWalletCurrency, KindBalance, KindBankGroup}
+
+// Stats счётчики обращений к кэшу. Ошибка кэша считается и ошибкой, и промахом.
+type Stats struct {
+	counters map[string]*counters
+}
+
+type counters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+type KindStats struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	Errors   int64   `json:"errors"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+func NewStats() *Stats {
+	stats := &Stats{counters: make(map[string]*counters, len(kinds))}
+	for _, kind := range kinds {
+		stats.counters[kind] = &counters{}
+	}
+
+	return stats
+}
+
+func (s *Stats) hit(kind string) {
+	s.counters[kind].hits.Add(1)
+}
+
+func (s *Stats) miss(kind string) {
+	s.counters[kind].misses.Add(1)
+}
+
+func (s *Stats) error(kind string) {
+	s.counters[kind].errors.Add(1)
+	s.counters[kind].misses.Add(1)
+}
+
+// Snapshot статистика по видам данных и итог под ключом KindTotal.
+func (s *Stats) Snapshot() map[string]KindStats {
+	result := make(map[string]KindStats, len(kinds)+1)
+
+	var total KindStats
+
+	for _, kind := range kinds {
+		item := KindStats{
+			Hits:   s.counters[kind].hits.Load(),
+			Misses: s.counters[kind].misses.Load(),
+			Errors: s.counters[kind].errors.Load(),
+		}
+		item.HitRatio = hitRatio(item.Hits, item.Misses)
+		result[kind] = item
+
+		total.Hits += item.Hits
+		total.Misses += item.Misses
+		total.Errors += item.Errors
+	}
+
+	total.HitRatio = hitRatio(total.Hits, total.Misses)
+	result[KindTotal] = total
+
+	return result
+}
+
+func hitRatio(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(hits+misses)
+}
+
+// ServeHTTP отдаёт Snapshot в JSON.
+func (s *Stats) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(s.Snapshot())
+}
@@ -0,0 +1,375 @@
+package walletcache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/rpcbatch"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+var _ seamlessv2.Store = (*Store)(nil)
+
+// Store кэширует поверх другого seamlessv2.Store справочники, игроков, валюту кошелька и баланс.
+// База остаётся источником истины: кэш пишется только после фиксации транзакции, ошибка кэша
+// означает чтение из базы.
+//
+// Баланс из кэша отдаётся только вызовам, которые его не меняют. Вызовы с seamlessv2.WithBalanceUpdate
+// и вызовы внутри атомарного пакета читают баланс из базы, а после фиксации записывают новый баланс в кэш.
+// Записи параллельных транзакций могут лечь в кэш не в порядке фиксации, такое расхождение живёт не дольше ttl.
+type Store struct {
+	inner  seamlessv2.Store
+	cache  cashstore.Cache
+	ttl    time.Duration
+	stats  *Stats
+	logger *zap.Logger
+}
+
+func NewStore(inner seamlessv2.Store, cache cashstore.Cache, ttl time.Duration, stats *Stats, logger *zap.Logger) *Store {
+	return &Store{
+		inner:  inner,
+		cache:  cache,
+		ttl:    ttl,
+		stats:  stats,
+		logger: logger,
+	}
+}
+
+//nolint:ireturn // intentional
+func (s *Store) Begin(ctx context.Context) (seamlessv2.StoreTx, error) {
+	inner, errBegin := s.inner.Begin(ctx)
+	if errBegin != nil {
+		return nil, errBegin //nolint:wrapcheck // intentional
+	}
+
+	return &storeTx{
+		StoreTx:  inner,
+		store:    s,
+		balances: make(map[int]int),
+		stale:    make(map[int]bool),
+		touched:  make(map[int]bool),
+	}, nil
+}
+
+// load второй результат false - промах, значение нужно читать из базы.
+func load[T any](ctx context.Context, s *Store, kind, key string, value *T) bool {
+	raw, ok, errGet := s.cache.Get(ctx, key)
+	if errGet == nil && ok {
+		errGet = json.Unmarshal(raw, value)
+	}
+
+	switch {
+	case errGet != nil:
+		s.stats.error(kind)
+		s.logger.Debug("cache read failed", zap.String("key", key), zap.Error(errGet))
+
+		return false
+	case !ok:
+		s.stats.miss(kind)
+
+		return false
+	}
+
+	s.stats.hit(kind)
+
+	return true
+}
+
+// store запись после фиксации транзакции. Контекст вызова к этому моменту может быть уже отменён,
+// а пропущенная запись оставила бы в кэше старое значение, поэтому используется свой контекст.
+// onlyNew - заполнение после промаха: не затирает значение, которое успел записать платёж.
+func (s *Store) store(key string, value interface{}, onlyNew bool) {
+	raw, errMarshal := json.Marshal(value)
+	if errMarshal != nil {
+		s.logger.Debug("cache marshal failed", zap.String("key", key), zap.Error(errMarshal))
+
+		return
+	}
+
+	var errSet error
+	if onlyNew {
+		_, errSet = s.cache.SetNX(context.Background(), key, raw, s.ttl)
+	} else {
+		errSet = s.cache.Set(context.Background(), key, raw, s.ttl)
+	}
+
+	if errSet != nil {
+		s.logger.Debug("cache write failed", zap.String("key", key), zap.Error(errSet))
+		s.delete(key)
+	}
+}
+
+func (s *Store) delete(keys ...string) {
+	if errDelete := s.cache.Delete(context.Background(), keys...); errDelete != nil {
+		s.logger.Warn("cache invalidation failed", zap.Strings("keys", keys), zap.Error(errDelete))
+	}
+}
+
+type storeTx struct {
+	seamlessv2.StoreTx
+	store *Store
+	// balances баланс игрока, прочитанный из базы в этой транзакции, с учётом её платежей
+	balances map[int]int
+	// stale баланс игрока изменён, но итог неизвестен: после фиксации ключ удаляется
+	stale map[int]bool
+	// touched для игрока уже запланирована запись баланса после фиксации
+	touched map[int]bool
+}
+
+//nolint:ireturn // intentional
+func (t *storeTx) Users() seamlessv2.UserRepository {
+	return users{UserRepository: t.StoreTx.Users(), tx: t}
+}
+
+//nolint:ireturn // intentional
+func (t *storeTx) Wallets() seamlessv2.WalletRepository {
+	return wallets{WalletRepository: t.StoreTx.Wallets(), tx: t}
+}
+
+//nolint:ireturn // intentional
+func (t *storeTx) Payments() seamlessv2.PaymentRepository {
+	return payments{PaymentRepository: t.StoreTx.Payments(), tx: t}
+}
+
+//nolint:ireturn // intentional
+func (t *storeTx) Currencies() seamlessv2.CurrencyRepository {
+	return currencies{CurrencyRepository: t.StoreTx.Currencies(), tx: t}
+}
+
+//nolint:ireturn // intentional
+func (t *storeTx) BankGroups() seamlessv2.BankGroupRepository {
+	return bankGroups{BankGroupRepository: t.StoreTx.BankGroups(), tx: t}
+}
+
+func (t *storeTx) fill(key string, value interface{}) {
+	t.AfterCommit(func() { t.store.store(key, value, true) })
+}
+
+// balanceChanged платёж игрока изменил баланс на delta. Итоговый баланс пишется в кэш один раз
+// после фиксации, если он был прочитан из базы в этой же транзакции, иначе ключ удаляется.
+func (t *storeTx) balanceChanged(userID, delta int) {
+	if balance, ok := t.balances[userID]; ok && !t.stale[userID] {
+		t.balances[userID] = balance + delta
+	} else {
+		t.stale[userID] = true
+	}
+
+	if t.touched[userID] {
+		return
+	}
+
+	t.touched[userID] = true
+
+	t.AfterCommit(func() {
+		if t.stale[userID] {
+			t.store.delete(balanceKey(userID))
+
+			return
+		}
+
+		t.store.store(balanceKey(userID), t.balances[userID], false)
+	})
+}
+
+type users struct {
+	seamlessv2.UserRepository
+	tx *storeTx
+}
+
+// FindByName версия читается до базы: если статус игрока изменят после этого чтения, запись ляжет
+// под старую версию и не вернёт старый статус.
+func (u users) FindByName(ctx context.Context, name string) (*repo.User, error) {
+	version, errVersion := currentVersion(ctx, u.tx.store.cache, userVersionKey(name), userVersionTTL)
+	if errVersion != nil {
+		u.tx.store.stats.error(KindUser)
+
+		return u.UserRepository.FindByName(ctx, name) //nolint:wrapcheck // intentional
+	}
+
+	key := userKey(version, name)
+
+	var user repo.User
+	if load(ctx, u.tx.store, KindUser, key, &user) {
+		return &user, nil
+	}
+
+	found, errFindByName := u.UserRepository.FindByName(ctx, name)
+	if errFindByName != nil {
+		return nil, errFindByName //nolint:wrapcheck // intentional
+	}
+
+	u.tx.fill(key, found)
+
+	return found, nil
+}
+
+type wallets struct {
+	seamlessv2.WalletRepository
+	tx *storeTx
+}
+
+func (w wallets) Balance(ctx context.Context, userID int) (int, error) {
+	fresh := seamlessv2.IsBalanceUpdate(ctx) || rpcbatch.InBatch(ctx)
+
+	var balance int
+	if !fresh && load(ctx, w.tx.store, KindBalance, balanceKey(userID), &balance) {
+		return balance, nil
+	}
+
+	balance, errBalance := w.WalletRepository.Balance(ctx, userID)
+	if errBalance != nil {
+		return 0, errBalance //nolint:wrapcheck // intentional
+	}
+
+	if fresh {
+		if _, ok := w.tx.balances[userID]; !ok && !w.tx.stale[userID] {
+			w.tx.balances[userID] = balance
+		}
+
+		return balance, nil
+	}
+
+	w.tx.fill(balanceKey(userID), balance)
+
+	return balance, nil
+}
+
+// CurrencyID кэшируется только найденная валюта: она задаётся первым платежом и больше не меняется.
+func (w wallets) CurrencyID(ctx context.Context, userID int) (int, error) {
+	var currencyID int
+	if load(ctx, w.tx.store, KindWalletCurrency, walletCurrencyKey(userID), &currencyID) {
+		return currencyID, nil
+	}
+
+	currencyID, errCurrencyID := w.WalletRepository.CurrencyID(ctx, userID)
+	if errCurrencyID != nil {
+		return 0, errCurrencyID //nolint:wrapcheck // intentional
+	}
+
+	if currencyID != -1 {
+		w.tx.fill(walletCurrencyKey(userID), currencyID)
+	}
+
+	return currencyID, nil
+}
+
+type payments struct {
+	seamlessv2.PaymentRepository
+	tx *storeTx
+}
+
+func (p payments) Create(
+	ctx context.Context,
+	userID, currencyID, withdraw, deposit int,
+	transactionRef string,
+	paymentContext repo.PaymentContext,
+) (*repo.Payment, error) {
+	payment, errCreate := p.PaymentRepository.Create(
+		ctx,
+		userID,
+		currencyID,
+		withdraw,
+		deposit,
+		transactionRef,
+		paymentContext,
+	)
+	if errCreate != nil {
+		return nil, errCreate //nolint:wrapcheck // intentional
+	}
+
+	p.tx.balanceChanged(userID, deposit-withdraw)
+
+	return payment, nil
+}
+
+func (p payments) Rollback(ctx context.Context, transactionRef string) ([]repo.Payment, error) {
+	rolledBack, errRollback := p.PaymentRepository.Rollback(ctx, transactionRef)
+	if errRollback != nil {
+		return nil, errRollback //nolint:wrapcheck // intentional
+	}
+
+	// повторный откат ничего не возвращает, поэтому каждый откаченный платёж меняет баланс
+	for _, payment := range rolledBack {
+		p.tx.balanceChanged(payment.UserID, payment.Withdraw-payment.Deposit)
+	}
+
+	return rolledBack, nil
+}
+
+type currencies struct {
+	seamlessv2.CurrencyRepository
+	tx *storeTx
+}
+
+func (c currencies) ByID(ctx context.Context, id int) (repo.Currency, error) {
+	var currency repo.Currency
+	if load(ctx, c.tx.store, KindCurrency, currencyIDKey(id), &currency) {
+		return currency, nil
+	}
+
+	currency, errByID := c.CurrencyRepository.ByID(ctx, id)
+	if errByID != nil {
+		return repo.Currency{}, errByID //nolint:wrapcheck // intentional
+	}
+
+	c.tx.fill(currencyIDKey(id), currency)
+
+	return currency, nil
+}
+
+func (c currencies) ByCode(ctx context.Context, code string) (*repo.Currency, error) {
+	var currency repo.Currency
+	if load(ctx, c.tx.store, KindCurrency, currencyCodeKey(code), &currency) {
+		return &currency, nil
+	}
+
+	found, errByCode := c.CurrencyRepository.ByCode(ctx, code)
+	if errByCode != nil || found == nil {
+		return found, errByCode //nolint:wrapcheck // intentional
+	}
+
+	c.tx.fill(currencyCodeKey(code), found)
+
+	return found, nil
+}
+
+type bankGroups struct {
+	seamlessv2.BankGroupRepository
+	tx *storeTx
+}
+
+// operatorBankGroup кэшируется и отсутствие группы, иначе операторы без группы всегда ходили бы в базу.
+type operatorBankGroup struct {
+	BankGroup   *repo.BankGroup `json:"bank_group"`
+	CurrencyIDs []int           `json:"currency_ids"`
+}
+
+func (b bankGroups) ByOperator(ctx context.Context, operatorID int) (*repo.BankGroup, []int, error) {
+	version, errVersion := currentVersion(ctx, b.tx.store.cache, bankGroupVersionKey, 0)
+	if errVersion != nil {
+		b.tx.store.stats.error(KindBankGroup)
+
+		return b.BankGroupRepository.ByOperator(ctx, operatorID) //nolint:wrapcheck // intentional
+	}
+
+	key := bankGroupKey(version, operatorID)
+
+	var cached operatorBankGroup
+	if load(ctx, b.tx.store, KindBankGroup, key, &cached) {
+		return cached.BankGroup, cached.CurrencyIDs, nil
+	}
+
+	bankGroup, currencyIDs, errByOperator := b.BankGroupRepository.ByOperator(ctx, operatorID)
+	if errByOperator != nil {
+		return nil, nil, errByOperator //nolint:wrapcheck // intentional
+	}
+
+	b.tx.fill(key, operatorBankGroup{BankGroup: bankGroup, CurrencyIDs: currencyIDs})
+
+	return bankGroup, currencyIDs, nil
+}
@@ -0,0 +1,180 @@
+package walletcache_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/memstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/types"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const (
+	// benchLatency имитирует сеть до базы на каждый запрос к репозиторию.
+	benchLatency = 100 * time.Microsecond
+	benchPlayers = 100
+)
+
+// BenchmarkGetBalance сравнивает getBalance без кэша и с кэшем кошельков:
+//
+//	go test -run '^$' -bench GetBalance ./internal/pkg/walletcache/
+func BenchmarkGetBalance(b *testing.B) {
+	for _, withCache := range []bool{false, true} {
+		name := "db"
+		if withCache {
+			name = "cache"
+		}
+
+		withCache := withCache
+
+		b.Run(name, func(b *testing.B) {
+			var store seamlessv2.Store = slowStore{
+				Store: memstore.New(repo.Currency{
+					ID:                                     978,
+					Code:                                   "EUR",
+					NumberOfDigitsAfterTheDecimalSeparator: 2,
+					Name:                                   "Euro",
+				}),
+				latency: benchLatency,
+			}
+
+			stats := walletcache.NewStats()
+			if withCache {
+				store = walletcache.NewStore(store, cashstore.NewLRU(0, 0), time.Minute, stats, zap.NewNop())
+			}
+
+			svc := seamlessv2.NewRPCService(store, nil, seamlessv2.DefaultStartingBalance, zap.NewNop())
+			ctx := opentracing.ContextWithSpan(context.Background(), opentracing.NoopTracer{}.StartSpan("bench"))
+
+			request := func(i int) *types.GetBalanceRequest {
+				return &types.GetBalanceRequest{PlayerName: "bench-" + strconv.Itoa(i%benchPlayers), Currency: "EUR"}
+			}
+
+			// первый вызов для каждого игрока заводит кошелёк и в замер не входит
+			for i := 0; i < benchPlayers; i++ {
+				if _, errGetBalance := svc.GetBalance(ctx, request(i)); errGetBalance != nil {
+					b.Fatalf("open wallet: %v", errGetBalance)
+				}
+			}
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, errGetBalance := svc.GetBalance(ctx, request(i)); errGetBalance != nil {
+					b.Fatalf("get balance: %v", errGetBalance)
+				}
+			}
+
+			if withCache {
+				b.ReportMetric(stats.Snapshot()[walletcache.KindTotal].HitRatio, "hit-ratio")
+			}
+		})
+	}
+}
+
+// slowStore memstore с паузой на каждый запрос, как будто до базы есть сеть.
+type slowStore struct {
+	*memstore.Store
+	latency time.Duration
+}
+
+//nolint:ireturn // intentional
+func (s slowStore) Begin(ctx context.Context) (seamlessv2.StoreTx, error) {
+	time.Sleep(s.latency)
+
+	tx, errBegin := s.Store.Begin(ctx)
+	if errBegin != nil {
+		return nil, errBegin //nolint:wrapcheck // intentional
+	}
+
+	return slowTx{StoreTx: tx, latency: s.latency}, nil
+}
+
+type slowTx struct {
+	seamlessv2.StoreTx
+	latency time.Duration
+}
+
+//nolint:ireturn // intentional
+func (t slowTx) Users() seamlessv2.UserRepository {
+	return slowUsers{UserRepository: t.StoreTx.Users(), latency: t.latency}
+}
+
+//nolint:ireturn // intentional
+func (t slowTx) Wallets() seamlessv2.WalletRepository {
+	return slowWallets{WalletRepository: t.StoreTx.Wallets(), latency: t.latency}
+}
+
+//nolint:ireturn // intentional
+func (t slowTx) Currencies() seamlessv2.CurrencyRepository {
+	return slowCurrencies{CurrencyRepository: t.StoreTx.Currencies(), latency: t.latency}
+}
+
+//nolint:ireturn // intentional
+func (t slowTx) BankGroups() seamlessv2.BankGroupRepository {
+	return slowBankGroups{BankGroupRepository: t.StoreTx.BankGroups(), latency: t.latency}
+}
+
+type slowUsers struct {
+	seamlessv2.UserRepository
+	latency time.Duration
+}
+
+func (u slowUsers) FindByName(ctx context.Context, name string) (*repo.User, error) {
+	time.Sleep(u.latency)
+
+	return u.UserRepository.FindByName(ctx, name) //nolint:wrapcheck // intentional
+}
+
+type slowWallets struct {
+	seamlessv2.WalletRepository
+	latency time.Duration
+}
+
+func (w slowWallets) Balance(ctx context.Context, userID int) (int, error) {
+	time.Sleep(w.latency)
+
+	return w.WalletRepository.Balance(ctx, userID) //nolint:wrapcheck // intentional
+}
+
+func (w slowWallets) CurrencyID(ctx context.Context, userID int) (int, error) {
+	time.Sleep(w.latency)
+
+	return w.WalletRepository.CurrencyID(ctx, userID) //nolint:wrapcheck // intentional
+}
+
+type slowCurrencies struct {
+	seamlessv2.CurrencyRepository
+	latency time.Duration
+}
+
+func (c slowCurrencies) ByID(ctx context.Context, id int) (repo.Currency, error) {
+	time.Sleep(c.latency)
+
+	return c.CurrencyRepository.ByID(ctx, id) //nolint:wrapcheck // intentional
+}
+
+func (c slowCurrencies) ByCode(ctx context.Context, code string) (*repo.Currency, error) {
+	time.Sleep(c.latency)
+
+	return c.CurrencyRepository.ByCode(ctx, code) //nolint:wrapcheck // intentional
+}
+
+type slowBankGroups struct {
+	seamlessv2.BankGroupRepository
+	latency time.Duration
+}
+
+func (b slowBankGroups) ByOperator(ctx context.Context, operatorID int) (*repo.BankGroup, []int, error) {
+	time.Sleep(b.latency)
+
+	return b.BankGroupRepository.ByOperator(ctx, operatorID) //nolint:wrapcheck // intentional
+}
@@ -0,0 +1,112 @@
+package walletcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/cashstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/seamlessv2/memstore"
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/walletcache"
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const player = "player1"
+
+func newStore(t *testing.T) (*memstore.Store, cashstore.Cache, *walletcache.Store, *walletcache.Stats) {
+	t.Helper()
+
+	inner := memstore.New(repo.Currency{ID: 1, Code: "EUR", NumberOfDigitsAfterTheDecimalSeparator: 2, Name: "Euro"})
+	inner.AddUser(repo.User{Name: player})
+
+	cache := cashstore.NewLRU(0, 0)
+	stats := walletcache.NewStats()
+
+	return inner, cache, walletcache.NewStore(inner, cache, time.Minute, stats, zap.NewNop()), stats
+}
+
+func findUser(t *testing.T, store *walletcache.Store) *repo.User {
+	t.Helper()
+
+	tx, errBegin := store.Begin(context.Background())
+	if errBegin != nil {
+		t.Fatalf("begin: %v", errBegin)
+	}
+
+	user, errFindByName := tx.Users().FindByName(context.Background(), player)
+	if errFindByName != nil {
+		t.Fatalf("find user: %v", errFindByName)
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		t.Fatalf("commit: %v", errCommit)
+	}
+
+	return user
+}
+
+func TestFindByNameCached(t *testing.T) {
+	inner, _, store, stats := newStore(t)
+
+	findUser(t, store)
+	inner.SetUserStatus(player, repo.UserStatusSuspended, nil)
+
+	// без сброса кэш отдаёт прочитанное до изменения
+	if user := findUser(t, store); user.Status != repo.UserStatusActive {
+		t.Fatalf("status = %q, want cached %q", user.Status, repo.UserStatusActive)
+	}
+
+	if got := stats.Snapshot()[walletcache.KindUser]; got.Hits != 1 || got.Misses != 1 {
+		t.Fatalf("stats = %+v, want 1 hit and 1 miss", got)
+	}
+}
+
+func TestInvalidateUser(t *testing.T) {
+	inner, cache, store, _ := newStore(t)
+
+	findUser(t, store)
+	inner.SetUserStatus(player, repo.UserStatusSuspended, nil)
+
+	if errInvalidate := walletcache.InvalidateUser(context.Background(), cache, player); errInvalidate != nil {
+		t.Fatalf("invalidate: %v", errInvalidate)
+	}
+
+	if user := findUser(t, store); user.Status != repo.UserStatusSuspended {
+		t.Fatalf("status = %q, want %q", user.Status, repo.UserStatusSuspended)
+	}
+}
+
+// TestInvalidateUserBeforeStaleFill транзакция прочитала игрока до смены статуса, а её заполнение
+// кэша легло уже после сброса: старый статус не должен вернуться.
+func TestInvalidateUserBeforeStaleFill(t *testing.T) {
+	inner, cache, store, _ := newStore(t)
+
+	tx, errBegin := store.Begin(context.Background())
+	if errBegin != nil {
+		t.Fatalf("begin: %v", errBegin)
+	}
+
+	// memstore держит блокировку до Commit, поэтому админка меняет статус между фиксацией
+	// и заполнением кэша: этот AfterCommit выполнится раньше заполнения из FindByName
+	tx.AfterCommit(func() {
+		inner.SetUserStatus(player, repo.UserStatusSuspended, nil)
+
+		if errInvalidate := walletcache.InvalidateUser(context.Background(), cache, player); errInvalidate != nil {
+			t.Errorf("invalidate: %v", errInvalidate)
+		}
+	})
+
+	if _, errFindByName := tx.Users().FindByName(context.Background(), player); errFindByName != nil {
+		t.Fatalf("find user: %v", errFindByName)
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		t.Fatalf("commit: %v", errCommit)
+	}
+
+	if user := findUser(t, store); user.Status != repo.UserStatusSuspended {
+		t.Fatalf("status = %q, want %q", user.Status, repo.UserStatusSuspended)
+	}
+}
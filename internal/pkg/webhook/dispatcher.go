@@ -0,0 +1,197 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/repo"
+)
+
+const (
+	defaultBatchSize   = 50
+	defaultInterval    = time.Second
+	defaultMaxAttempts = 8
+	defaultBaseDelay   = 10 * time.Second
+	defaultMaxDelay    = time.Hour
+	defaultTimeout     = 10 * time.Second
+	// defaultLease на сколько забранная пачка скрыта от других инстансов, с запасом покрывает
+	// отправку всей пачки: defaultBatchSize * defaultTimeout.
+	defaultLease = 15 * time.Minute
+)
+
+var ErrUnexpectedStatus = errors.New("unexpected webhook response status")
+
+// Dispatcher отправляет доставки из billing.webhook_deliveries операторам. Неудачная доставка
+// повторяется с экспоненциальной задержкой, после maxAttempts попыток уходит в dead letter.
+type Dispatcher struct {
+	db          *sqlx.DB
+	client      *http.Client
+	logger      *zap.Logger
+	batchSize   int
+	interval    time.Duration
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	lease       time.Duration
+	now         func() time.Time
+}
+
+func NewDispatcher(db *sqlx.DB, client *http.Client, logger *zap.Logger) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return &Dispatcher{
+		db:          db,
+		client:      client,
+		logger:      logger,
+		batchSize:   defaultBatchSize,
+		interval:    defaultInterval,
+		maxAttempts: defaultMaxAttempts,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+		lease:       defaultLease,
+		now:         time.Now,
+	}
+}
+
+// Run отправляет доставки до отмены ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, errFlush := d.Flush(ctx); errFlush != nil {
+			d.logger.Error("webhook dispatcher flush failed", zap.Error(errFlush))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush обрабатывает одну пачку доставок, которым подошло время, и возвращает число успешных.
+// Доставки забираются и отмечаются короткими транзакциями, а отправка идёт без открытой транзакции:
+// медленный оператор не держит соединение с базой и блокировки строк.
+func (d *Dispatcher) Flush(ctx context.Context) (int, error) {
+	var deliveries []repo.WebhookDelivery
+
+	if errClaim := d.inTx(ctx, func(tx *sqlx.Tx) error {
+		var errClaimDue error
+		deliveries, errClaimDue = repo.ClaimDueWebhookDeliveries(ctx, tx, d.batchSize, d.now().Add(d.lease))
+
+		return errClaimDue //nolint:wrapcheck // intentional
+	}); errClaim != nil {
+		return 0, errClaim
+	}
+
+	var delivered int
+
+	for _, delivery := range deliveries {
+		errSend := d.send(ctx, delivery)
+		if errSend == nil {
+			if errMark := d.inTx(ctx, func(tx *sqlx.Tx) error {
+				return repo.MarkWebhookDelivered(ctx, tx, delivery.ID) //nolint:wrapcheck // intentional
+			}); errMark != nil {
+				return delivered, errMark
+			}
+
+			delivered++
+
+			continue
+		}
+
+		d.logger.Warn(
+			"webhook delivery failed",
+			zap.Int64("delivery_id", delivery.ID),
+			zap.Int("webhook_id", delivery.WebhookID),
+			zap.Int("attempt", delivery.Attempts+1),
+			zap.Error(errSend),
+		)
+
+		if errFailed := d.inTx(ctx, func(tx *sqlx.Tx) error {
+			if delivery.Attempts+1 >= d.maxAttempts {
+				return repo.DeadLetterWebhookDelivery(ctx, tx, delivery.ID, errSend.Error()) //nolint:wrapcheck // intentional
+			}
+
+			return repo.RescheduleWebhookDelivery( //nolint:wrapcheck // intentional
+				ctx,
+				tx,
+				delivery.ID,
+				d.now().Add(d.backoff(delivery.Attempts)),
+				errSend.Error(),
+			)
+		}); errFailed != nil {
+			return delivered, errFailed
+		}
+	}
+
+	return delivered, nil
+}
+
+func (d *Dispatcher) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, errBeginTxx := d.db.BeginTxx(ctx, nil)
+	if errBeginTxx != nil {
+		return errBeginTxx //nolint:wrapcheck // intentional
+	}
+	defer tx.Rollback() //nolint:errcheck // после Commit ничего не делает
+
+	if errFn := fn(tx); errFn != nil {
+		return errFn
+	}
+
+	return tx.Commit() //nolint:wrapcheck // intentional
+}
+
+// backoff baseDelay * 2^attempts, но не больше maxDelay.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseDelay
+	for i := 0; i < attempts && delay < d.maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > d.maxDelay {
+		delay = d.maxDelay
+	}
+
+	return delay
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery repo.WebhookDelivery) error {
+	req, errNewRequest := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if errNewRequest != nil {
+		return fmt.Errorf("new request: %w", errNewRequest)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.now().Unix(), delivery.Payload))
+
+	resp, errDo := d.client.Do(req)
+	if errDo != nil {
+		return fmt.Errorf("post webhook: %w", errDo)
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+var errUnexpectedQuery = errors.New("unexpected query")
+
+// deliveryColumns колонки RETURNING в ClaimDueWebhookDeliveries.
+var deliveryColumns = []string{"id", "created_at", "webhook_id", "event_type", "payload", "attempts", "url", "secret"}
+
+type statement struct {
+	query string
+	args  []driver.NamedValue
+}
+
+// fakeDB отдаёт claimed на забор доставок и запоминает остальные запросы.
+type fakeDB struct {
+	mu         sync.Mutex
+	claimed    [][]driver.Value
+	statements []statement
+}
+
+func (d *fakeDB) executed(match string) []statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var result []statement
+
+	for _, s := range d.statements {
+		if strings.Contains(s.query, match) {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errUnexpectedQuery }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "RETURNING d.id") {
+		return nil, errUnexpectedQuery
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	rows := c.db.claimed
+	c.db.claimed = nil
+
+	return &fakeRows{rows: rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.statements = append(c.db.statements, statement{query: query, args: args})
+
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return deliveryColumns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+var (
+	fakeDBs   = map[string]*fakeDB{}
+	fakeDBsMu sync.Mutex
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+
+	return fakeConn{db: fakeDBs[dsn]}, nil
+}
+
+func init() {
+	sql.Register("webhook-fake", fakeDriver{})
+}
+
+// newDispatcher диспетчер на фейковой базе, в которой ждёт одна доставка на url с attempts попыток.
+func newDispatcher(t *testing.T, url string, attempts int) (*Dispatcher, *fakeDB, time.Time) {
+	t.Helper()
+
+	db := &fakeDB{claimed: [][]driver.Value{{
+		int64(7), time.Now(), int64(3), "balance_changed", []byte(`{"balance":100}`), int64(attempts), url, "secret",
+	}}}
+
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = db
+	fakeDBsMu.Unlock()
+
+	sqlDB, errOpen := sqlx.Open("webhook-fake", t.Name())
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	now := time.Unix(1700000000, 0)
+
+	dispatcher := NewDispatcher(sqlDB, nil, zap.NewNop())
+	dispatcher.now = func() time.Time { return now }
+
+	return dispatcher, db, now
+}
+
+func TestFlushDelivers(t *testing.T) {
+	var (
+		gotHeader http.Header
+		gotBody   []byte
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	dispatcher, db, now := newDispatcher(t, server.URL, 0)
+
+	delivered, errFlush := dispatcher.Flush(context.Background())
+	if errFlush != nil || delivered != 1 {
+		t.Fatalf("flush = %d, %v, want 1 delivered", delivered, errFlush)
+	}
+
+	if string(gotBody) != `{"balance":100}` {
+		t.Fatalf("body = %s", gotBody)
+	}
+
+	for header, want := range map[string]string{
+		"Content-Type":       "application/json",
+		"X-Webhook-Event":    "balance_changed",
+		"X-Webhook-Delivery": "7",
+	} {
+		if got := gotHeader.Get(header); got != want {
+			t.Fatalf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	if errVerify := Verify("secret", gotHeader.Get(SignatureHeader), gotBody, now, time.Minute); errVerify != nil {
+		t.Fatalf("signature: %v", errVerify)
+	}
+
+	if marked := db.executed("delivered_at = now()"); len(marked) != 1 || marked[0].args[0].Value != int64(7) {
+		t.Fatalf("delivery is not marked delivered: %+v", db.statements)
+	}
+}
+
+func TestFlushReschedulesFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	// третья попытка: следующая через baseDelay * 2^2
+	dispatcher, db, now := newDispatcher(t, server.URL, 2)
+
+	delivered, errFlush := dispatcher.Flush(context.Background())
+	if errFlush != nil || delivered != 0 {
+		t.Fatalf("flush = %d, %v, want 0 delivered", delivered, errFlush)
+	}
+
+	rescheduled := db.executed("next_attempt_at = $2")
+	if len(rescheduled) != 1 {
+		t.Fatalf("delivery is not rescheduled: %+v", db.statements)
+	}
+
+	args := rescheduled[0].args
+	if nextAttemptAt, _ := args[1].Value.(time.Time); !nextAttemptAt.Equal(now.Add(4 * defaultBaseDelay)) {
+		t.Fatalf("next attempt at %v, want %v", args[1].Value, now.Add(4*defaultBaseDelay))
+	}
+
+	if lastError, _ := args[2].Value.(string); !strings.Contains(lastError, "502") {
+		t.Fatalf("last error = %q", lastError)
+	}
+
+	if len(db.executed("webhook_dead_letters")) != 0 {
+		t.Fatal("delivery with attempts left moved to dead letters")
+	}
+}
+
+func TestFlushDeadLettersLastAttempt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dispatcher, db, _ := newDispatcher(t, server.URL, defaultMaxAttempts-1)
+
+	if _, errFlush := dispatcher.Flush(context.Background()); errFlush != nil {
+		t.Fatalf("flush: %v", errFlush)
+	}
+
+	if len(db.executed("webhook_dead_letters")) != 1 || len(db.executed("next_attempt_at = $2")) != 0 {
+		t.Fatalf("last failed attempt is not dead lettered: %+v", db.statements)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(nil, nil, zap.NewNop())
+
+	for attempts, want := range map[int]time.Duration{
+		0:  defaultBaseDelay,
+		1:  2 * defaultBaseDelay,
+		3:  8 * defaultBaseDelay,
+		20: defaultMaxDelay,
+	} {
+		if got := dispatcher.backoff(attempts); got != want {
+			t.Fatalf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
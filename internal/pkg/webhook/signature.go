@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader заголовок с подписью вида "t=<unix time>,v1=<hex hmac-sha256>".
+// Подписывается строка "<unix time>.<тело запроса>" секретом вебхука.
+const SignatureHeader = "X-Webhook-Signature"
+
+var (
+	ErrMalformedSignature = errors.New("malformed webhook signature")
+	ErrSignatureMismatch  = errors.New("webhook signature mismatch")
+	ErrSignatureExpired   = errors.New("webhook signature timestamp out of tolerance")
+)
+
+func Sign(secret string, timestamp int64, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac(secret, timestamp, body)))
+}
+
+// Verify проверка подписи на стороне получателя, tolerance защищает от повторной отправки старых запросов.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var (
+		timestamp int64
+		signature []byte
+		errParse  error
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return ErrMalformedSignature
+		}
+
+		switch key {
+		case "t":
+			timestamp, errParse = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature, errParse = hex.DecodeString(value)
+		}
+
+		if errParse != nil {
+			return ErrMalformedSignature
+		}
+	}
+
+	if timestamp == 0 || signature == nil {
+		return ErrMalformedSignature
+	}
+
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > tolerance || diff < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	if !hmac.Equal(signature, mac(secret, timestamp, body)) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
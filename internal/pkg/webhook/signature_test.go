@@ -0,0 +1,87 @@
+package webhook_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rinatusmanov/jsonrpc20/internal/pkg/webhook"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"balance":100}`)
+	signed := webhook.Sign("secret", now.Unix(), body)
+
+	testCases := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{name: "valid", secret: "secret", header: signed, body: body, now: now},
+		{name: "within tolerance", secret: "secret", header: signed, body: body, now: now.Add(4 * time.Minute)},
+		{
+			name:    "other secret",
+			secret:  "other",
+			header:  signed,
+			body:    body,
+			now:     now,
+			wantErr: webhook.ErrSignatureMismatch,
+		},
+		{
+			name:    "tampered body",
+			secret:  "secret",
+			header:  signed,
+			body:    []byte(`{"balance":1000}`),
+			now:     now,
+			wantErr: webhook.ErrSignatureMismatch,
+		},
+		{
+			name:    "old timestamp",
+			secret:  "secret",
+			header:  signed,
+			body:    body,
+			now:     now.Add(6 * time.Minute),
+			wantErr: webhook.ErrSignatureExpired,
+		},
+		{
+			name:    "future timestamp",
+			secret:  "secret",
+			header:  signed,
+			body:    body,
+			now:     now.Add(-6 * time.Minute),
+			wantErr: webhook.ErrSignatureExpired,
+		},
+		{name: "empty", secret: "secret", body: body, now: now, wantErr: webhook.ErrMalformedSignature},
+		{
+			name:    "no signature",
+			secret:  "secret",
+			header:  "t=1700000000",
+			body:    body,
+			now:     now,
+			wantErr: webhook.ErrMalformedSignature,
+		},
+		{
+			name:    "not hex",
+			secret:  "secret",
+			header:  "t=1700000000,v1=zz",
+			body:    body,
+			now:     now,
+			wantErr: webhook.ErrMalformedSignature,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			errVerify := webhook.Verify(tc.secret, tc.header, tc.body, tc.now, 5*time.Minute)
+			if !errors.Is(errVerify, tc.wantErr) {
+				t.Fatalf("verify = %v, want %v", errVerify, tc.wantErr)
+			}
+		})
+	}
+}
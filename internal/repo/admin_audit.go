@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AdminAudit struct {
+	ID        int64           `json:"id" db:"id"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	Admin     string          `json:"admin" db:"admin"`
+	Method    string          `json:"method" db:"method"`
+	Params    json.RawMessage `json:"params" db:"params"`
+	Error     string          `json:"error" db:"error"`
+}
+
+// NewAdminAudit пишется вне транзакции операции, чтобы неудачные попытки тоже оставались в журнале.
+func NewAdminAudit(
+	ctx context.Context,
+	db *sqlx.DB,
+	admin, method string,
+	params json.RawMessage,
+	errText string,
+) error {
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"INSERT INTO billing.admin_audit(admin, method, params, error) VALUES ($1, $2, $3, $4)",
+		admin,
+		method,
+		string(params),
+		errText,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
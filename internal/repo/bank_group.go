@@ -2,16 +2,43 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+type RoundingMode string
+
+const (
+	RoundingNone   RoundingMode = "none"
+	RoundingDown   RoundingMode = "down"
+	RoundingHalfUp RoundingMode = "half_up"
+)
+
+type FreeRoundsPolicy string
+
+const (
+	FreeRoundsAllow FreeRoundsPolicy = "allow"
+	FreeRoundsDeny  FreeRoundsPolicy = "deny"
+)
+
+// BankGroup настройки кошельков операторов, привязанных к группе. CurrencyID валюта по умолчанию,
+// разрешённые валюты лежат в billing.ref_bank_group_currencies.
 type BankGroup struct {
-	ID         string     `json:"id" db:"id,type:text"`
-	CreatedAt  *time.Time `json:"created_at" db:"created_at,type:timestamp"`
-	UpdatedAt  *time.Time `json:"updated_at" db:"updated_at,type:timestamp"`
-	CurrencyID int        `json:"currency_id" db:"currency_id"`
+	ID                  string           `json:"id" db:"id,type:text"`
+	CreatedAt           *time.Time       `json:"created_at" db:"created_at,type:timestamp"`
+	UpdatedAt           *time.Time       `json:"updated_at" db:"updated_at,type:timestamp"`
+	CurrencyID          int              `json:"currency_id" db:"currency_id"`
+	StartingBalance     int              `json:"starting_balance" db:"starting_balance"`
+	BetMin              int              `json:"bet_min" db:"bet_min"`
+	BetMax              int              `json:"bet_max" db:"bet_max"`
+	RoundingStep        int              `json:"rounding_step" db:"rounding_step"`
+	RoundingMode        RoundingMode     `json:"rounding_mode" db:"rounding_mode"`
+	FreeRounds          FreeRoundsPolicy `json:"free_rounds" db:"free_rounds"`
+	FreeRoundsMaxCharge int              `json:"free_rounds_max_charge" db:"free_rounds_max_charge"`
+	// CurrencyConversion ставки в валюте игры пересчитываются в валюту кошелька по billing.exchange_rates.
+	CurrencyConversion bool `json:"currency_conversion" db:"currency_conversion"`
 }
 
 func GetBankGroups(ctx context.Context, db *sqlx.Tx) ([]BankGroup, error) {
@@ -21,9 +48,21 @@ func GetBankGroups(ctx context.Context, db *sqlx.Tx) ([]BankGroup, error) {
 	return bankGroup, err //nolint:wrapcheck // intentional
 }
 
+func GetBankGroupsByCurrencyID(ctx context.Context, db *sqlx.Tx, currencyID int) ([]BankGroup, error) {
+	var bankGroups []BankGroup
+	err := db.SelectContext(
+		ctx,
+		&bankGroups,
+		"SELECT * FROM billing.ref_bank_groups WHERE currency_id = $1 ORDER BY id",
+		currencyID,
+	)
+
+	return bankGroups, err //nolint:wrapcheck // intentional
+}
+
 func GetBankByCurrencyID(ctx context.Context, db *sqlx.Tx, currencyID int) (BankGroup, error) {
 	var bankGroup BankGroup
-	err := db.SelectContext(
+	err := db.GetContext(
 		ctx,
 		&bankGroup,
 		"SELECT * FROM billing.ref_bank_groups WHERE currency_id = $1 LIMIT 1",
@@ -33,6 +72,7 @@ func GetBankByCurrencyID(ctx context.Context, db *sqlx.Tx, currencyID int) (Bank
 	return bankGroup, err //nolint:wrapcheck // intentional
 }
 
+// NewBankGroup валюта по умолчанию сразу попадает в разрешённые валюты группы.
 func NewBankGroup(ctx context.Context, db *sqlx.Tx, id string, currencyID int) (*BankGroup, error) {
 	bankGroup := BankGroup{
 		ID:         id,
@@ -49,5 +89,152 @@ func NewBankGroup(ctx context.Context, db *sqlx.Tx, id string, currencyID int) (
 		return nil, errGetContext //nolint:wrapcheck // intentional
 	}
 
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"INSERT INTO billing.ref_bank_group_currencies(bank_group_id, currency_id) VALUES ($1, $2)",
+		id,
+		currencyID,
+	); errExecContext != nil {
+		return nil, errExecContext //nolint:wrapcheck // intentional
+	}
+
 	return &bankGroup, nil
 }
+
+func GetBankGroupByID(ctx context.Context, db *sqlx.Tx, id string) (*BankGroup, error) {
+	var bankGroups []BankGroup
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&bankGroups,
+		"SELECT * FROM billing.ref_bank_groups WHERE id = $1",
+		id,
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(bankGroups) == 0 {
+		return nil, errBankGroupNotFound
+	}
+
+	return &bankGroups[0], nil
+}
+
+// GetBankGroupByOperatorID nil без ошибки, если оператор не заведён или группа ему не назначена.
+func GetBankGroupByOperatorID(ctx context.Context, db *sqlx.Tx, operatorID int) (*BankGroup, error) {
+	var bankGroups []BankGroup
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&bankGroups,
+		`SELECT g.* FROM billing.ref_bank_groups g
+		JOIN billing.ref_operator o ON o.bank_group_id = g.id
+		WHERE o.id = $1`,
+		operatorID,
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(bankGroups) == 0 {
+		return nil, nil //nolint:nilnil // группа не назначена
+	}
+
+	return &bankGroups[0], nil
+}
+
+// UpdateBankGroupPolicy меняет настройки кошельков группы, валюта по умолчанию не меняется.
+func UpdateBankGroupPolicy(ctx context.Context, db *sqlx.Tx, bankGroup BankGroup) (*BankGroup, error) {
+	var bankGroups []BankGroup
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&bankGroups,
+		`UPDATE billing.ref_bank_groups
+		SET starting_balance = $2, bet_min = $3, bet_max = $4, rounding_step = $5, rounding_mode = $6,
+			free_rounds = $7, free_rounds_max_charge = $8, currency_conversion = $9, updated_at = now()
+		WHERE id = $1 returning *`,
+		bankGroup.ID,
+		bankGroup.StartingBalance,
+		bankGroup.BetMin,
+		bankGroup.BetMax,
+		bankGroup.RoundingStep,
+		bankGroup.RoundingMode,
+		bankGroup.FreeRounds,
+		bankGroup.FreeRoundsMaxCharge,
+		bankGroup.CurrencyConversion,
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(bankGroups) == 0 {
+		return nil, errBankGroupNotFound
+	}
+
+	return &bankGroups[0], nil
+}
+
+func GetBankGroupCurrencyIDs(ctx context.Context, db *sqlx.Tx, bankGroupID string) ([]int, error) {
+	var currencyIDs []int
+	err := db.SelectContext(
+		ctx,
+		&currencyIDs,
+		"SELECT currency_id FROM billing.ref_bank_group_currencies WHERE bank_group_id = $1 ORDER BY currency_id",
+		bankGroupID,
+	)
+
+	return currencyIDs, err //nolint:wrapcheck // intentional
+}
+
+// SetBankGroupCurrencies заменяет список разрешённых валют группы целиком.
+func SetBankGroupCurrencies(ctx context.Context, db *sqlx.Tx, bankGroupID string, currencyIDs []int) error {
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"DELETE FROM billing.ref_bank_group_currencies WHERE bank_group_id = $1",
+		bankGroupID,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	for _, currencyID := range currencyIDs {
+		if _, errExecContext := db.ExecContext(
+			ctx,
+			"INSERT INTO billing.ref_bank_group_currencies(bank_group_id, currency_id) VALUES ($1, $2)",
+			bankGroupID,
+			currencyID,
+		); errExecContext != nil {
+			return errExecContext //nolint:wrapcheck // intentional
+		}
+	}
+
+	return nil
+}
+
+// SetOperatorBankGroup заводит оператора, если его ещё нет. Пустой bankGroupID отвязывает группу.
+func SetOperatorBankGroup(ctx context.Context, db *sqlx.Tx, operatorID int, bankGroupID string) error {
+	var bankGroup *string
+	if bankGroupID != "" {
+		bankGroup = &bankGroupID
+	}
+
+	_, err := db.ExecContext(
+		ctx,
+		`INSERT INTO billing.ref_operator(id, bank_group_id) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET bank_group_id = excluded.bank_group_id`,
+		operatorID,
+		bankGroup,
+	)
+
+	return err //nolint:wrapcheck // intentional
+}
+
+var errBankGroupNotFound = errors.New("bank group not found")
+
+func DeleteBankGroup(ctx context.Context, db *sqlx.Tx, id string) error {
+	result, errExecContext := db.ExecContext(ctx, "DELETE FROM billing.ref_bank_groups WHERE id = $1", id)
+	if errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		return errBankGroupNotFound
+	}
+
+	return nil
+}
@@ -15,16 +15,20 @@ type Currency struct {
 	Code                                   string     `json:"code" db:"code,type:text"`
 	NumberOfDigitsAfterTheDecimalSeparator int        `json:"number_of_digits_after_the_decimal_separator" db:"number_of_digits_after_the_decimal_separator,type:int"` //nolint:lll // intentional
 	Name                                   string     `json:"name" db:"name,type:text"`
+	// Disabled отключённая валюта остаётся в справочнике для старых платежей, но новые кошельки в ней не работают.
+	Disabled bool `json:"disabled" db:"disabled"`
 }
 
 func NewCurrency(
 	ctx context.Context,
 	db *sqlx.Tx,
+	id int,
 	code string,
 	numberOfDigitsAfterTheDecimalSeparator int,
 	name string,
 ) (Currency, error) {
 	currency := Currency{
+		ID:                                     id,
 		Code:                                   code,
 		NumberOfDigitsAfterTheDecimalSeparator: numberOfDigitsAfterTheDecimalSeparator,
 		Name:                                   name,
@@ -32,7 +36,8 @@ func NewCurrency(
 	err := db.GetContext(
 		ctx,
 		&currency,
-		"INSERT INTO billing.ref_currency(code, number_of_digits_after_the_decimal_separator, name) VALUES ($1, $2, $3) returning *", //nolint:lll // intentional
+		"INSERT INTO billing.ref_currency(id, code, number_of_digits_after_the_decimal_separator, name) VALUES ($1, $2, $3, $4) returning *", //nolint:lll // intentional
+		id,
 		code,
 		numberOfDigitsAfterTheDecimalSeparator,
 		name,
@@ -71,3 +76,22 @@ func GetCurrencyByCode(ctx context.Context, db *sqlx.Tx, code string) (*Currency
 
 	return &currencies[0], nil
 }
+
+func SetCurrencyDisabled(ctx context.Context, db *sqlx.Tx, code string, disabled bool) (*Currency, error) {
+	var currencies []Currency
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&currencies,
+		"UPDATE billing.ref_currency SET disabled = $2, updated_at = now() WHERE code = $1 returning *",
+		code,
+		disabled,
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(currencies) == 0 {
+		return nil, errNotFoundCurrency
+	}
+
+	return &currencies[0], nil
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ExchangeRate одна единица FromCurrencyID стоит Rate единиц ToCurrencyID начиная с EffectiveAt.
+// Rate хранится строкой, чтобы не терять точность numeric.
+type ExchangeRate struct {
+	ID             int64      `json:"id" db:"id"`
+	CreatedAt      *time.Time `json:"created_at" db:"created_at"`
+	FromCurrencyID int        `json:"from_currency_id" db:"from_currency_id"`
+	ToCurrencyID   int        `json:"to_currency_id" db:"to_currency_id"`
+	Rate           string     `json:"rate" db:"rate"`
+	EffectiveAt    time.Time  `json:"effective_at" db:"effective_at"`
+	Source         string     `json:"source" db:"source"`
+}
+
+// UpsertExchangeRate повторный импорт того же курса на ту же дату заменяет значение.
+func UpsertExchangeRate(ctx context.Context, db *sqlx.Tx, rate ExchangeRate) (*ExchangeRate, error) {
+	if errGetContext := db.GetContext(
+		ctx,
+		&rate,
+		`INSERT INTO billing.exchange_rates(from_currency_id, to_currency_id, rate, effective_at, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (from_currency_id, to_currency_id, effective_at)
+		DO UPDATE SET rate = excluded.rate, source = excluded.source
+		returning *`,
+		rate.FromCurrencyID,
+		rate.ToCurrencyID,
+		rate.Rate,
+		rate.EffectiveAt,
+		rate.Source,
+	); errGetContext != nil {
+		return nil, errGetContext //nolint:wrapcheck // intentional
+	}
+
+	return &rate, nil
+}
+
+// GetExchangeRate курс, действующий на момент at, nil если курса нет.
+func GetExchangeRate(
+	ctx context.Context,
+	db *sqlx.Tx,
+	fromCurrencyID, toCurrencyID int,
+	at time.Time,
+) (*ExchangeRate, error) {
+	var rates []ExchangeRate
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&rates,
+		`SELECT * FROM billing.exchange_rates
+		WHERE from_currency_id = $1 AND to_currency_id = $2 AND effective_at <= $3
+		ORDER BY effective_at DESC LIMIT 1`,
+		fromCurrencyID,
+		toCurrencyID,
+		at,
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(rates) == 0 {
+		return nil, nil //nolint:nilnil // курса нет
+	}
+
+	return &rates[0], nil
+}
+
+// GetExchangeRates 0 в fromCurrencyID или toCurrencyID - любая валюта.
+func GetExchangeRates(ctx context.Context, db *sqlx.Tx, fromCurrencyID, toCurrencyID int) ([]ExchangeRate, error) {
+	var rates []ExchangeRate
+	err := db.SelectContext(
+		ctx,
+		&rates,
+		`SELECT * FROM billing.exchange_rates
+		WHERE ($1 = 0 OR from_currency_id = $1) AND ($2 = 0 OR to_currency_id = $2)
+		ORDER BY from_currency_id, to_currency_id, effective_at`,
+		fromCurrencyID,
+		toCurrencyID,
+	)
+
+	return rates, err //nolint:wrapcheck // intentional
+}
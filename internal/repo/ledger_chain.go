@@ -0,0 +1,318 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type LedgerLinkKind string
+
+const (
+	LedgerLinkPayment  LedgerLinkKind = "payment"
+	LedgerLinkRollback LedgerLinkKind = "rollback"
+)
+
+// LedgerLink звено цепочки хэшей кошелька.
+type LedgerLink struct {
+	ID         int64          `db:"id"`
+	CreatedAt  time.Time      `db:"created_at"`
+	UserID     int            `db:"user_id"`
+	CurrencyID int            `db:"currency_id"`
+	PaymentID  int            `db:"payment_id"`
+	Kind       LedgerLinkKind `db:"kind"`
+	RollbackAt *time.Time     `db:"rollback_at"`
+	PrevHash   string         `db:"prev_hash"`
+	Hash       string         `db:"hash"`
+}
+
+type LedgerCheckpoint struct {
+	ID         int64     `db:"id"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastLinkID int64     `db:"last_link_id"`
+	Wallets    int       `db:"wallets"`
+	RootHash   string    `db:"root_hash"`
+	Signature  string    `db:"signature"`
+}
+
+// ledgerChainLockNamespace первый ключ advisory lock, второй - user_id: звенья одного кошелька дописываются по очереди.
+const ledgerChainLockNamespace = 2022090701
+
+// LedgerLinkHash sha256 от хэша предыдущего звена и содержимого звена. Для звена платежа
+// хэшируются неизменяемые поля платежа, для звена отката - время отката.
+func LedgerLinkHash(link *LedgerLink, payment *Payment) string {
+	parts := []string{
+		link.PrevHash,
+		string(link.Kind),
+		strconv.Itoa(link.UserID),
+		strconv.Itoa(link.CurrencyID),
+		strconv.Itoa(link.PaymentID),
+	}
+
+	switch link.Kind {
+	case LedgerLinkPayment:
+		parts = append(
+			parts,
+			formatLedgerTime(payment.CreatedAt),
+			strconv.Itoa(payment.UserID),
+			strconv.Itoa(payment.CurrencyID),
+			strconv.Itoa(payment.Withdraw),
+			strconv.Itoa(payment.Deposit),
+			payment.TransactionRef,
+			payment.GameID,
+			payment.GameRoundRef,
+			payment.Reason,
+			payment.SessionID,
+			strconv.Itoa(payment.CallerID),
+			payment.BonusID,
+			payment.BetType,
+			payment.WinType,
+			string(payment.Extras),
+		)
+
+		// пересчёт валюты добавлен позже, для старых платежей хэш не меняется
+		if payment.GameCurrencyID != nil {
+			parts = append(
+				parts,
+				strconv.Itoa(*payment.GameCurrencyID),
+				strconv.Itoa(payment.GameWithdraw),
+				strconv.Itoa(payment.GameDeposit),
+				stringValue(payment.ExchangeRate),
+			)
+		}
+	case LedgerLinkRollback:
+		parts = append(parts, formatLedgerTime(link.RollbackAt))
+	}
+
+	h := sha256.New()
+
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func formatLedgerTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// AppendLedgerLink дописывает звено в цепочку кошелька платежа в транзакции платежа.
+// Звено отката у платежа одно: rollback_at ставится один раз, второе звено ничего бы не подтверждало.
+func AppendLedgerLink(ctx context.Context, db *sqlx.Tx, kind LedgerLinkKind, payment *Payment) error {
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"SELECT pg_advisory_xact_lock($1, $2)",
+		ledgerChainLockNamespace,
+		payment.UserID,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	if kind == LedgerLinkRollback {
+		var linked bool
+		if errGetContext := db.GetContext(
+			ctx,
+			&linked,
+			"SELECT exists(SELECT 1 FROM billing.ledger_chain WHERE payment_id = $1 AND kind = $2)",
+			payment.ID,
+			LedgerLinkRollback,
+		); errGetContext != nil {
+			return errGetContext //nolint:wrapcheck // intentional
+		}
+
+		if linked {
+			return nil
+		}
+	}
+
+	link := LedgerLink{
+		UserID:     payment.UserID,
+		CurrencyID: payment.CurrencyID,
+		PaymentID:  payment.ID,
+		Kind:       kind,
+	}
+
+	if kind == LedgerLinkRollback {
+		link.RollbackAt = payment.RollBackAt
+	}
+
+	if errGetContext := db.GetContext(
+		ctx,
+		&link.PrevHash,
+		`SELECT coalesce((
+			SELECT hash FROM billing.ledger_chain
+			WHERE user_id = $1 AND currency_id = $2
+			ORDER BY id DESC LIMIT 1
+		), '')`,
+		link.UserID,
+		link.CurrencyID,
+	); errGetContext != nil {
+		return errGetContext //nolint:wrapcheck // intentional
+	}
+
+	link.Hash = LedgerLinkHash(&link, payment)
+
+	_, errExecContext := db.ExecContext(
+		ctx,
+		`INSERT INTO billing.ledger_chain(user_id, currency_id, payment_id, kind, rollback_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		link.UserID,
+		link.CurrencyID,
+		link.PaymentID,
+		link.Kind,
+		link.RollbackAt,
+		link.PrevHash,
+		link.Hash,
+	)
+
+	return errExecContext //nolint:wrapcheck // intentional
+}
+
+func GetLedgerLinksAfter(ctx context.Context, db *sqlx.Tx, afterID int64, limit int) ([]LedgerLink, error) {
+	var links []LedgerLink
+	err := db.SelectContext(
+		ctx,
+		&links,
+		"SELECT * FROM billing.ledger_chain WHERE id > $1 ORDER BY id LIMIT $2",
+		afterID,
+		limit,
+	)
+
+	return links, err //nolint:wrapcheck // intentional
+}
+
+func GetPaymentsByIDs(ctx context.Context, db *sqlx.Tx, ids []int) ([]Payment, error) {
+	var payments []Payment
+	err := db.SelectContext(
+		ctx,
+		&payments,
+		"SELECT * FROM billing.payments WHERE id = ANY($1)",
+		pq.Array(ids),
+	)
+
+	return payments, err //nolint:wrapcheck // intentional
+}
+
+// GetUnchainedPayments платежи без звена в цепочке: созданные до её появления или вставленные в обход NewPayment.
+func GetUnchainedPayments(ctx context.Context, db *sqlx.Tx, limit int) ([]Payment, error) {
+	var payments []Payment
+	err := db.SelectContext(
+		ctx,
+		&payments,
+		`SELECT p.* FROM billing.payments p
+		WHERE NOT EXISTS (SELECT 1 FROM billing.ledger_chain c WHERE c.payment_id = p.id AND c.kind = 'payment')
+		ORDER BY p.id LIMIT $1`,
+		limit,
+	)
+
+	return payments, err //nolint:wrapcheck // intentional
+}
+
+// GetRollbackMismatches платежи, у которых rollback_at не совпадает с последним звеном отката:
+// откат без звена или rollback_at, изменённый после отката.
+func GetRollbackMismatches(ctx context.Context, db *sqlx.Tx, limit int) ([]Payment, error) {
+	var payments []Payment
+	err := db.SelectContext(
+		ctx,
+		&payments,
+		`SELECT p.* FROM billing.payments p
+		WHERE p.rollback_at IS DISTINCT FROM (
+			SELECT c.rollback_at FROM billing.ledger_chain c
+			WHERE c.payment_id = p.id AND c.kind = 'rollback'
+			ORDER BY c.id DESC LIMIT 1
+		)
+		ORDER BY p.id LIMIT $1`,
+		limit,
+	)
+
+	return payments, err //nolint:wrapcheck // intentional
+}
+
+// GetLedgerHeads последние звенья всех кошельков среди звеньев с id не больше upToID.
+func GetLedgerHeads(ctx context.Context, db *sqlx.Tx, upToID int64) ([]LedgerLink, error) {
+	var links []LedgerLink
+	err := db.SelectContext(
+		ctx,
+		&links,
+		`SELECT DISTINCT ON (user_id, currency_id) * FROM billing.ledger_chain
+		WHERE id <= $1
+		ORDER BY user_id, currency_id, id DESC`,
+		upToID,
+	)
+
+	return links, err //nolint:wrapcheck // intentional
+}
+
+// GetSettledLedgerLinkID последнее звено старше settle: более свежие id могут ещё не быть видны
+// из-за незакоммиченных транзакций с меньшими id.
+func GetSettledLedgerLinkID(ctx context.Context, db *sqlx.Tx, settle time.Duration) (int64, error) {
+	var id int64
+	err := db.GetContext(
+		ctx,
+		&id,
+		"SELECT coalesce(max(id), 0) FROM billing.ledger_chain WHERE created_at < now() - $1 * interval '1 microsecond'",
+		settle.Microseconds(),
+	)
+
+	return id, err //nolint:wrapcheck // intentional
+}
+
+func GetLastLedgerCheckpoint(ctx context.Context, db *sqlx.Tx) (*LedgerCheckpoint, error) {
+	var checkpoints []LedgerCheckpoint
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&checkpoints,
+		"SELECT * FROM billing.ledger_checkpoints ORDER BY id DESC LIMIT 1",
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(checkpoints) == 0 {
+		return nil, nil //nolint:nilnil // снимков ещё нет
+	}
+
+	return &checkpoints[0], nil
+}
+
+func GetLedgerCheckpoints(ctx context.Context, db *sqlx.Tx) ([]LedgerCheckpoint, error) {
+	var checkpoints []LedgerCheckpoint
+	err := db.SelectContext(
+		ctx,
+		&checkpoints,
+		"SELECT * FROM billing.ledger_checkpoints ORDER BY last_link_id, id",
+	)
+
+	return checkpoints, err //nolint:wrapcheck // intentional
+}
+
+func NewLedgerCheckpoint(ctx context.Context, db *sqlx.Tx, checkpoint *LedgerCheckpoint) error {
+	return db.GetContext( //nolint:wrapcheck // intentional
+		ctx,
+		checkpoint,
+		`INSERT INTO billing.ledger_checkpoints(last_link_id, wallets, root_hash, signature)
+		VALUES ($1, $2, $3, $4) returning *`,
+		checkpoint.LastLinkID,
+		checkpoint.Wallets,
+		checkpoint.RootHash,
+		checkpoint.Signature,
+	)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,181 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type OutboxEventType string
+
+const (
+	OutboxEventBet      OutboxEventType = "bet"
+	OutboxEventWin      OutboxEventType = "win"
+	OutboxEventRollback OutboxEventType = "rollback"
+)
+
+type OutboxEvent struct {
+	ID          int64           `json:"id" db:"id"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UserID      int             `json:"user_id" db:"user_id"`
+	EventType   OutboxEventType `json:"event_type" db:"event_type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	PublishedAt *time.Time      `json:"published_at" db:"published_at"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	LastError   string          `json:"last_error" db:"last_error"`
+	// NextAttemptAt раньше этого времени событие и следующие события игрока не публикуются.
+	NextAttemptAt time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+}
+
+// OutboxPayload тело события, баланс считается в момент записи события внутри транзакции платежа.
+type OutboxPayload struct {
+	PaymentID      int    `json:"payment_id"`
+	UserID         int    `json:"user_id"`
+	CurrencyID     int    `json:"currency_id"`
+	TransactionRef string `json:"transaction_ref"`
+	GameID         string `json:"game_id"`
+	GameRoundRef   string `json:"game_round_ref"`
+	Reason         string `json:"reason"`
+	Amount         int    `json:"amount"`
+}
+
+// outboxLockID ключ advisory lock, под которым работает единственный relay, чтобы не нарушать порядок событий игрока.
+const outboxLockID = 2022090201
+
+func NewOutboxEvent(
+	ctx context.Context,
+	db *sqlx.Tx,
+	eventType OutboxEventType,
+	payload OutboxPayload,
+) error {
+	raw, errMarshal := json.Marshal(payload)
+	if errMarshal != nil {
+		return errMarshal //nolint:wrapcheck // intentional
+	}
+
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		`INSERT INTO billing.outbox(user_id, event_type, payload)
+		SELECT $1, $2, $3::jsonb || jsonb_build_object('balance', coalesce(sum(deposit - withdraw), 0))
+		FROM billing.payments WHERE user_id = $1 AND rollback_at IS NULL`,
+		payload.UserID,
+		eventType,
+		string(raw),
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+// EventPayload тело события о платеже: amount - сумма ставки, выигрыша или изменение баланса при откате.
+func (p *Payment) EventPayload(amount int) OutboxPayload {
+	return OutboxPayload{
+		PaymentID:      p.ID,
+		UserID:         p.UserID,
+		CurrencyID:     p.CurrencyID,
+		TransactionRef: p.TransactionRef,
+		GameID:         p.GameID,
+		GameRoundRef:   p.GameRoundRef,
+		Reason:         p.Reason,
+		Amount:         amount,
+	}
+}
+
+func newPaymentOutboxEvents(ctx context.Context, db *sqlx.Tx, payment *Payment) error {
+	if payment.Withdraw > 0 {
+		if errNewOutboxEvent := NewOutboxEvent(
+			ctx,
+			db,
+			OutboxEventBet,
+			payment.EventPayload(payment.Withdraw),
+		); errNewOutboxEvent != nil {
+			return errNewOutboxEvent
+		}
+	}
+
+	if payment.Deposit > 0 {
+		if errNewOutboxEvent := NewOutboxEvent(
+			ctx,
+			db,
+			OutboxEventWin,
+			payment.EventPayload(payment.Deposit),
+		); errNewOutboxEvent != nil {
+			return errNewOutboxEvent
+		}
+	}
+
+	return nil
+}
+
+// TryLockOutbox берёт advisory lock на соединение conn, false если relay уже работает в другом инстансе.
+// Lock держится между транзакциями, пока соединение не вызовет UnlockOutbox или не закроется.
+func TryLockOutbox(ctx context.Context, conn *sqlx.Conn) (bool, error) {
+	var locked bool
+	err := conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", outboxLockID)
+
+	return locked, err //nolint:wrapcheck // intentional
+}
+
+func UnlockOutbox(ctx context.Context, conn *sqlx.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", outboxLockID)
+
+	return err //nolint:wrapcheck // intentional
+}
+
+// GetUnpublishedOutboxEvents первые по id события, которые можно публиковать: событие игрока не попадает
+// в выборку, пока у него есть более раннее неопубликованное событие, отложенное после ошибки.
+// Так игрок с постоянно падающим событием не занимает пачку и не задерживает остальных.
+func GetUnpublishedOutboxEvents(ctx context.Context, db *sqlx.Tx, limit int) ([]OutboxEvent, error) {
+	var events []OutboxEvent
+	err := db.SelectContext(
+		ctx,
+		&events,
+		`SELECT * FROM billing.outbox o
+		WHERE o.published_at IS NULL AND o.next_attempt_at <= now()
+		AND NOT EXISTS (
+			SELECT 1 FROM billing.outbox b
+			WHERE b.user_id = o.user_id AND b.published_at IS NULL AND b.id < o.id AND b.next_attempt_at > now()
+		)
+		ORDER BY o.id
+		LIMIT $1`,
+		limit,
+	)
+
+	return events, err //nolint:wrapcheck // intentional
+}
+
+func MarkOutboxEventPublished(ctx context.Context, db *sqlx.Tx, id int64) error {
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"UPDATE billing.outbox SET published_at = now(), attempts = attempts + 1, last_error = '' WHERE id = $1",
+		id,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+// MarkOutboxEventFailed откладывает событие до nextAttemptAt, вместе с ним ждут и следующие события игрока.
+func MarkOutboxEventFailed(
+	ctx context.Context,
+	db *sqlx.Tx,
+	id int64,
+	nextAttemptAt time.Time,
+	lastError string,
+) error {
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"UPDATE billing.outbox SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3 WHERE id = $1",
+		id,
+		nextAttemptAt,
+		lastError,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
@@ -2,10 +2,12 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Payment struct {
@@ -17,6 +19,32 @@ type Payment struct {
 	Withdraw       int        `json:"withdraw" db:"withdraw"`
 	Deposit        int        `json:"deposit" db:"deposit"`
 	TransactionRef string     `json:"transaction_ref" db:"transaction_ref"`
+	PaymentContext
+}
+
+// PaymentContext игровой контекст платежа из запроса провайдера. Поля запроса,
+// для которых нет отдельной колонки, лежат в Extras.
+type PaymentContext struct {
+	GameID       string          `json:"game_id" db:"game_id"`
+	GameRoundRef string          `json:"game_round_ref" db:"game_round_ref"`
+	Reason       string          `json:"reason" db:"reason"`
+	SessionID    string          `json:"session_id" db:"session_id"`
+	CallerID     int             `json:"caller_id" db:"caller_id"`
+	BonusID      string          `json:"bonus_id" db:"bonus_id"`
+	BetType      string          `json:"bet_type" db:"bet_type"`
+	WinType      string          `json:"win_type" db:"win_type"`
+	Extras       json.RawMessage `json:"extras" db:"extras"`
+	PaymentConversion
+}
+
+// PaymentConversion заполняется, если ставка пришла в валюте игры: Withdraw и Deposit платежа уже
+// в валюте кошелька, а суммы провайдера и курс, по которому они пересчитаны, хранятся здесь.
+type PaymentConversion struct {
+	GameCurrencyID *int    `json:"game_currency_id" db:"game_currency_id"`
+	GameWithdraw   int     `json:"game_withdraw" db:"game_withdraw"`
+	GameDeposit    int     `json:"game_deposit" db:"game_deposit"`
+	ExchangeRate   *string `json:"exchange_rate" db:"exchange_rate"`
+	ExchangeRateID *int64  `json:"exchange_rate_id" db:"exchange_rate_id"`
 }
 
 func GetCurrencyID(ctx context.Context, db *sqlx.Tx, userID int) (int, error) {
@@ -40,6 +68,7 @@ func NewPayment(
 	db *sqlx.Tx,
 	userID, currencyID, withdraw, deposit int,
 	transactionRef string,
+	paymentContext PaymentContext,
 ) (*Payment, error) {
 	payment := Payment{
 		UserID:         userID,
@@ -47,21 +76,62 @@ func NewPayment(
 		Withdraw:       withdraw,
 		Deposit:        deposit,
 		TransactionRef: transactionRef,
+		PaymentContext: paymentContext,
+	}
+
+	extras := "{}"
+	if len(paymentContext.Extras) != 0 {
+		extras = string(paymentContext.Extras)
 	}
 
 	if errGetContext := db.GetContext(
 		ctx,
 		&payment,
-		"INSERT INTO billing.payments(user_id, currency_id, withdraw, deposit, transaction_ref) VALUES ($1, $2, $3, $4, $5) returning *", //nolint:lll // intentional
+		`INSERT INTO billing.payments(
+			user_id, currency_id, withdraw, deposit, transaction_ref,
+			game_id, game_round_ref, reason, session_id, caller_id, bonus_id, bet_type, win_type, extras,
+			game_currency_id, game_withdraw, game_deposit, exchange_rate, exchange_rate_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19) returning *`,
 		userID,
 		currencyID,
 		withdraw,
 		deposit,
 		transactionRef,
+		paymentContext.GameID,
+		paymentContext.GameRoundRef,
+		paymentContext.Reason,
+		paymentContext.SessionID,
+		paymentContext.CallerID,
+		paymentContext.BonusID,
+		paymentContext.BetType,
+		paymentContext.WinType,
+		extras,
+		paymentContext.GameCurrencyID,
+		paymentContext.GameWithdraw,
+		paymentContext.GameDeposit,
+		paymentContext.ExchangeRate,
+		paymentContext.ExchangeRateID,
 	); errGetContext != nil {
+		if isUniqueViolation(errGetContext, paymentsTransactionRefIndex) {
+			return nil, errNotUniqueTransactionRef
+		}
+
 		return nil, errGetContext //nolint:wrapcheck // intentional
 	}
 
+	if errAppendLedgerLink := AppendLedgerLink(ctx, db, LedgerLinkPayment, &payment); errAppendLedgerLink != nil {
+		return nil, errAppendLedgerLink
+	}
+
+	// событие пишется в той же транзакции, поэтому не теряется и не публикуется для откаченного платежа
+	if errNewPaymentOutboxEvents := newPaymentOutboxEvents(ctx, db, &payment); errNewPaymentOutboxEvents != nil {
+		return nil, errNewPaymentOutboxEvents
+	}
+
+	if errNotify := notifyBalanceChanged(ctx, db, payment.EventPayload(deposit-withdraw)); errNotify != nil {
+		return nil, errNotify
+	}
+
 	return &payment, nil
 }
 
@@ -87,6 +157,18 @@ func GetDepositByUserID(ctx context.Context, db *sqlx.Tx, userID int) (int, erro
 
 var errNotUniqueTransactionRef = errors.New("not unique transactionRef")
 
+// paymentsTransactionRefIndex уникальный индекс по transaction_ref: CheckUniqueTransactionRef не видит
+// незафиксированный платёж параллельной транзакции, вторая вставка упирается в индекс.
+const paymentsTransactionRefIndex = "payments_transaction_ref_uidx"
+
+const pqUniqueViolation = "23505"
+
+func isUniqueViolation(err error, constraint string) bool {
+	var errPQ *pq.Error
+
+	return errors.As(err, &errPQ) && errPQ.Code == pqUniqueViolation && errPQ.Constraint == constraint
+}
+
 func CheckUniqueTransactionRef(ctx context.Context, db *sqlx.Tx, transactionRef string) error {
 	var payments []Payment
 	if errSelectContext := db.SelectContext(
@@ -107,19 +189,87 @@ func CheckUniqueTransactionRef(ctx context.Context, db *sqlx.Tx, transactionRef
 
 var errPaymentNotFound = errors.New("payment not found")
 
-func RollbackPayment(ctx context.Context, db *sqlx.Tx, rollbackPayment string) error {
-	result, errGetContext := db.ExecContext(
+// RollbackPayment возвращает откаченные платежи, по ним вызывающий узнаёт игрока и изменение баланса.
+// Повторный откат ничего не меняет и возвращает пустой список: провайдер повторяет rollbackTransaction,
+// пока не получит ответ, и каждый повтор не должен порождать новое событие.
+func RollbackPayment(ctx context.Context, db *sqlx.Tx, rollbackPayment string) ([]Payment, error) {
+	var payments []Payment
+	if errSelectContext := db.SelectContext(
 		ctx,
-		"UPDATE billing.payments SET rollback_at = now() WHERE transaction_ref = $1",
+		&payments,
+		`UPDATE billing.payments SET rollback_at = now()
+		WHERE transaction_ref = $1 AND rollback_at IS NULL
+		RETURNING *`,
 		rollbackPayment,
-	)
-	if errGetContext != nil {
-		return errGetContext //nolint:wrapcheck // intentional
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
 	}
 
-	if count, _ := result.RowsAffected(); count == 0 {
-		return errPaymentNotFound
+	if len(payments) == 0 {
+		// параллельный откат того же платежа дождался блокировки строки и здесь тоже ничего не обновил
+		if _, errFindPayment := FindPaymentByTransactionRef(ctx, db, rollbackPayment); errFindPayment != nil {
+			return nil, errFindPayment
+		}
+
+		return nil, nil
 	}
 
-	return nil
+	for i := range payments {
+		payment := &payments[i]
+
+		// строка платежа меняется на месте, поэтому откат фиксируется отдельным звеном цепочки,
+		// только при первом переходе rollback_at из NULL: повтор сюда не доходит
+		if errAppendLedgerLink := AppendLedgerLink(ctx, db, LedgerLinkRollback, payment); errAppendLedgerLink != nil {
+			return nil, errAppendLedgerLink
+		}
+
+		if errNewOutboxEvent := NewOutboxEvent(
+			ctx,
+			db,
+			OutboxEventRollback,
+			payment.EventPayload(payment.Withdraw-payment.Deposit),
+		); errNewOutboxEvent != nil {
+			return nil, errNewOutboxEvent
+		}
+
+		if errNotify := notifyBalanceChanged(
+			ctx,
+			db,
+			payment.EventPayload(payment.Withdraw-payment.Deposit),
+		); errNotify != nil {
+			return nil, errNotify
+		}
+	}
+
+	return payments, nil
+}
+
+func FindPaymentByTransactionRef(ctx context.Context, db *sqlx.Tx, transactionRef string) (*Payment, error) {
+	var payments []Payment
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&payments,
+		"SELECT * FROM billing.payments WHERE transaction_ref = $1 LIMIT 1",
+		transactionRef,
+	); errSelectContext != nil {
+		return nil, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(payments) == 0 {
+		return nil, errPaymentNotFound
+	}
+
+	return &payments[0], nil
+}
+
+func GetPaymentsByUserID(ctx context.Context, db *sqlx.Tx, userID int) ([]Payment, error) {
+	var payments []Payment
+	err := db.SelectContext(
+		ctx,
+		&payments,
+		"SELECT * FROM billing.payments WHERE user_id = $1 ORDER BY id",
+		userID,
+	)
+
+	return payments, err //nolint:wrapcheck // intentional
 }
@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PaymentHistoryFilter пустые поля фильтра не ограничивают выборку.
+type PaymentHistoryFilter struct {
+	UserID       int
+	From         *time.Time
+	To           *time.Time
+	GameID       string
+	GameRoundRef string
+	Reason       string
+	RolledBack   *bool
+	// BeforeID ключ пагинации: отдаются платежи с id меньше указанного, 0 - с самого нового.
+	BeforeID int
+}
+
+type PaymentHistoryRow struct {
+	Payment
+	// Balance баланс игрока сразу после платежа, откаченные платежи на него не влияют.
+	Balance int `db:"balance"`
+}
+
+type PaymentHistoryTotals struct {
+	Count    int `db:"count"`
+	Withdraw int `db:"withdraw"`
+	Deposit  int `db:"deposit"`
+}
+
+// paymentHistoryFilter условие фильтра по платежам p, параметры $1-$7 из PaymentHistoryFilter.args.
+const paymentHistoryFilter = `p.user_id = $1
+	AND ($2::timestamp IS NULL OR p.created_at >= $2)
+	AND ($3::timestamp IS NULL OR p.created_at < $3)
+	AND ($4 = '' OR p.game_id = $4)
+	AND ($5 = '' OR p.game_round_ref = $5)
+	AND ($6 = '' OR p.reason = $6)
+	AND ($7::boolean IS NULL OR (p.rollback_at IS NOT NULL) = $7)`
+
+// paymentHistoryQuery сначала выбирает страницу, а баланс считает окном только по платежам игрока
+// между первой и последней записью страницы. Окно начинается с баланса до первой записи: это сумма
+// без сортировки, а не окно по всей истории игрока на каждую страницу.
+const paymentHistoryQuery = `
+WITH page AS (
+	SELECT p.* FROM billing.payments p
+	WHERE ` + paymentHistoryFilter + `
+		AND ($8 = 0 OR p.id < $8)
+	ORDER BY p.id DESC
+	LIMIT $9
+), bounds AS (
+	SELECT min(id) AS first_id, max(id) AS last_id FROM page
+), seed AS (
+	SELECT coalesce(sum(p.deposit - p.withdraw), 0) AS balance
+	FROM billing.payments p, bounds
+	WHERE p.user_id = $1 AND p.rollback_at IS NULL AND p.id < bounds.first_id
+), window_balance AS (
+	SELECT p.id, seed.balance + sum(CASE WHEN p.rollback_at IS NULL THEN p.deposit - p.withdraw ELSE 0 END)
+		OVER (ORDER BY p.id) AS balance
+	FROM billing.payments p, bounds, seed
+	WHERE p.user_id = $1 AND p.id BETWEEN bounds.first_id AND bounds.last_id
+)
+SELECT page.*, window_balance.balance
+FROM page JOIN window_balance ON window_balance.id = page.id
+ORDER BY page.id DESC`
+
+func (f PaymentHistoryFilter) args() []interface{} {
+	return []interface{}{f.UserID, f.From, f.To, f.GameID, f.GameRoundRef, f.Reason, f.RolledBack}
+}
+
+func GetPaymentHistory(
+	ctx context.Context,
+	db *sqlx.Tx,
+	filter PaymentHistoryFilter,
+	limit int,
+) ([]PaymentHistoryRow, error) {
+	var rows []PaymentHistoryRow
+	err := db.SelectContext(
+		ctx,
+		&rows,
+		paymentHistoryQuery,
+		append(filter.args(), filter.BeforeID, limit)...,
+	)
+
+	return rows, err //nolint:wrapcheck // intentional
+}
+
+// GetPaymentHistoryTotals итоги по всей отфильтрованной выборке без учёта пагинации,
+// суммы считаются только по не откаченным платежам. Баланс итогам не нужен, окна здесь нет.
+func GetPaymentHistoryTotals(
+	ctx context.Context,
+	db *sqlx.Tx,
+	filter PaymentHistoryFilter,
+) (PaymentHistoryTotals, error) {
+	var totals PaymentHistoryTotals
+	err := db.GetContext(
+		ctx,
+		&totals,
+		`SELECT count(*) AS count,
+			coalesce(sum(withdraw) FILTER (WHERE rollback_at IS NULL), 0) AS withdraw,
+			coalesce(sum(deposit) FILTER (WHERE rollback_at IS NULL), 0) AS deposit
+		FROM billing.payments p
+		WHERE `+paymentHistoryFilter,
+		filter.args()...,
+	)
+
+	return totals, err //nolint:wrapcheck // intentional
+}
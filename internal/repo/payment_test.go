@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var errUnexpectedQuery = errors.New("unexpected query")
+
+// scriptedResult ответ на запрос, в текст которого входит match: строки или ошибка.
+type scriptedResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+// scriptedDriver отвечает на запросы по сценарию и запоминает их. Сценарий выбирается по dsn.
+type scriptedDriver struct {
+	mu      sync.Mutex
+	scripts map[string]*script
+}
+
+type script struct {
+	results []scriptedResult
+	queries []string
+	args    [][]driver.NamedValue
+}
+
+func (d *scriptedDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return &scriptedConn{script: d.scripts[dsn]}, nil
+}
+
+type scriptedConn struct {
+	script *script
+}
+
+func (c *scriptedConn) Prepare(string) (driver.Stmt, error) { return nil, errUnexpectedQuery }
+func (c *scriptedConn) Close() error                        { return nil }
+func (c *scriptedConn) Begin() (driver.Tx, error)           { return scriptedTx{}, nil }
+
+func (c *scriptedConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.script.queries = append(c.script.queries, query)
+	c.script.args = append(c.script.args, args)
+
+	for _, result := range c.script.results {
+		if strings.Contains(query, result.match) {
+			if result.err != nil {
+				return nil, result.err
+			}
+
+			return &scriptedRows{columns: result.columns, rows: result.rows}, nil
+		}
+	}
+
+	return nil, errUnexpectedQuery
+}
+
+// ExecContext как QueryContext, но у совпавшего ответа берётся только ошибка.
+func (c *scriptedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, errQuery := c.QueryContext(ctx, query, args); errQuery != nil {
+		return nil, errQuery
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+type scriptedTx struct{}
+
+func (scriptedTx) Commit() error   { return nil }
+func (scriptedTx) Rollback() error { return nil }
+
+type scriptedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *scriptedRows) Columns() []string { return r.columns }
+func (r *scriptedRows) Close() error      { return nil }
+
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+var fakeDriver = &scriptedDriver{scripts: map[string]*script{}}
+
+func init() {
+	sql.Register("repo-scripted", fakeDriver)
+}
+
+// beginScripted открывает транзакцию на сценарии теста.
+func beginScripted(t *testing.T, results ...scriptedResult) (*sqlx.Tx, *script) {
+	t.Helper()
+
+	s := &script{results: results}
+
+	fakeDriver.mu.Lock()
+	fakeDriver.scripts[t.Name()] = s
+	fakeDriver.mu.Unlock()
+
+	db, errOpen := sqlx.Open("repo-scripted", t.Name())
+	if errOpen != nil {
+		t.Fatalf("open: %v", errOpen)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	tx, errBegin := db.Beginx()
+	if errBegin != nil {
+		t.Fatalf("begin: %v", errBegin)
+	}
+
+	t.Cleanup(func() { _ = tx.Rollback() })
+
+	return tx, s
+}
+
+func TestNewPaymentDuplicateRef(t *testing.T) {
+	testCases := []struct {
+		name    string
+		errPQ   *pq.Error
+		wantDup bool
+	}{
+		{
+			name:    "transaction ref index",
+			errPQ:   &pq.Error{Code: pqUniqueViolation, Constraint: paymentsTransactionRefIndex},
+			wantDup: true,
+		},
+		{
+			name:  "other constraint",
+			errPQ: &pq.Error{Code: pqUniqueViolation, Constraint: "payments_pkey"},
+		},
+		{
+			name:  "other error",
+			errPQ: &pq.Error{Code: "23503", Constraint: paymentsTransactionRefIndex},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			tx, _ := beginScripted(t, scriptedResult{match: "INSERT INTO billing.payments", err: tc.errPQ})
+
+			_, errNewPayment := NewPayment(context.Background(), tx, 1, 1, 100, 0, "tx-1", PaymentContext{})
+			if errors.Is(errNewPayment, errNotUniqueTransactionRef) != tc.wantDup {
+				t.Fatalf("error = %v, want duplicate %v", errNewPayment, tc.wantDup)
+			}
+		})
+	}
+}
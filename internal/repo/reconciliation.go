@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type LedgerEntry struct {
+	TransactionRef string     `db:"transaction_ref"`
+	CreatedAt      time.Time  `db:"created_at"`
+	RollBackAt     *time.Time `db:"rollback_at"`
+	Currency       string     `db:"currency"`
+	GameID         string     `db:"game_id"`
+	Withdraw       int        `db:"withdraw"`
+	Deposit        int        `db:"deposit"`
+}
+
+// GetLedgerEntries платежи провайдеров за период [from, to) плюс платежи по refs вне периода,
+// чтобы транзакции на границе периода не попадали в расхождения. Служебные платежи
+// (начальный баланс, ручные корректировки) в сверку не входят. Для ставок в валюте игры
+// берутся суммы и валюта провайдера, как в его выписке, а не пересчитанные в валюту кошелька.
+func GetLedgerEntries(
+	ctx context.Context,
+	db *sqlx.Tx,
+	from, to time.Time,
+	refs []string,
+) ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+	err := db.SelectContext(
+		ctx,
+		&entries,
+		`SELECT p.transaction_ref, p.created_at, p.rollback_at, p.game_id,
+			coalesce(gc.code, c.code) AS currency,
+			CASE WHEN p.game_currency_id IS NULL THEN p.withdraw ELSE p.game_withdraw END AS withdraw,
+			CASE WHEN p.game_currency_id IS NULL THEN p.deposit ELSE p.game_deposit END AS deposit
+		FROM billing.payments p
+		JOIN billing.ref_currency c ON c.id = p.currency_id
+		LEFT JOIN billing.ref_currency gc ON gc.id = p.game_currency_id
+		WHERE p.transaction_ref <> 'init' AND p.transaction_ref NOT LIKE 'admin-%'
+			AND ((p.created_at >= $1 AND p.created_at < $2) OR p.transaction_ref = ANY($3))
+		ORDER BY p.id`,
+		from,
+		to,
+		pq.Array(refs),
+	)
+
+	return entries, err //nolint:wrapcheck // intentional
+}
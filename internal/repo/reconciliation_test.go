@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGetLedgerEntriesGameCurrency ставка в валюте игры сверяется в суммах и валюте провайдера.
+func TestGetLedgerEntriesGameCurrency(t *testing.T) {
+	tx, s := beginScripted(t, scriptedResult{
+		match:   "FROM billing.payments",
+		columns: []string{"transaction_ref", "created_at", "rollback_at", "game_id", "currency", "withdraw", "deposit"},
+		rows:    [][]driver.Value{{"tx-1", time.Now(), nil, "g", "USD", int64(110), int64(55)}},
+	})
+
+	entries, errGetLedgerEntries := GetLedgerEntries(context.Background(), tx, time.Now(), time.Now(), nil)
+	if errGetLedgerEntries != nil || len(entries) != 1 {
+		t.Fatalf("entries = %+v, %v", entries, errGetLedgerEntries)
+	}
+
+	for _, column := range []string{"p.game_withdraw", "p.game_deposit", "gc.code", "gc.id = p.game_currency_id"} {
+		if !strings.Contains(s.queries[0], column) {
+			t.Fatalf("ledger query does not use %s:\n%s", column, s.queries[0])
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// RevenueDaily дневной агрегат выручки. stake и win считаются по платежам провайдеров
+// без откатов, ggr = stake - win, adjustments - чистая сумма ручных зачислений игрокам,
+// ngr = ggr - adjustments. Начальный баланс новых игроков в выручку не входит.
+// Платёж относится к оператору, который его провёл (caller_id), а не к тому, у которого
+// игрок зарегистрирован: игрок одного оператора может играть и через другого.
+type RevenueDaily struct {
+	Day         time.Time `db:"day"`
+	OperatorID  int       `db:"operator_id"`
+	GameID      string    `db:"game_id"`
+	CurrencyID  int       `db:"currency_id"`
+	Currency    string    `db:"currency"`
+	Bets        int       `db:"bets"`
+	Stake       int64     `db:"stake"`
+	Win         int64     `db:"win"`
+	Adjustments int64     `db:"adjustments"`
+	GGR         int64     `db:"ggr"`
+	NGR         int64     `db:"ngr"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// RevenueFilter пустые поля не ограничивают выборку, период [From, To).
+type RevenueFilter struct {
+	From       time.Time
+	To         time.Time
+	OperatorID *int
+	GameID     string
+	Currency   string
+}
+
+// RefreshRevenueDaily пересчитывает дни, в которые с прошлого обновления появились
+// или были откачены платежи. Сегодня и вчера пересчитываются всегда, чтобы подобрать
+// транзакции, закоммиченные позже платежей с большим id. Возвращает число пересчитанных дней.
+func RefreshRevenueDaily(ctx context.Context, db *sqlx.Tx) (int, error) {
+	var state struct {
+		LastPaymentID int64     `db:"last_payment_id"`
+		RefreshedAt   time.Time `db:"refreshed_at"`
+	}
+
+	// блокировка строки состояния не даёт двум инстансам пересчитывать одновременно
+	if errGetContext := db.GetContext(
+		ctx,
+		&state,
+		"SELECT last_payment_id, refreshed_at FROM billing.revenue_rollup_state WHERE id = 1 FOR UPDATE",
+	); errGetContext != nil {
+		return 0, errGetContext //nolint:wrapcheck // intentional
+	}
+
+	var maxPaymentID int64
+	if errGetContext := db.GetContext(
+		ctx,
+		&maxPaymentID,
+		"SELECT coalesce(max(id), 0) FROM billing.payments",
+	); errGetContext != nil {
+		return 0, errGetContext //nolint:wrapcheck // intentional
+	}
+
+	var days []string
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&days,
+		`SELECT to_char(day, 'YYYY-MM-DD') FROM (
+			SELECT DISTINCT created_at::date AS day FROM billing.payments WHERE id > $1 OR rollback_at >= $2
+			UNION SELECT current_date UNION SELECT current_date - 1
+		) d`,
+		state.LastPaymentID,
+		state.RefreshedAt,
+	); errSelectContext != nil {
+		return 0, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"DELETE FROM billing.revenue_daily WHERE day = ANY($1::date[])",
+		pq.Array(days),
+	); errExecContext != nil {
+		return 0, errExecContext //nolint:wrapcheck // intentional
+	}
+
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		`INSERT INTO billing.revenue_daily(day, operator_id, game_id, currency_id, bets, stake, win, adjustments, ggr, ngr)
+		SELECT day, operator_id, game_id, currency_id, bets, stake, win, adjustments, stake - win, stake - win - adjustments
+		FROM (
+			SELECT p.created_at::date AS day,
+				coalesce(nullif(p.caller_id, 0), u.operator_id, 0) AS operator_id,
+				p.game_id,
+				p.currency_id,
+				count(*) FILTER (WHERE NOT p.manual AND p.withdraw > 0) AS bets,
+				coalesce(sum(p.withdraw) FILTER (WHERE NOT p.manual), 0) AS stake,
+				coalesce(sum(p.deposit) FILTER (WHERE NOT p.manual), 0) AS win,
+				coalesce(sum(p.deposit - p.withdraw) FILTER (WHERE p.manual), 0) AS adjustments
+			FROM (
+				SELECT *, transaction_ref LIKE 'admin-%' AS manual FROM billing.payments
+				WHERE created_at::date = ANY($1::date[]) AND rollback_at IS NULL AND transaction_ref <> 'init'
+			) p
+			-- у платежей до появления caller_id и у ручных начислений оператора нет, берётся оператор игрока
+			JOIN public.users u ON u.id = p.user_id
+			GROUP BY 1, 2, 3, 4
+		) a`,
+		pq.Array(days),
+	); errExecContext != nil {
+		return 0, errExecContext //nolint:wrapcheck // intentional
+	}
+
+	// запас по времени на откаты, чьи транзакции начались раньше нашей, а закоммитились позже
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"UPDATE billing.revenue_rollup_state SET last_payment_id = $1, refreshed_at = now() - interval '10 minutes' WHERE id = 1", //nolint:lll // intentional
+		maxPaymentID,
+	); errExecContext != nil {
+		return 0, errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return len(days), nil
+}
+
+func GetRevenueDaily(ctx context.Context, db *sqlx.Tx, filter RevenueFilter) ([]RevenueDaily, error) {
+	var rows []RevenueDaily
+	err := db.SelectContext(
+		ctx,
+		&rows,
+		`SELECT r.*, c.code AS currency
+		FROM billing.revenue_daily r
+		JOIN billing.ref_currency c ON c.id = r.currency_id
+		WHERE r.day >= $1 AND r.day < $2
+			AND ($3::integer IS NULL OR r.operator_id = $3)
+			AND ($4 = '' OR r.game_id = $4)
+			AND ($5 = '' OR c.code = $5)
+		ORDER BY r.day, r.operator_id, r.game_id, c.code`,
+		filter.From,
+		filter.To,
+		filter.OperatorID,
+		filter.GameID,
+		filter.Currency,
+	)
+
+	return rows, err //nolint:wrapcheck // intentional
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRefreshRevenueDailyOperator выручка считается по оператору платежа, оператор игрока - только запасной.
+func TestRefreshRevenueDailyOperator(t *testing.T) {
+	tx, s := beginScripted(t,
+		scriptedResult{
+			match:   "FOR UPDATE",
+			columns: []string{"last_payment_id", "refreshed_at"},
+			rows:    [][]driver.Value{{int64(10), time.Now()}},
+		},
+		scriptedResult{match: "coalesce(max(id), 0)", columns: []string{"coalesce"}, rows: [][]driver.Value{{int64(12)}}},
+		scriptedResult{match: "to_char(day", columns: []string{"to_char"}, rows: [][]driver.Value{{"2024-01-01"}}},
+		scriptedResult{match: "DELETE FROM billing.revenue_daily"},
+		scriptedResult{match: "INSERT INTO billing.revenue_daily"},
+		scriptedResult{match: "UPDATE billing.revenue_rollup_state"},
+	)
+
+	days, errRefresh := RefreshRevenueDaily(context.Background(), tx)
+	if errRefresh != nil || days != 1 {
+		t.Fatalf("refresh = %d, %v, want 1 day", days, errRefresh)
+	}
+
+	var insert string
+
+	for _, query := range s.queries {
+		if strings.Contains(query, "INSERT INTO billing.revenue_daily") {
+			insert = query
+		}
+	}
+
+	if !strings.Contains(insert, "coalesce(nullif(p.caller_id, 0), u.operator_id, 0) AS operator_id") {
+		t.Fatalf("revenue is not grouped by the payment's operator:\n%s", insert)
+	}
+}
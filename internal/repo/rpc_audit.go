@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RPCAudit struct {
+	ID            int64     `db:"id"`
+	CreatedAt     time.Time `db:"created_at"`
+	Method        string    `db:"method"`
+	CallerID      int       `db:"caller_id"`
+	RemoteAddr    string    `db:"remote_addr"`
+	RequestID     string    `db:"request_id"`
+	Params        string    `db:"params"`
+	Response      string    `db:"response"`
+	Error         string    `db:"error"`
+	LatencyMicros int64     `db:"latency_micros"`
+	PrevHash      string    `db:"prev_hash"`
+	Hash          string    `db:"hash"`
+}
+
+type RPCAuditAnchor struct {
+	LastPrunedID   int64  `db:"last_pruned_id"`
+	LastPrunedHash string `db:"last_pruned_hash"`
+}
+
+// rpcAuditLockID ключ advisory lock, под которым дописывается цепочка, чтобы у двух записей не было общего предка.
+const rpcAuditLockID = 2022090601
+
+func LockRPCAudit(ctx context.Context, db *sqlx.Tx) error {
+	if _, errExecContext := db.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", rpcAuditLockID); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+// GetLastRPCAuditHash хэш последней записи, а если журнал пуст - хэш последней удалённой.
+func GetLastRPCAuditHash(ctx context.Context, db *sqlx.Tx) (string, error) {
+	var hash string
+	err := db.GetContext(
+		ctx,
+		&hash,
+		`SELECT coalesce(
+			(SELECT hash FROM billing.rpc_audit ORDER BY id DESC LIMIT 1),
+			(SELECT last_pruned_hash FROM billing.rpc_audit_anchor WHERE id = 1)
+		)`,
+	)
+
+	return hash, err //nolint:wrapcheck // intentional
+}
+
+func NewRPCAudit(ctx context.Context, db *sqlx.Tx, audit *RPCAudit) error {
+	return db.GetContext( //nolint:wrapcheck // intentional
+		ctx,
+		&audit.ID,
+		`INSERT INTO billing.rpc_audit(
+			created_at, method, caller_id, remote_addr, request_id, params, response, error, latency_micros, prev_hash, hash
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id`,
+		audit.CreatedAt,
+		audit.Method,
+		audit.CallerID,
+		audit.RemoteAddr,
+		audit.RequestID,
+		audit.Params,
+		audit.Response,
+		audit.Error,
+		audit.LatencyMicros,
+		audit.PrevHash,
+		audit.Hash,
+	)
+}
+
+func GetRPCAuditAnchor(ctx context.Context, db *sqlx.Tx) (RPCAuditAnchor, error) {
+	var anchor RPCAuditAnchor
+	err := db.GetContext(
+		ctx,
+		&anchor,
+		"SELECT last_pruned_id, last_pruned_hash FROM billing.rpc_audit_anchor WHERE id = 1",
+	)
+
+	return anchor, err //nolint:wrapcheck // intentional
+}
+
+// GetRPCAuditAfter следующая пачка записей журнала по возрастанию id.
+func GetRPCAuditAfter(ctx context.Context, db *sqlx.Tx, afterID int64, limit int) ([]RPCAudit, error) {
+	var audits []RPCAudit
+	err := db.SelectContext(
+		ctx,
+		&audits,
+		"SELECT * FROM billing.rpc_audit WHERE id > $1 ORDER BY id LIMIT $2",
+		afterID,
+		limit,
+	)
+
+	return audits, err //nolint:wrapcheck // intentional
+}
+
+// PruneRPCAudit удаляет записи старше before и запоминает хэш последней удалённой как начало цепочки.
+func PruneRPCAudit(ctx context.Context, db *sqlx.Tx, before time.Time) (int64, error) {
+	if _, errExecContext := db.ExecContext(ctx, "SET LOCAL billing.rpc_audit_retention = 'on'"); errExecContext != nil {
+		return 0, errExecContext //nolint:wrapcheck // intentional
+	}
+
+	var last []RPCAudit
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&last,
+		"SELECT * FROM billing.rpc_audit WHERE created_at < $1 ORDER BY id DESC LIMIT 1",
+		before,
+	); errSelectContext != nil {
+		return 0, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(last) == 0 {
+		return 0, nil
+	}
+
+	// удаляем по id, а не по времени, чтобы в цепочке не осталось дыр
+	result, errExecContext := db.ExecContext(ctx, "DELETE FROM billing.rpc_audit WHERE id <= $1", last[0].ID)
+	if errExecContext != nil {
+		return 0, errExecContext //nolint:wrapcheck // intentional
+	}
+
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"UPDATE billing.rpc_audit_anchor SET last_pruned_id = $1, last_pruned_hash = $2 WHERE id = 1",
+		last[0].ID,
+		last[0].Hash,
+	); errExecContext != nil {
+		return 0, errExecContext //nolint:wrapcheck // intentional
+	}
+
+	count, _ := result.RowsAffected()
+
+	return count, nil
+}
@@ -7,10 +7,42 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+type UserStatus string
+
+const (
+	UserStatusActive       UserStatus = "active"
+	UserStatusSuspended    UserStatus = "suspended"
+	UserStatusSelfExcluded UserStatus = "self_excluded"
+	UserStatusClosed       UserStatus = "closed"
+)
+
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserStatusActive, UserStatusSuspended, UserStatusSelfExcluded, UserStatusClosed:
+		return true
+	default:
+		return false
+	}
+}
+
 type User struct {
-	ID        int       `db:"id"`
-	CreatedAt time.Time `db:"created_at"`
-	Name      string    `db:"name"`
+	ID            int        `db:"id"`
+	CreatedAt     time.Time  `db:"created_at"`
+	Name          string     `db:"name"`
+	Status        UserStatus `db:"status"`
+	ExcludedUntil *time.Time `db:"excluded_until"`
+	StatusReason  string     `db:"status_reason"`
+	UpdatedAt     time.Time  `db:"updated_at"`
+	OperatorID    *int       `db:"operator_id"`
+}
+
+// EffectiveStatus статус игрока на момент now: истёкшее самоисключение считается активным статусом.
+func (u *User) EffectiveStatus(now time.Time) UserStatus {
+	if u.Status == UserStatusSelfExcluded && u.ExcludedUntil != nil && !now.Before(*u.ExcludedUntil) {
+		return UserStatusActive
+	}
+
+	return u.Status
 }
 
 func FindUserByID(ctx context.Context, db *sqlx.Tx, id int) (*User, error) {
@@ -38,19 +70,77 @@ func FindUserByName(ctx context.Context, db *sqlx.Tx, name string) (*User, error
 	return &user, nil
 }
 
-func NewUser(ctx context.Context, db *sqlx.Tx, name string) (*User, error) {
+// LockUserByName блокирует строку игрока до конца транзакции: изменения баланса одного игрока идут
+// по очереди, и проверка баланса не устаревает до записи платежа. Берут все пути, меняющие баланс:
+// withdrawAndDeposit и ручные корректировки админки.
+func LockUserByName(ctx context.Context, db *sqlx.Tx, name string) (*User, error) {
+	var user User
+
+	if errGetContext := db.GetContext(
+		ctx,
+		&user,
+		"SELECT * FROM public.users WHERE name = $1 FOR UPDATE",
+		name,
+	); errGetContext != nil {
+		return nil, errGetContext //nolint:wrapcheck // intentional
+	}
+
+	return &user, nil
+}
+
+// NewUser operatorID оператор, через которого игрок пришёл впервые, ему уходят вебхуки об изменении баланса.
+func NewUser(ctx context.Context, db *sqlx.Tx, name string, operatorID int) (*User, error) {
 	user := User{
-		Name: name,
+		Name:       name,
+		OperatorID: &operatorID,
 	}
 
 	if errGetContext := db.GetContext(
 		ctx,
 		&user,
-		"INSERT INTO public.users(\"name\") VALUES ($1) returning *",
+		"INSERT INTO public.users(\"name\", operator_id) VALUES ($1, $2) returning *",
 		name,
+		operatorID,
 	); errGetContext != nil {
 		return nil, errGetContext //nolint:wrapcheck // intentional
 	}
 
 	return &user, nil
 }
+
+// SetUserStatus меняет статус игрока и записывает изменение вместе с причиной в историю.
+func SetUserStatus(
+	ctx context.Context,
+	db *sqlx.Tx,
+	userID int,
+	status UserStatus,
+	excludedUntil *time.Time,
+	reason string,
+) (*User, error) {
+	var user User
+
+	if errGetContext := db.GetContext(
+		ctx,
+		&user,
+		"UPDATE public.users SET status = $2, excluded_until = $3, status_reason = $4, updated_at = now() WHERE id = $1 returning *", //nolint:lll // intentional
+		userID,
+		status,
+		excludedUntil,
+		reason,
+	); errGetContext != nil {
+		return nil, errGetContext //nolint:wrapcheck // intentional
+	}
+
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"INSERT INTO public.user_status_history(user_id, status, excluded_until, reason) VALUES ($1, $2, $3, $4)",
+		userID,
+		status,
+		excludedUntil,
+		reason,
+	); errExecContext != nil {
+		return nil, errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,248 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WebhookEventBalanceChanged баланс игрока изменился не по запросу оператора:
+// ручная корректировка, принудительный откат и т.п.
+const WebhookEventBalanceChanged = "balance_changed"
+
+type (
+	operatorCallKey  struct{}
+	webhookReasonKey struct{}
+)
+
+// WithOperatorCall помечает изменения баланса, которые оператор делает сам через seamless API:
+// о них он узнаёт из ответа, вебхук ему не нужен. Все остальные изменения баланса NewPayment
+// и RollbackPayment сами ставят в доставку, так что новый путь не может о ней забыть.
+func WithOperatorCall(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operatorCallKey{}, true)
+}
+
+func isOperatorCall(ctx context.Context) bool {
+	operatorCall, _ := ctx.Value(operatorCallKey{}).(bool)
+
+	return operatorCall
+}
+
+// WithWebhookReason причина изменения в вебхуке вместо причины платежа, например FORCED_ROLLBACK.
+func WithWebhookReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, webhookReasonKey{}, reason)
+}
+
+// notifyBalanceChanged ставит вебхук об изменении баланса, если его сделал не сам оператор.
+func notifyBalanceChanged(ctx context.Context, db *sqlx.Tx, payload OutboxPayload) error {
+	if isOperatorCall(ctx) {
+		return nil
+	}
+
+	if reason, ok := ctx.Value(webhookReasonKey{}).(string); ok {
+		payload.Reason = reason
+	}
+
+	return EnqueueOperatorWebhooks(ctx, db, WebhookEventBalanceChanged, payload)
+}
+
+type OperatorWebhook struct {
+	ID         int       `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	OperatorID int       `json:"operator_id" db:"operator_id"`
+	URL        string    `json:"url" db:"url"`
+	Secret     string    `json:"-" db:"secret"`
+	Active     bool      `json:"active" db:"active"`
+}
+
+// WebhookDelivery доставка вместе с адресом и секретом вебхука.
+type WebhookDelivery struct {
+	ID        int64           `db:"id"`
+	CreatedAt time.Time       `db:"created_at"`
+	WebhookID int             `db:"webhook_id"`
+	EventType string          `db:"event_type"`
+	Payload   json.RawMessage `db:"payload"`
+	Attempts  int             `db:"attempts"`
+	URL       string          `db:"url"`
+	Secret    string          `db:"secret"`
+}
+
+var (
+	errWebhookNotFound    = errors.New("webhook not found")
+	errDeadLetterNotFound = errors.New("dead letter not found or already replayed")
+)
+
+func NewOperatorWebhook(ctx context.Context, db *sqlx.Tx, operatorID int, url, secret string) (*OperatorWebhook, error) {
+	var webhook OperatorWebhook
+
+	if errGetContext := db.GetContext(
+		ctx,
+		&webhook,
+		"INSERT INTO billing.operator_webhooks(operator_id, url, secret) VALUES ($1, $2, $3) returning *",
+		operatorID,
+		url,
+		secret,
+	); errGetContext != nil {
+		return nil, errGetContext //nolint:wrapcheck // intentional
+	}
+
+	return &webhook, nil
+}
+
+func DeactivateOperatorWebhook(ctx context.Context, db *sqlx.Tx, id int) error {
+	result, errExecContext := db.ExecContext(
+		ctx,
+		"UPDATE billing.operator_webhooks SET active = false WHERE id = $1",
+		id,
+	)
+	if errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		return errWebhookNotFound
+	}
+
+	return nil
+}
+
+// EnqueueOperatorWebhooks ставит доставку на все активные вебхуки оператора игрока в той же транзакции,
+// что и изменение баланса. Игроки без оператора пропускаются.
+func EnqueueOperatorWebhooks(ctx context.Context, db *sqlx.Tx, eventType string, payload OutboxPayload) error {
+	raw, errMarshal := json.Marshal(payload)
+	if errMarshal != nil {
+		return errMarshal //nolint:wrapcheck // intentional
+	}
+
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		`INSERT INTO billing.webhook_deliveries(webhook_id, event_type, payload)
+		SELECT w.id, $2, $3::jsonb || jsonb_build_object('balance', (
+			SELECT coalesce(sum(deposit - withdraw), 0) FROM billing.payments WHERE user_id = $1 AND rollback_at IS NULL
+		))
+		FROM billing.operator_webhooks w
+		JOIN public.users u ON u.operator_id = w.operator_id
+		WHERE u.id = $1 AND w.active`,
+		payload.UserID,
+		eventType,
+		string(raw),
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+// ClaimDueWebhookDeliveries забирает доставки, которым подошло время, и откладывает их до leaseUntil.
+// Строки блокируются только на время этого запроса: пока идёт отправка, другие инстансы их не видят
+// по next_attempt_at, а если инстанс упал посреди отправки, доставки вернутся в очередь после leaseUntil.
+func ClaimDueWebhookDeliveries(
+	ctx context.Context,
+	db *sqlx.Tx,
+	limit int,
+	leaseUntil time.Time,
+) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.SelectContext(
+		ctx,
+		&deliveries,
+		`WITH due AS (
+			SELECT id FROM billing.webhook_deliveries
+			WHERE delivered_at IS NULL AND next_attempt_at <= now()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE billing.webhook_deliveries d SET next_attempt_at = $2
+		FROM due, billing.operator_webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.created_at, d.webhook_id, d.event_type, d.payload, d.attempts, w.url, w.secret`,
+		limit,
+		leaseUntil,
+	)
+
+	// RETURNING не сохраняет порядок подзапроса
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+
+	return deliveries, err //nolint:wrapcheck // intentional
+}
+
+func MarkWebhookDelivered(ctx context.Context, db *sqlx.Tx, id int64) error {
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"UPDATE billing.webhook_deliveries SET delivered_at = now(), attempts = attempts + 1, last_error = '' WHERE id = $1",
+		id,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+func RescheduleWebhookDelivery(
+	ctx context.Context,
+	db *sqlx.Tx,
+	id int64,
+	nextAttemptAt time.Time,
+	lastError string,
+) error {
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		"UPDATE billing.webhook_deliveries SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3 WHERE id = $1",
+		id,
+		nextAttemptAt,
+		lastError,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+// DeadLetterWebhookDelivery переносит доставку, исчерпавшую попытки, в billing.webhook_dead_letters.
+func DeadLetterWebhookDelivery(ctx context.Context, db *sqlx.Tx, id int64, lastError string) error {
+	if _, errExecContext := db.ExecContext(
+		ctx,
+		`WITH moved AS (
+			DELETE FROM billing.webhook_deliveries WHERE id = $1 RETURNING *
+		)
+		INSERT INTO billing.webhook_dead_letters(delivery_id, webhook_id, event_type, payload, attempts, last_error)
+		SELECT id, webhook_id, event_type, payload, attempts + 1, $2 FROM moved`,
+		id,
+		lastError,
+	); errExecContext != nil {
+		return errExecContext //nolint:wrapcheck // intentional
+	}
+
+	return nil
+}
+
+// ReplayWebhookDeadLetter заново ставит доставку из dead letter и возвращает её id.
+func ReplayWebhookDeadLetter(ctx context.Context, db *sqlx.Tx, id int64) (int64, error) {
+	var deliveryIDs []int64
+	if errSelectContext := db.SelectContext(
+		ctx,
+		&deliveryIDs,
+		`WITH replayed AS (
+			UPDATE billing.webhook_dead_letters SET replayed_at = now()
+			WHERE id = $1 AND replayed_at IS NULL
+			RETURNING webhook_id, event_type, payload
+		)
+		INSERT INTO billing.webhook_deliveries(webhook_id, event_type, payload)
+		SELECT webhook_id, event_type, payload FROM replayed
+		RETURNING id`,
+		id,
+	); errSelectContext != nil {
+		return 0, errSelectContext //nolint:wrapcheck // intentional
+	}
+
+	if len(deliveryIDs) == 0 {
+		return 0, errDeadLetterNotFound
+	}
+
+	return deliveryIDs[0], nil
+}
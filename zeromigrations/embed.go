@@ -0,0 +1,11 @@
+// Package zeromigrations SQL миграции, встроенные в бинарь. NAME.SQL накатывает миграцию,
+// NAME.down.SQL откатывает её, миграции применяются в порядке имён файлов.
+package zeromigrations
+
+import "embed"
+
+// Dir каталог миграций внутри FS.
+const Dir = "common"
+
+//go:embed common/*.SQL
+var FS embed.FS